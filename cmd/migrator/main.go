@@ -7,13 +7,41 @@ import (
 
 	"ariga.io/atlas-provider-gorm/gormschema"
 
+	"github.com/auth-engine/config"
 	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/internal/pkg/store"
+	"github.com/auth-engine/internal/pkg/utils"
 )
 
+// 用法：
+//
+//	go run ./cmd/migrator              输出 gorm 模型对应的表结构，供 atlas 使用
+//	go run ./cmd/migrator hash-tokens  将历史明文 Token 转换为摘要，需在执行完 v0.2.0 迁移后运行
+//	go run ./cmd/migrator reencrypt-exports <file>...  使用主密钥重新加密 Token 导出文件，轮换导出密钥后运行
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "hash-tokens" {
+		if err := hashTokens(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to hash tokens: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "reencrypt-exports" {
+		if err := reencryptExports(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to re-encrypt export files: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 	stmts, err := gormschema.New("mysql").Load(
 		&dao.TokenEntity{},
 		&dao.TokenValidityPolicy{},
+		&dao.TokenUsage{},
+		&dao.AuditLog{},
+		&dao.Calendar{},
+		&dao.CalendarDate{},
+		&dao.TokenCalendar{},
 	)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to load gorm schema: %v\n", err)
@@ -21,3 +49,72 @@ func main() {
 	}
 	io.WriteString(os.Stdout, stmts)
 }
+
+// hashTokens 计算历史明文 Token 的摘要，并清空明文
+func hashTokens() error {
+	appConfig, err := config.InitConfig()
+	if err != nil {
+		return err
+	}
+	db, err := dao.Connect(appConfig)
+	if err != nil {
+		return err
+	}
+	memoryStore := store.NewMemoryStore(0, 0)
+	defer memoryStore.Close()
+	tokenService := services.NewTokenService(dao.NewTokenDao(db), dao.NewTokenValidityPolicyDao(db), appConfig, memoryStore)
+	count, err := tokenService.HashPlaintextTokens()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "%d tokens hashed\n", count)
+	return nil
+}
+
+// reencryptExports 使用主密钥重新加密导出文件，已使用主密钥加密的文件保持不变
+func reencryptExports(files []string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("no export files specified")
+	}
+	appConfig, err := config.InitConfig()
+	if err != nil {
+		return err
+	}
+	exportCipher, err := utils.NewExportCipher(appConfig.Export)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		// 口令加密的文件不使用服务端密钥，无需重新加密
+		if utils.IsPassphraseProtected(data) {
+			fmt.Fprintf(os.Stdout, "%s: protected by passphrase, skipped\n", file)
+			continue
+		}
+		result, changed, err := exportCipher.Reencrypt(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if !changed {
+			fmt.Fprintf(os.Stdout, "%s: already encrypted with key %s\n", file, exportCipher.PrimaryKeyID())
+			continue
+		}
+		// 先写入临时文件再重命名，避免写入中断导致原文件损坏
+		tmpFile := file + ".tmp"
+		if err := os.WriteFile(tmpFile, result, info.Mode().Perm()); err != nil {
+			return err
+		}
+		if err := os.Rename(tmpFile, file); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "%s: re-encrypted with key %s\n", file, exportCipher.PrimaryKeyID())
+	}
+	return nil
+}
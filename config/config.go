@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -15,6 +16,10 @@ type AppConfig struct {
 	Kube               Kube        `yaml:"kube"`
 	MySQL              DBConfig    `yaml:"mysql"`
 	Redis              RedisConfig `yaml:"redis"`
+	Auth               Auth        `yaml:"auth"`
+	JWT                JWTConfig   `yaml:"jwt"`
+	Export             Export      `yaml:"export"`
+	ExtAuthz           ExtAuthz    `yaml:"extAuthz"`
 	InsecureSkipVerify bool        `yaml:"insecureSkipVerify"`
 	Level              string      `yaml:"level"`
 	HostCluster        string      `yaml:"hostCluster"`
@@ -24,20 +29,84 @@ type AppConfig struct {
 
 // MySQL DB 配置
 type DBConfig struct {
-	DBType               string        `yaml:"dbType"`               // 数据库类型，默认 mysql
-	DSN                  string        `yaml:"dsn"`                  // data source name, e.g. root:123456@tcp(127.0.0.1:3306)/hydra
-	MaxIdleConns         int           `yaml:"maxIdleConns"`         // 最大空闲连接数
-	MaxOpenConns         int           `yaml:"maxOpenConns"`         // 最大连接数
-	AutoMigrate          bool          `yaml:"autoMigrate"`          // 自动建表，补全缺失字段，初始化数据
-	Debug                bool          `yaml:"debug"`                // 是否开启调试模式
-	CacheFlag            bool          `yaml:"cacheFlag"`            // 是否开启查询缓存
-	CacheExpiration      time.Duration `yaml:"cacheExpiration"`      // 缓存过期时间
-	CacheCleanupInterval time.Duration `yaml:"cacheCleanupInterval"` // 缓存清理时间间隔
-}
-
-// Redis 配置
+	DBType                  string        `yaml:"dbType"`                  // 数据库类型，默认 mysql
+	DSN                     string        `yaml:"dsn"`                     // data source name, e.g. root:123456@tcp(127.0.0.1:3306)/hydra
+	MaxIdleConns            int           `yaml:"maxIdleConns"`            // 最大空闲连接数
+	MaxOpenConns            int           `yaml:"maxOpenConns"`            // 最大连接数
+	AutoMigrate             bool          `yaml:"autoMigrate"`             // 自动建表，补全缺失字段，初始化数据
+	Debug                   bool          `yaml:"debug"`                   // 是否开启调试模式
+	CacheFlag               bool          `yaml:"cacheFlag"`               // 是否开启查询缓存
+	CacheExpiration         time.Duration `yaml:"cacheExpiration"`         // 缓存过期时间
+	CacheNegativeExpiration time.Duration `yaml:"cacheNegativeExpiration"` // 不存在的 Token 的缓存过期时间
+	CacheCleanupInterval    time.Duration `yaml:"cacheCleanupInterval"`    // 缓存清理时间间隔
+	CacheMaxEntries         int           `yaml:"cacheMaxEntries"`         // 缓存最大条目数
+}
+
+// Redis 配置，未配置 URL 时共享状态只保存在当前副本的内存中
 type RedisConfig struct {
-	URL string `yaml:"port"`
+	URL       string `yaml:"url"`       // redis 地址，如 redis://:password@127.0.0.1:6379/0
+	KeyPrefix string `yaml:"keyPrefix"` // key 前缀，默认 auth-engine:
+}
+
+// Auth Token 认证配置
+type Auth struct {
+	TokenPepper string        `yaml:"tokenPepper"` // Token 摘要密钥，用于计算 HMAC-SHA256 摘要，修改后已有 Token 全部失效，应通过环境变量 TOKEN_PEPPER 设置，不要写在配置文件中
+	LeaseTTL    time.Duration `yaml:"leaseTTL"`    // 并发租约过期时间，调用方未释放的租约在过期后自动释放，默认 5m
+	// 认证次数统计先在内存中累加，每隔 UsageFlushInterval 批量写入数据库，默认 10s，必须大于 0
+	UsageFlushInterval time.Duration `yaml:"usageFlushInterval"`
+	// 轮换 Token 后轮换前的密钥继续有效的时间，默认 24h，轮换时可单独指定
+	RotationGracePeriod time.Duration `yaml:"rotationGracePeriod"`
+	// 有效期策略默认时区，IANA 时区名称，如 Asia/Shanghai，Token 和策略未指定时区时使用，为空时使用服务器时区，
+	// 可通过环境变量 AUTH_TIMEZONE 覆盖
+	Timezone string `yaml:"timezone"`
+	// 转发认证（nginx auth_request、Traefik ForwardAuth）在 Authorization 不是 Bearer 格式时读取 Token 的请求头，默认 X-Api-Key
+	ForwardAuthHeader string `yaml:"forwardAuthHeader"`
+	// 为 true 时认证请求必须携带模型（请求参数、X-Requested-Model 请求头或请求体中的 model），未携带时拒绝，默认 false 不校验
+	RequireModel bool `yaml:"requireModel"`
+}
+
+// JWTConfig 管理接口 JWT 校验配置，JWKSURL、PublicKeys 和 PublicKeysFile 至少配置一个
+type JWTConfig struct {
+	JWKSURL         string        `yaml:"jwksURL"`         // JWKS 地址，如 https://idp.example.com/.well-known/jwks.json
+	PublicKeys      []string      `yaml:"publicKeys"`      // PEM 格式的公钥或证书，与 JWKS 中的公钥同时生效
+	PublicKeysFile  string        `yaml:"publicKeysFile"`  // 挂载的 PEM 文件，可包含多个公钥或证书，与 PublicKeys 合并
+	Issuer          string        `yaml:"issuer"`          // 签发者，为空时不校验
+	Audience        string        `yaml:"audience"`        // 受众，为空时不校验
+	RefreshInterval time.Duration `yaml:"refreshInterval"` // JWKS 刷新间隔，默认 1h，遇到未知 kid 时立即刷新
+	Leeway          time.Duration `yaml:"leeway"`          // 校验有效期时允许的时钟偏差，默认 1m
+}
+
+// Export Token 导出文件加密配置，可同时配置多个密钥，新导出的文件使用主密钥加密，导入时按文件中的密钥 ID 选择密钥
+type Export struct {
+	// 加密密钥，只从环境变量 EXPORT_KEYS 读取，格式为 id:key,id:key，不允许写在配置文件中
+	Keys         []ExportKey `yaml:"-" mapstructure:"-"`
+	KeysFile     string      `yaml:"keysFile"`     // 挂载的密钥文件，每行一个 id:key，与 EXPORT_KEYS 合并
+	PrimaryKeyID string      `yaml:"primaryKeyID"` // 新导出文件使用的密钥 ID，默认为第一个密钥，可通过环境变量 EXPORT_PRIMARY_KEY_ID 覆盖
+	LegacyKeyID  string      `yaml:"legacyKeyID"`  // 解密不带密钥 ID 的旧版本导出文件使用的密钥 ID，为空时不支持导入旧版本文件
+}
+
+// ExportKey 导出文件加密密钥，Key 长度为 16、24 或 32 字节，分别对应 AES-128、AES-192、AES-256
+type ExportKey struct {
+	ID  string `yaml:"id"`
+	Key string `yaml:"key"`
+}
+
+// ParseExportKeys 解析 id:key 格式的密钥列表，以逗号或换行分隔，忽略空行和 # 开头的注释
+func ParseExportKeys(s string) ([]ExportKey, error) {
+	var keys []ExportKey
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		item = strings.TrimSpace(item)
+		if item == "" || strings.HasPrefix(item, "#") {
+			continue
+		}
+		id, key, ok := strings.Cut(item, ":")
+		if !ok || id == "" || key == "" {
+			// 不输出原始内容，避免密钥出现在日志中
+			return nil, xerrors.New("invalid export key, expected id:key")
+		}
+		keys = append(keys, ExportKey{ID: id, Key: key})
+	}
+	return keys, nil
 }
 
 // Server Port 配置
@@ -45,6 +114,11 @@ type Server struct {
 	Port uint16 `yaml:"port"`
 }
 
+// ExtAuthz Envoy 外部认证 gRPC 服务配置
+type ExtAuthz struct {
+	Port uint16 `yaml:"port"` // 监听端口，为 0 时不启动
+}
+
 // DCE 配置
 type DCE struct {
 	URL     string `yaml:"url"`
@@ -100,6 +174,12 @@ func InitConfig() (*AppConfig, error) {
 	appConfig.Level = "debug"
 	appConfig.HostCluster = "kpanda-global-cluster"
 	appConfig.HostNamespace = "auth-engine-system"
+	appConfig.Redis.KeyPrefix = "auth-engine:"
+	appConfig.Auth.LeaseTTL = 5 * time.Minute
+	appConfig.Auth.UsageFlushInterval = 10 * time.Second
+	appConfig.Auth.RotationGracePeriod = 24 * time.Hour
+	appConfig.JWT.RefreshInterval = time.Hour
+	appConfig.JWT.Leeway = time.Minute
 	if clusterFromEnv := os.Getenv("HOST_CLUSTER"); len(clusterFromEnv) != 0 {
 		appConfig.HostCluster = clusterFromEnv
 	}
@@ -114,6 +194,32 @@ func InitConfig() (*AppConfig, error) {
 	if err := newViper.Unmarshal(&appConfig); err != nil {
 		return nil, xerrors.Errorf(": %w", err)
 	}
+	if pepperFromEnv := os.Getenv("TOKEN_PEPPER"); len(pepperFromEnv) != 0 {
+		appConfig.Auth.TokenPepper = pepperFromEnv
+	}
+	if appConfig.Auth.TokenPepper == "" {
+		return nil, xerrors.New("TOKEN_PEPPER or auth.tokenPepper is required")
+	}
+	// 不定时写入时认证次数统计只在服务退出时写入，异常退出会丢失
+	if appConfig.Auth.UsageFlushInterval <= 0 {
+		return nil, xerrors.New("auth.usageFlushInterval must be greater than 0")
+	}
+	if timezoneFromEnv := os.Getenv("AUTH_TIMEZONE"); len(timezoneFromEnv) != 0 {
+		appConfig.Auth.Timezone = timezoneFromEnv
+	}
+	if _, err := time.LoadLocation(appConfig.Auth.Timezone); err != nil {
+		return nil, xerrors.Errorf("auth.timezone: %w", err)
+	}
+	if keysFromEnv := os.Getenv("EXPORT_KEYS"); len(keysFromEnv) != 0 {
+		keys, err := ParseExportKeys(keysFromEnv)
+		if err != nil {
+			return nil, xerrors.Errorf("EXPORT_KEYS: %w", err)
+		}
+		appConfig.Export.Keys = keys
+	}
+	if primaryKeyIDFromEnv := os.Getenv("EXPORT_PRIMARY_KEY_ID"); len(primaryKeyIDFromEnv) != 0 {
+		appConfig.Export.PrimaryKeyID = primaryKeyIDFromEnv
+	}
 	EnvConfs = appConfig.EnvConfs
 	for _, envConf := range appConfig.EnvConfs {
 		if envConf.IsDefault {
@@ -0,0 +1,186 @@
+package authn
+
+import (
+	"context"
+	"crypto"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/auth-engine/config"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultRefreshInterval = time.Hour
+	// minRefreshInterval 遇到未知 kid 时强制刷新 JWKS 的最小间隔，避免伪造的 kid 导致频繁请求
+	minRefreshInterval = 10 * time.Second
+	jwksFetchTimeout   = 10 * time.Second
+)
+
+var ErrKeyNotFound = xerrors.New("no verification key found")
+
+// Claims 管理接口 JWT 中使用的字段
+type Claims struct {
+	jwt.RegisteredClaims
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// Verifier 校验管理接口的 JWT 签名、签发者、受众和有效期
+// 公钥来自 JWKS 地址或配置的 PEM 公钥，JWKS 按 RefreshInterval 定期刷新，遇到未知 kid 时立即刷新
+type Verifier struct {
+	jwksURL         string
+	refreshInterval time.Duration
+	httpClient      *http.Client
+	parser          *jwt.Parser
+	staticKeys      []crypto.PublicKey
+
+	refreshMu   sync.Mutex // 保证同一时间只有一个刷新请求
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey // kid -> 公钥
+	refreshedAt time.Time                   // 最近一次成功刷新的时间
+	attemptedAt time.Time                   // 最近一次尝试刷新的时间
+}
+
+// NewVerifier 根据配置创建 Verifier，JWKSURL、PublicKeys 和 PublicKeysFile 至少配置一个
+// 启动时获取 JWKS 失败不会返回错误，校验时会重试
+func NewVerifier(cfg config.JWTConfig) (*Verifier, error) {
+	if cfg.JWKSURL == "" && len(cfg.PublicKeys) == 0 && cfg.PublicKeysFile == "" {
+		return nil, xerrors.New("jwt.jwksURL, jwt.publicKeys or jwt.publicKeysFile is required")
+	}
+	v := &Verifier{
+		jwksURL:         cfg.JWKSURL,
+		refreshInterval: cfg.RefreshInterval,
+		httpClient:      &http.Client{Timeout: jwksFetchTimeout},
+		keys:            map[string]crypto.PublicKey{},
+	}
+	if v.refreshInterval <= 0 {
+		v.refreshInterval = defaultRefreshInterval
+	}
+	for i, data := range cfg.PublicKeys {
+		key, err := parsePEMPublicKey(data)
+		if err != nil {
+			return nil, xerrors.Errorf("parse jwt.publicKeys[%d] failed: %w", i, err)
+		}
+		v.staticKeys = append(v.staticKeys, key)
+	}
+	if cfg.PublicKeysFile != "" {
+		data, err := os.ReadFile(cfg.PublicKeysFile)
+		if err != nil {
+			return nil, xerrors.Errorf("read jwt.publicKeysFile failed: %w", err)
+		}
+		keys, err := parsePEMPublicKeys(data)
+		if err != nil {
+			return nil, xerrors.Errorf("parse jwt.publicKeysFile failed: %w", err)
+		}
+		v.staticKeys = append(v.staticKeys, keys...)
+	}
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(options...)
+	if v.jwksURL != "" {
+		if err := v.refresh(context.Background()); err != nil {
+			hlog.Warnf("fetch jwks from %s failed: %v", v.jwksURL, err)
+		}
+	}
+	return v, nil
+}
+
+// Verify 校验 JWT 并返回其中的字段，sub 为空时视为无效
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.verificationKeys(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, xerrors.New("token has no sub claim")
+	}
+	return claims, nil
+}
+
+// verificationKeys 返回可用于校验的公钥，kid 为空时返回所有公钥
+func (v *Verifier) verificationKeys(ctx context.Context, kid string) (jwt.VerificationKeySet, error) {
+	if v.jwksURL != "" {
+		v.mu.RLock()
+		_, found := v.keys[kid]
+		stale := time.Since(v.refreshedAt) > v.refreshInterval
+		v.mu.RUnlock()
+		if stale || (kid != "" && !found) {
+			if err := v.refresh(ctx); err != nil {
+				hlog.CtxWarnf(ctx, "refresh jwks from %s failed: %v", v.jwksURL, err)
+			}
+		}
+	}
+	var set jwt.VerificationKeySet
+	v.mu.RLock()
+	for keyID, key := range v.keys {
+		if kid == "" || keyID == kid {
+			set.Keys = append(set.Keys, key)
+		}
+	}
+	v.mu.RUnlock()
+	for _, key := range v.staticKeys {
+		set.Keys = append(set.Keys, key)
+	}
+	if len(set.Keys) == 0 {
+		return set, ErrKeyNotFound
+	}
+	return set, nil
+}
+
+// refresh 重新获取 JWKS，距离上次尝试不足 minRefreshInterval 时跳过，失败时保留已缓存的公钥
+func (v *Verifier) refresh(ctx context.Context) error {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+	if time.Since(v.attemptedAt) < minRefreshInterval {
+		return nil
+	}
+	v.attemptedAt = time.Now()
+	keys, err := v.fetchJWKS(ctx)
+	if err != nil {
+		return err
+	}
+	v.mu.Lock()
+	v.keys = keys
+	v.refreshedAt = time.Now()
+	v.mu.Unlock()
+	return nil
+}
+
+func (v *Verifier) fetchJWKS(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, xerrors.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	return parseJWKS(data)
+}
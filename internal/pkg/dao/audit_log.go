@@ -0,0 +1,89 @@
+package dao
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditLog Token 管理操作审计日志，只追加不修改
+type AuditLog struct {
+	ID          uint      `json:"id" gorm:"column:id;primaryKey;autoIncrement;comment:ID"`                                                  // ID
+	WorkspaceID string    `json:"workspaceId" gorm:"column:workspace_id;type:varchar(64);not null;index:idx_workspace_time;comment:工作空间ID"` // 工作空间ID
+	TokenID     string    `json:"tokenId" gorm:"column:token_id;type:varchar(64);not null;index:idx_token_id;comment:token ID"`             // token ID
+	Action      string    `json:"action" gorm:"column:action;type:varchar(32);not null;comment:操作类型"`                                       // 操作类型
+	ActorID     string    `json:"actorId" gorm:"column:actor_id;type:varchar(64);not null;comment:操作人ID"`                                   // 操作人ID
+	Actor       string    `json:"actor" gorm:"column:actor;type:varchar(64);not null;comment:操作人"`                                          // 操作人
+	SourceIP    string    `json:"sourceIp" gorm:"column:source_ip;type:varchar(64);not null;comment:来源IP"`                                  // 来源IP
+	Diff        JSON      `json:"diff" gorm:"column:diff;type:json;null;comment:变更内容"`                                                      // 变更内容，字段名 -> {before, after}
+	CreateTime  time.Time `json:"createTime" gorm:"column:create_time;type:datetime;not null;index:idx_workspace_time;comment:操作时间"`        // 操作时间
+}
+
+func (*AuditLog) TableName() string {
+	return "token_audit_logs"
+}
+
+func init() {
+	registerInjector(func(d *daoInit) {
+		setupTableModel(d, &AuditLog{})
+	})
+}
+
+type AuditLogQueryParam struct {
+	TokenID   string `json:"tokenId"`   // token ID
+	Action    string `json:"action"`    // 操作类型
+	Actor     string `json:"actor"`     // 操作人
+	StartTime string `json:"startTime"` // 开始时间，如 2024-01-01 00:00:00
+	EndTime   string `json:"endTime"`   // 结束时间
+}
+
+type AuditLogDao struct {
+	DB *gorm.DB
+}
+
+type IAuditLogDao interface {
+	Create(log *AuditLog) error
+	QueryPageList(workspaceID string, pageParam PageParam, queryParam AuditLogQueryParam) (int64, []*AuditLog, error)
+}
+
+func NewAuditLogDao(db *gorm.DB) IAuditLogDao {
+	if db == nil {
+		db = GetDB()
+	}
+	return &AuditLogDao{DB: db}
+}
+
+func (d *AuditLogDao) Create(log *AuditLog) error {
+	return d.DB.Create(log).Error
+}
+
+func (d *AuditLogDao) QueryPageList(workspaceID string, pageParam PageParam, queryParam AuditLogQueryParam) (int64, []*AuditLog, error) {
+	var (
+		count int64
+		logs  []*AuditLog
+	)
+	query := d.DB.Model(&AuditLog{}).Where("workspace_id = ?", workspaceID)
+	if queryParam.TokenID != "" {
+		query = query.Where("token_id = ?", queryParam.TokenID)
+	}
+	if queryParam.Action != "" {
+		query = query.Where("action = ?", queryParam.Action)
+	}
+	if queryParam.Actor != "" {
+		query = query.Where("actor like concat('%',?,'%')", queryParam.Actor)
+	}
+	if queryParam.StartTime != "" {
+		query = query.Where("create_time >= ?", queryParam.StartTime)
+	}
+	if queryParam.EndTime != "" {
+		query = query.Where("create_time <= ?", queryParam.EndTime)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return 0, nil, err
+	}
+	offset, limit := CalculatePagination(pageParam)
+	if err := query.Order("create_time desc, id desc").Offset(offset).Limit(limit).Find(&logs).Error; err != nil {
+		return 0, nil, err
+	}
+	return count, logs, nil
+}
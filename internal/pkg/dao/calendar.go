@@ -0,0 +1,233 @@
+package dao
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Calendar 节假日和封网日历，Token 关联日历后在日历中的日期认证失败
+type Calendar struct {
+	ID          string    `json:"id" gorm:"column:id;type:varchar(64);not null;primaryKey;comment:ID"`                                                   // ID
+	WorkspaceID string    `json:"workspaceId" gorm:"column:workspace_id;type:varchar(64);not null;index:idx_workspace_name,unique;comment:工作空间ID"`       // 工作空间ID
+	Name        string    `json:"name" gorm:"column:name;type:varchar(64);not null;index:idx_workspace_name,unique;comment:日历名称"`                        // 日历名称，同一工作空间下唯一
+	Description string    `json:"description" gorm:"column:description;type:varchar(255);not null;default:'';comment:描述"`                                // 描述
+	Timezone    string    `json:"timezone" gorm:"column:timezone;type:varchar(64);not null;default:'';comment:日历时区"`                                     // 日历时区，为空时使用默认时区
+	CreateBy    string    `json:"createBy" gorm:"column:create_by;type:varchar(36);comment:创建人"`                                                         // 创建人
+	UpdateBy    string    `json:"updateBy" gorm:"column:update_by;type:varchar(36);comment:更新人"`                                                         // 更新人
+	CreateTime  time.Time `json:"createTime" gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;comment:创建时间"`                             // 创建时间
+	UpdateTime  time.Time `json:"updateTime" gorm:"column:update_time;type:datetime;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;comment:更新时间"` // 更新时间
+}
+
+func (*Calendar) TableName() string {
+	return "token_calendars"
+}
+
+// CalendarDate 日历中的日期范围，包含开始和结束日期
+type CalendarDate struct {
+	ID         uint      `json:"id" gorm:"column:id;primaryKey;autoIncrement;comment:ID"`                                           // ID
+	CalendarID string    `json:"calendarId" gorm:"column:calendar_id;type:varchar(64);not null;index:idx_calendar_id;comment:日历ID"` // 日历ID
+	StartDate  time.Time `json:"startDate" gorm:"column:start_date;type:date;not null;comment:开始日期"`                                // 开始日期
+	EndDate    time.Time `json:"endDate" gorm:"column:end_date;type:date;not null;comment:结束日期"`                                    // 结束日期
+	Note       string    `json:"note" gorm:"column:note;type:varchar(255);not null;default:'';comment:备注"`                          // 备注，如节日名称
+}
+
+func (*CalendarDate) TableName() string {
+	return "token_calendar_dates"
+}
+
+// TokenCalendar Token 关联的日历
+type TokenCalendar struct {
+	TokenID    string `json:"tokenId" gorm:"column:token_id;type:varchar(64);not null;primaryKey;comment:token ID"`                         // token ID
+	CalendarID string `json:"calendarId" gorm:"column:calendar_id;type:varchar(64);not null;primaryKey;index:idx_calendar_id;comment:日历ID"` // 日历ID
+}
+
+func (*TokenCalendar) TableName() string {
+	return "token_calendar_bindings"
+}
+
+func init() {
+	registerInjector(func(d *daoInit) {
+		setupTableModel(d, &Calendar{})
+		setupTableModel(d, &CalendarDate{})
+		setupTableModel(d, &TokenCalendar{})
+	})
+}
+
+type CalendarQueryParam struct {
+	Name string `json:"name"` // 日历名称，模糊匹配
+}
+
+type CalendarDao struct {
+	DB *gorm.DB
+}
+
+type ICalendarDao interface {
+	Create(calendar *Calendar, dates []*CalendarDate) error
+	Get(workspaceID, id string) (*Calendar, error)
+	CheckNameExists(workspaceID, name, id string) (bool, error)
+	QueryPageList(workspaceID string, pageParam PageParam, queryParam CalendarQueryParam) (int64, []*Calendar, error)
+	Update(calendar *Calendar, dates []*CalendarDate) error
+	Delete(id string) error
+	ListDates(calendarIDs []string) ([]*CalendarDate, error)
+	ListByIDs(workspaceID string, ids []string) ([]*Calendar, error)
+	ListByTokenID(tokenID string) ([]*Calendar, error)
+	ListTokenIDs(calendarID string) ([]string, error)
+	ReplaceTokenCalendars(tokenID string, calendarIDs []string) error
+}
+
+func NewCalendarDao(db *gorm.DB) ICalendarDao {
+	if db == nil {
+		db = GetDB()
+	}
+	return &CalendarDao{DB: db}
+}
+
+func (d *CalendarDao) Create(calendar *Calendar, dates []*CalendarDate) error {
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(calendar).Error; err != nil {
+			return err
+		}
+		return createCalendarDates(tx, calendar.ID, dates)
+	})
+}
+
+func (d *CalendarDao) Get(workspaceID, id string) (*Calendar, error) {
+	var calendar Calendar
+	if err := d.DB.Where("workspace_id = ? and id = ?", workspaceID, id).First(&calendar).Error; err != nil {
+		return nil, err
+	}
+	return &calendar, nil
+}
+
+// CheckNameExists 检查同一工作空间下是否存在同名日历，id 不为空时排除该日历
+func (d *CalendarDao) CheckNameExists(workspaceID, name, id string) (bool, error) {
+	query := d.DB.Model(&Calendar{}).Where("workspace_id = ? and name = ?", workspaceID, name)
+	if id != "" {
+		query = query.Where("id <> ?", id)
+	}
+	return RecordExists(query)
+}
+
+func (d *CalendarDao) QueryPageList(workspaceID string, pageParam PageParam, queryParam CalendarQueryParam) (int64, []*Calendar, error) {
+	var (
+		count     int64
+		calendars []*Calendar
+	)
+	query := d.DB.Model(&Calendar{}).Where("workspace_id = ?", workspaceID)
+	if queryParam.Name != "" {
+		query = query.Where("name like concat('%',?,'%')", queryParam.Name)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return 0, nil, err
+	}
+	offset, limit := CalculatePagination(pageParam)
+	if err := query.Order("create_time desc, id desc").Offset(offset).Limit(limit).Find(&calendars).Error; err != nil {
+		return 0, nil, err
+	}
+	return count, calendars, nil
+}
+
+// Update 更新日历，日期范围全部替换
+func (d *CalendarDao) Update(calendar *Calendar, dates []*CalendarDate) error {
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"name":        calendar.Name,
+			"description": calendar.Description,
+			"timezone":    calendar.Timezone,
+			"update_by":   calendar.UpdateBy,
+		}
+		if err := tx.Model(&Calendar{}).Where("id = ?", calendar.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("calendar_id = ?", calendar.ID).Delete(&CalendarDate{}).Error; err != nil {
+			return err
+		}
+		return createCalendarDates(tx, calendar.ID, dates)
+	})
+}
+
+// Delete 删除日历、日期范围和 Token 的关联
+func (d *CalendarDao) Delete(id string) error {
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("calendar_id = ?", id).Delete(&TokenCalendar{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("calendar_id = ?", id).Delete(&CalendarDate{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&Calendar{}).Error
+	})
+}
+
+func (d *CalendarDao) ListDates(calendarIDs []string) ([]*CalendarDate, error) {
+	var dates []*CalendarDate
+	if len(calendarIDs) == 0 {
+		return dates, nil
+	}
+	if err := d.DB.Where("calendar_id in ?", calendarIDs).Order("start_date, id").Find(&dates).Error; err != nil {
+		return nil, err
+	}
+	return dates, nil
+}
+
+// ListByIDs 查询工作空间下的日历，不属于该工作空间的日历被忽略
+func (d *CalendarDao) ListByIDs(workspaceID string, ids []string) ([]*Calendar, error) {
+	var calendars []*Calendar
+	if len(ids) == 0 {
+		return calendars, nil
+	}
+	if err := d.DB.Where("workspace_id = ? and id in ?", workspaceID, ids).Order("name").Find(&calendars).Error; err != nil {
+		return nil, err
+	}
+	return calendars, nil
+}
+
+func (d *CalendarDao) ListByTokenID(tokenID string) ([]*Calendar, error) {
+	var calendars []*Calendar
+	err := d.DB.Model(&Calendar{}).
+		Joins("join `token_calendar_bindings` as tcb on tcb.calendar_id = token_calendars.id").
+		Where("tcb.token_id = ?", tokenID).
+		Order("token_calendars.name").
+		Find(&calendars).Error
+	if err != nil {
+		return nil, err
+	}
+	return calendars, nil
+}
+
+// ListTokenIDs 查询关联了日历的 Token ID
+func (d *CalendarDao) ListTokenIDs(calendarID string) ([]string, error) {
+	var tokenIDs []string
+	if err := d.DB.Model(&TokenCalendar{}).Where("calendar_id = ?", calendarID).Pluck("token_id", &tokenIDs).Error; err != nil {
+		return nil, err
+	}
+	return tokenIDs, nil
+}
+
+// ReplaceTokenCalendars 替换 Token 关联的日历，calendarIDs 为空时取消全部关联
+func (d *CalendarDao) ReplaceTokenCalendars(tokenID string, calendarIDs []string) error {
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_id = ?", tokenID).Delete(&TokenCalendar{}).Error; err != nil {
+			return err
+		}
+		if len(calendarIDs) == 0 {
+			return nil
+		}
+		bindings := make([]*TokenCalendar, 0, len(calendarIDs))
+		for _, calendarID := range calendarIDs {
+			bindings = append(bindings, &TokenCalendar{TokenID: tokenID, CalendarID: calendarID})
+		}
+		return tx.Create(&bindings).Error
+	})
+}
+
+func createCalendarDates(tx *gorm.DB, calendarID string, dates []*CalendarDate) error {
+	if len(dates) == 0 {
+		return nil
+	}
+	for _, date := range dates {
+		date.CalendarID = calendarID
+	}
+	return tx.CreateInBatches(dates, 100).Error
+}
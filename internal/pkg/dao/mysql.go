@@ -39,9 +39,15 @@ func getDBConfig(appConfig *config.AppConfig) *config.DBConfig {
 	if mysqlConf.CacheExpiration == 0 {
 		mysqlConf.CacheExpiration = 5 * time.Minute
 	}
+	if mysqlConf.CacheNegativeExpiration == 0 {
+		mysqlConf.CacheNegativeExpiration = 10 * time.Second
+	}
 	if mysqlConf.CacheCleanupInterval == 0 {
 		mysqlConf.CacheCleanupInterval = 10 * time.Minute
 	}
+	if mysqlConf.CacheMaxEntries <= 0 {
+		mysqlConf.CacheMaxEntries = 10000
+	}
 	return mysqlConf
 }
 
@@ -4,47 +4,98 @@ import (
 	"time"
 
 	"github.com/auth-engine/config"
+	"github.com/auth-engine/pkg/constants"
 	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"gorm.io/gorm"
 )
 
 type TokenAuthInfo struct {
-	UpdateTime           time.Time  `json:"updateTime"`           // Token 更新时间
-	TokenID              string     `json:"tokenId"`              // token ID
-	Token                string     `json:"token"`                // token
-	ExpiredTime          *time.Time `json:"expiredTime"`          // token 过期时间
-	AppScenarioName      string     `json:"appScenarioName"`      // 应用场景名称
-	ModelName            string     `json:"modelName"`            // 模型名称
-	EnvName              string     `json:"envName"`              // 环境名称
-	MaxConcurrency       int        `json:"maxConcurrency"`       // 最高并发量
-	EnableValidityPolicy bool       `json:"enableValidityPolicy"` // 是否启用有效期策略
-	PolicyType           string     `json:"policyType"`           // 策略类型
-	StartTime            *time.Time `json:"startTime"`            // 开始时间 08:00:00
-	EndTime              *time.Time `json:"endTime"`              // 结束时间 12:00:00
-	StartDay             string     `json:"startDay"`             // 开始日
-	EndDay               string     `json:"endDay"`               // 结束日
-	StartDate            *time.Time `json:"startDate"`            // 开始日期 2023-01-01
-	EndDate              *time.Time `json:"endDate"`              // 结束日期 2023-01-31
+	UpdateTime               time.Time  `json:"updateTime"`               // Token 更新时间
+	TokenID                  string     `json:"tokenId"`                  // token ID
+	WorkspaceID              string     `json:"workspaceId"`              // 工作空间ID
+	TokenPrefix              string     `json:"tokenPrefix"`              // token 前缀，用于展示
+	MatchedSecret            string     `json:"matchedSecret"`            // 匹配的密钥，CURRENT 或 PREVIOUS（轮换前的密钥）
+	Status                   string     `json:"status"`                   // Token 状态
+	ResumeTime               *time.Time `json:"resumeTime"`               // 停用的 Token 自动恢复时间
+	PreviousTokenExpiredTime *time.Time `json:"previousTokenExpiredTime"` // 轮换前的密钥失效时间
+	ExpiredTime              *time.Time `json:"expiredTime"`              // token 过期时间
+	AppScenarioName          string     `json:"appScenarioName"`          // 应用场景名称
+	ModelName                string     `json:"modelName"`                // 模型名称
+	ModelPatterns            string     `json:"modelPatterns"`            // 允许认证的模型，逗号分隔，支持 * 通配符
+	EnvName                  string     `json:"envName"`                  // 环境名称
+	MaxConcurrency           int        `json:"maxConcurrency"`           // 最高并发量
+	RequestsPerMinute        int        `json:"requestsPerMinute"`        // 每分钟请求数上限
+	RequestsPerDay           int        `json:"requestsPerDay"`           // 每天请求数上限
+	EnableValidityPolicy     bool       `json:"enableValidityPolicy"`     // 是否启用有效期策略
+	PolicyType               string     `json:"policyType"`               // 策略类型
+	Timezone                 string     `json:"timezone"`                 // 策略时区，策略未指定时使用 Token 的时区，都为空时使用默认时区
+	StartTime                *time.Time `json:"startTime"`                // 开始时间 08:00:00
+	EndTime                  *time.Time `json:"endTime"`                  // 结束时间 12:00:00
+	StartDay                 string     `json:"startDay"`                 // 开始日
+	EndDay                   string     `json:"endDay"`                   // 结束日
+	Continuous               bool       `json:"continuous"`               // WEEKLY 策略是否从开始日连续有效到结束日
+	StartDate                *time.Time `json:"startDate"`                // 开始日期 2023-01-01
+	EndDate                  *time.Time `json:"endDate"`                  // 结束日期 2023-01-31
+	CronExpr                 string     `json:"cronExpr"`                 // CRON 表达式
+	CronDuration             int        `json:"cronDuration"`             // CRON 策略每次触发后的有效时长，单位秒
+	CalendarCount            int        `json:"calendarCount"`            // 关联的日历数量，为 0 时认证不查询日历
 }
 
 type TokenQueryParam struct {
 	AppScenarioName string `json:"appScenarioName"` // 应用场景名称
 	ModelName       string `json:"modelName"`       // 模型名称
 	EnvName         string `json:"envName"`         // 环境名称
+	Status          string `json:"status"`          // Token 状态，ACTIVE 或 SUSPENDED
+}
+
+// TokenDigest Token 摘要，数据库中只保存摘要、前缀和后四位，不保存 Token 明文
+type TokenDigest struct {
+	TokenHash   string `json:"-" gorm:"column:token_hash;type:varchar(64);null;index:idx_token_hash,unique;comment:token HMAC-SHA256 摘要"` // token 摘要
+	TokenPrefix string `json:"tokenPrefix" gorm:"column:token_prefix;type:varchar(16);not null;default:'';comment:token 前缀"`              // token 前缀，用于展示
+	TokenLast4  string `json:"tokenLast4" gorm:"column:token_last4;type:varchar(4);not null;default:'';comment:token 后四位"`                // token 后四位，用于展示
+}
+
+// PlaintextToken 历史遗留的明文 Token，仅用于迁移
+type PlaintextToken struct {
+	ID    string `gorm:"column:id"`
+	Token string `gorm:"column:token"`
 }
 
 type TokenEntity struct {
 	CommonModel
+	TokenDigest
 	ID                   string     `json:"id" gorm:"column:id;type:varchar(64);not null;primaryKey;comment:ID"`                                                           // ID
 	WorkspaceID          string     `json:"workspaceId" gorm:"column:workspace_id;type:varchar(64);not null;index:idx_workspace_id;comment:工作空间ID"`                        // 工作空间ID
-	Token                string     `json:"token" gorm:"column:token;type:varchar(255);not null;index:idx_token,unique;comment:token"`                                     // token
 	ExpiredTime          *time.Time `json:"expiredTime" gorm:"column:expired_time;type:datetime;null;comment:过期时间"`                                                        // 过期时间
 	AppScenarioName      string     `json:"appScenarioName" gorm:"column:app_scenario_name;type:varchar(255);not null;index:idx_app_model_env_name,unique;comment:应用场景名称"` // 应用场景名称
 	ModelName            string     `json:"modelName" gorm:"column:model_name;type:varchar(255);not null;index:idx_app_model_env_name,unique;comment:模型名称"`                // 模型名称
+	ModelPatterns        string     `json:"modelPatterns" gorm:"column:model_patterns;type:varchar(1024);not null;default:'';comment:允许认证的模型"`                             // 允许认证的模型，逗号分隔，支持 * 通配符，为空时只允许 ModelName
 	EnvName              string     `json:"envName" gorm:"column:env_name;type:varchar(255);not null;index:idx_app_model_env_name,unique;comment:环境名称"`                    // 环境名称
 	EnableValidityPolicy bool       `json:"enableValidityPolicy" gorm:"column:enable_validity_policy;type:tinyint(1);not null;comment:是否启用有效期策略"`                          // 是否启用有效期策略
-	PolicyType           string     `json:"policyType" gorm:"column:policy_type;type:enum('DAILY', 'WEEKLY', 'DATERANGE', '');null;comment:策略类型"`                          // 策略类型
+	PolicyType           string     `json:"policyType" gorm:"column:policy_type;type:enum('DAILY', 'WEEKLY', 'DATERANGE', 'CRON', '');null;comment:策略类型"`                  // 策略类型
+	Timezone             string     `json:"timezone" gorm:"column:timezone;type:varchar(64);not null;default:'';comment:有效期策略时区"`                                          // 有效期策略时区，为空时使用默认时区
 	MaxConcurrency       int        `json:"maxConcurrency" gorm:"column:max_concurrency;type:int;not null;comment:最高并发量"`                                                  // 最高并发量
+	RequestsPerMinute    int        `json:"requestsPerMinute" gorm:"column:requests_per_minute;type:int;not null;default:0;comment:每分钟请求数上限，0 表示不限制"`                      // 每分钟请求数上限，0 表示不限制
+	RequestsPerDay       int        `json:"requestsPerDay" gorm:"column:requests_per_day;type:int;not null;default:0;comment:每天请求数上限，0 表示不限制"`                             // 每天请求数上限，0 表示不限制
+	// 轮换 Token 后，轮换前的密钥在 PreviousTokenExpiredTime 之前仍然有效
+	PreviousTokenHash        string     `json:"-" gorm:"column:previous_token_hash;type:varchar(64);null;index:idx_previous_token_hash;comment:轮换前的 token 摘要"` // 轮换前的 token 摘要
+	PreviousTokenExpiredTime *time.Time `json:"previousTokenExpiredTime" gorm:"column:previous_token_expired_time;type:datetime;null;comment:轮换前的 token 失效时间"` // 轮换前的 token 失效时间
+	// 停用的 Token 认证失败，ResumeTime 不为空时到达该时间后自动恢复
+	Status        string     `json:"status" gorm:"column:status;type:enum('ACTIVE', 'SUSPENDED');not null;default:'ACTIVE';index:idx_status;comment:Token 状态"` // Token 状态
+	SuspendReason string     `json:"suspendReason" gorm:"column:suspend_reason;type:varchar(255);not null;default:'';comment:停用原因"`                            // 停用原因
+	SuspendTime   *time.Time `json:"suspendTime" gorm:"column:suspend_time;type:datetime;null;comment:停用时间"`                                                   // 停用时间
+	ResumeTime    *time.Time `json:"resumeTime" gorm:"column:resume_time;type:datetime;null;comment:自动恢复时间"`                                                   // 自动恢复时间
+}
+
+// EffectiveStatus 返回 Token 当前的状态，停用的 Token 到达自动恢复时间后视为启用
+func EffectiveStatus(status string, resumeTime *time.Time, now time.Time) string {
+	if status != constants.TokenStatusSuspended {
+		return constants.TokenStatusActive
+	}
+	if resumeTime != nil && !now.Before(*resumeTime) {
+		return constants.TokenStatusActive
+	}
+	return constants.TokenStatusSuspended
 }
 
 func (*TokenEntity) TableName() string {
@@ -62,15 +113,23 @@ type TokenDao struct {
 }
 
 type ITokenDao interface {
-	GetTokenAuthInfo(token string) ([]*TokenAuthInfo, error)
+	GetTokenAuthInfo(tokenHash string) ([]*TokenAuthInfo, error)
+	GetTokenAuthInfoByID(workspaceID, id string) ([]*TokenAuthInfo, error)
 	QueryPageList(
 		workspaceID string, pageParam PageParam, orderParam OrderParam, queryParam TokenQueryParam,
 	) (int64, []*TokenEntity, error)
 	CheckTokenExists(appScenarioName, modelName, envName, id string) (bool, error)
 	Create(req *TokenEntity) error
-	Get(id string) (*TokenEntity, error)
+	Get(workspaceID, id string) (*TokenEntity, error)
+	GetByTokenHash(tokenHash string) (*TokenEntity, error)
+	GetByName(appScenarioName, modelName, envName string) (*TokenEntity, error)
 	Update(id string, req *TokenEntity) error
 	Delete(id string) error
+	ListPlaintextTokens() ([]*PlaintextToken, error)
+	UpdateDigest(id string, digest *TokenDigest) error
+	Rotate(id string, req *TokenEntity) error
+	Overwrite(id string, req *TokenEntity, policies []*TokenValidityPolicy, digest *TokenDigest) error
+	UpdateStatus(id string, req *TokenEntity) error
 }
 
 // NewAiDatasetDao return the dao interface
@@ -81,29 +140,45 @@ func NewTokenDao(db *gorm.DB) ITokenDao {
 	return &TokenDao{DB: db}
 }
 
-func (d *TokenDao) GetTokenAuthInfo(token string) ([]*TokenAuthInfo, error) {
-	var tokenAuthInfos []*TokenAuthInfo
-	selectFields := `
+// tokenAuthInfoFields Token 和有效期策略的认证字段，每个策略一条记录
+const tokenAuthInfoFields = `
 	tokens.update_time,
 	tokens.id as token_id,
-	tokens.token,
+	tokens.previous_token_expired_time,
+	tokens.status,
+	tokens.resume_time,
+	tokens.workspace_id,
+	tokens.token_prefix,
 	tokens.expired_time,
 	tokens.app_scenario_name,
 	tokens.model_name,
+	tokens.model_patterns,
 	tokens.env_name,
 	tokens.max_concurrency,
+	tokens.requests_per_minute,
+	tokens.requests_per_day,
 	tokens.enable_validity_policy,
-	tokens.policy_type,
+	ifnull(tvp.policy_type, tokens.policy_type) as policy_type,
+	if(tvp.timezone <> '', tvp.timezone, tokens.timezone) as timezone,
 	tvp.start_time,
 	tvp.end_time,
 	tvp.start_day,
 	tvp.end_day,
+	ifnull(tvp.continuous, 0) as continuous,
 	tvp.start_date,
-	tvp.end_date`
-	err := d.DB.Select(selectFields).
+	tvp.end_date,
+	ifnull(tvp.cron_expr, '') as cron_expr,
+	ifnull(tvp.cron_duration, 0) as cron_duration,
+	(select count(*) from token_calendar_bindings as tcb where tcb.token_id = tokens.id) as calendar_count`
+
+func (d *TokenDao) GetTokenAuthInfo(tokenHash string) ([]*TokenAuthInfo, error) {
+	var tokenAuthInfos []*TokenAuthInfo
+	selectFields := "case when tokens.token_hash = ? then 'CURRENT' else 'PREVIOUS' end as matched_secret," + tokenAuthInfoFields
+	err := d.DB.Select(selectFields, tokenHash).
 		Joins("left join `token_validity_policies` as tvp on tvp.token_id = tokens.id").
-		Where("tokens.token = ? and tokens.del_flag = 0 and tokens.env_name = ?", token, config.CurrentEnvName).
-		Debug().Table("tokens").Find(&tokenAuthInfos).Error
+		Where("(tokens.token_hash = ? or tokens.previous_token_hash = ?) and tokens.del_flag = 0 and tokens.env_name = ?",
+			tokenHash, tokenHash, config.CurrentEnvName).
+		Table("tokens").Find(&tokenAuthInfos).Error
 	if err != nil {
 		hlog.Errorf("get token auth info failed, err: %v", err)
 		return nil, err
@@ -111,6 +186,19 @@ func (d *TokenDao) GetTokenAuthInfo(token string) ([]*TokenAuthInfo, error) {
 	return tokenAuthInfos, nil
 }
 
+// GetTokenAuthInfoByID 按 Token ID 查询认证信息，用于解释认证结果，不区分环境
+func (d *TokenDao) GetTokenAuthInfoByID(workspaceID, id string) ([]*TokenAuthInfo, error) {
+	var tokenAuthInfos []*TokenAuthInfo
+	err := d.DB.Select("'CURRENT' as matched_secret,"+tokenAuthInfoFields).
+		Joins("left join `token_validity_policies` as tvp on tvp.token_id = tokens.id").
+		Where("tokens.id = ? and tokens.workspace_id = ? and tokens.del_flag = 0", id, workspaceID).
+		Order("tvp.id").Table("tokens").Find(&tokenAuthInfos).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokenAuthInfos, nil
+}
+
 func (d *TokenDao) QueryPageList(
 	workspaceID string, pageParam PageParam, orderParam OrderParam, queryParam TokenQueryParam,
 ) (int64, []*TokenEntity, error) {
@@ -129,6 +217,13 @@ func (d *TokenDao) QueryPageList(
 	if queryParam.EnvName != "" {
 		query = query.Where("env_name = ?", queryParam.EnvName)
 	}
+	// 已到达自动恢复时间的 Token 按启用处理
+	switch queryParam.Status {
+	case constants.TokenStatusActive:
+		query = query.Where("(status = ? or resume_time <= ?)", constants.TokenStatusActive, time.Now())
+	case constants.TokenStatusSuspended:
+		query = query.Where("status = ? and (resume_time is null or resume_time > ?)", constants.TokenStatusSuspended, time.Now())
+	}
 	err := query.Table("tokens").Count(&count).Error
 	if err != nil {
 		return 0, nil, err
@@ -168,9 +263,33 @@ func (d *TokenDao) Create(req *TokenEntity) error {
 	return d.DB.Debug().Create(req).Error
 }
 
-func (d *TokenDao) Get(id string) (*TokenEntity, error) {
+// Get 查询工作空间下的 Token，Token 不属于该工作空间时返回 gorm.ErrRecordNotFound
+func (d *TokenDao) Get(workspaceID, id string) (*TokenEntity, error) {
+	var tokenEntity TokenEntity
+	err := d.DB.Debug().Where("id = ? and workspace_id = ? and del_flag = 0", id, workspaceID).First(&tokenEntity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tokenEntity, nil
+}
+
+// GetByTokenHash 按当前密钥的摘要查询 Token，不存在时返回 gorm.ErrRecordNotFound
+func (d *TokenDao) GetByTokenHash(tokenHash string) (*TokenEntity, error) {
+	var tokenEntity TokenEntity
+	err := d.DB.Debug().Where("token_hash = ? and del_flag = 0", tokenHash).First(&tokenEntity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tokenEntity, nil
+}
+
+// GetByName 按应用场景、模型和环境查询 Token，不存在时返回 gorm.ErrRecordNotFound
+func (d *TokenDao) GetByName(appScenarioName, modelName, envName string) (*TokenEntity, error) {
 	var tokenEntity TokenEntity
-	err := d.DB.Debug().Where("id = ? and del_flag = 0", id).First(&tokenEntity).Error
+	err := d.DB.Debug().Where(
+		"app_scenario_name = ? AND model_name = ? AND env_name = ? AND del_flag = 0",
+		appScenarioName, modelName, envName,
+	).First(&tokenEntity).Error
 	if err != nil {
 		return nil, err
 	}
@@ -178,21 +297,108 @@ func (d *TokenDao) Get(id string) (*TokenEntity, error) {
 }
 
 func (d *TokenDao) Update(id string, req *TokenEntity) error {
+	return d.DB.Debug().Model(&TokenEntity{}).Where("id = ?", id).Updates(tokenUpdates(req)).Error
+}
+
+// tokenUpdates Update 和 Overwrite 更新的 Token 字段
+func tokenUpdates(req *TokenEntity) map[string]interface{} {
 	updates := map[string]interface{}{
 		"expired_time":           req.ExpiredTime,
 		"app_scenario_name":      req.AppScenarioName,
 		"model_name":             req.ModelName,
+		"model_patterns":         req.ModelPatterns,
 		"enable_validity_policy": req.EnableValidityPolicy,
 		"policy_type":            req.PolicyType,
+		"timezone":               req.Timezone,
 		"max_concurrency":        req.MaxConcurrency,
+		"requests_per_minute":    req.RequestsPerMinute,
+		"requests_per_day":       req.RequestsPerDay,
 	}
 	if req.PolicyType == "" {
 		updates["policy_type"] = gorm.Expr("NULL")
 	}
-	return d.DB.Debug().Model(&TokenEntity{}).Where("id = ?", id).Updates(updates).Error
+	return updates
 }
 
 func (d TokenDao) Delete(id string) error {
 	// return d.DB.Debug().Model(&TokenEntity{}).Where("id = ?", id).Update("del_flag", 1).Error
 	return d.DB.Debug().Model(&TokenEntity{}).Where("id = ?", id).Delete(&TokenEntity{}).Error
 }
+
+// ListPlaintextTokens 查询仍保存明文且未计算摘要的 Token，用于历史数据迁移
+func (d *TokenDao) ListPlaintextTokens() ([]*PlaintextToken, error) {
+	var tokens []*PlaintextToken
+	// 新建的库中没有 token 列，无需迁移
+	if !d.DB.Migrator().HasColumn(&TokenEntity{}, "token") {
+		return tokens, nil
+	}
+	err := d.DB.Debug().Table("tokens").Select("id, token").
+		Where("token is not null and token != '' and (token_hash is null or token_hash = '')").
+		Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// UpdateDigest 写入 Token 摘要并清空明文
+func (d *TokenDao) UpdateDigest(id string, digest *TokenDigest) error {
+	updates := map[string]interface{}{
+		"token_hash":   digest.TokenHash,
+		"token_prefix": digest.TokenPrefix,
+		"token_last4":  digest.TokenLast4,
+		"token":        gorm.Expr("NULL"),
+	}
+	return d.DB.Debug().Table("tokens").Where("id = ?", id).Updates(updates).Error
+}
+
+// Rotate 更新 Token 密钥，轮换前的密钥在 PreviousTokenExpiredTime 之前仍然有效
+func (d *TokenDao) Rotate(id string, req *TokenEntity) error {
+	updates := map[string]interface{}{
+		"token_hash":                  req.TokenHash,
+		"token_prefix":                req.TokenPrefix,
+		"token_last4":                 req.TokenLast4,
+		"previous_token_hash":         req.PreviousTokenHash,
+		"previous_token_expired_time": req.PreviousTokenExpiredTime,
+		"update_by":                   req.UpdateBy,
+		"update_time":                 req.UpdateTime,
+	}
+	return d.DB.Debug().Model(&TokenEntity{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// Overwrite 在同一个事务中更新 Token、替换有效期策略，digest 不为空时同时替换密钥，
+// 原密钥和轮换前的密钥立即失效
+func (d *TokenDao) Overwrite(id string, req *TokenEntity, policies []*TokenValidityPolicy, digest *TokenDigest) error {
+	return d.DB.Debug().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_id = ?", id).Delete(&TokenValidityPolicy{}).Error; err != nil {
+			return err
+		}
+		if len(policies) > 0 {
+			if err := tx.CreateInBatches(policies, 100).Error; err != nil {
+				return err
+			}
+		}
+		updates := tokenUpdates(req)
+		if digest != nil {
+			updates["token_hash"] = digest.TokenHash
+			updates["token_prefix"] = digest.TokenPrefix
+			updates["token_last4"] = digest.TokenLast4
+			updates["previous_token_hash"] = gorm.Expr("NULL")
+			updates["previous_token_expired_time"] = gorm.Expr("NULL")
+		}
+		return tx.Model(&TokenEntity{}).Where("id = ?", id).Updates(updates).Error
+	})
+}
+
+// UpdateStatus 更新 Token 状态、停用原因和自动恢复时间
+func (d *TokenDao) UpdateStatus(id string, req *TokenEntity) error {
+	updates := map[string]interface{}{
+		"status":         req.Status,
+		"suspend_reason": req.SuspendReason,
+		"suspend_time":   req.SuspendTime,
+		"resume_time":    req.ResumeTime,
+		"update_by":      req.UpdateBy,
+		"update_time":    req.UpdateTime,
+	}
+	return d.DB.Debug().Model(&TokenEntity{}).Where("id = ?", id).Updates(updates).Error
+}
@@ -0,0 +1,103 @@
+package dao
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TokenUsage Token 每天的认证次数统计，不存在的 Token 统计在 token_id 为空的记录中
+type TokenUsage struct {
+	ID                uint      `json:"-" gorm:"column:id;primaryKey;autoIncrement;comment:ID"`                                                                          // ID
+	TokenID           string    `json:"tokenId" gorm:"column:token_id;type:varchar(64);not null;index:idx_token_date,unique;comment:token ID"`                           // token ID
+	WorkspaceID       string    `json:"workspaceId" gorm:"column:workspace_id;type:varchar(64);not null;index:idx_workspace_date;comment:工作空间ID"`                        // 工作空间ID
+	UsageDate         time.Time `json:"usageDate" gorm:"column:usage_date;type:date;not null;index:idx_token_date,unique;index:idx_workspace_date;comment:统计日期"`         // 统计日期
+	AllowedCount      int64     `json:"allowedCount" gorm:"column:allowed_count;type:bigint;not null;default:0;comment:认证通过次数"`                                          // 认证通过次数
+	ExpiredCount      int64     `json:"expiredCount" gorm:"column:expired_count;type:bigint;not null;default:0;comment:Token 过期拒绝次数"`                                    // Token 过期拒绝次数
+	PolicyDeniedCount int64     `json:"policyDeniedCount" gorm:"column:policy_denied_count;type:bigint;not null;default:0;comment:有效期策略拒绝次数"`                            // 有效期策略拒绝次数
+	LimitedCount      int64     `json:"limitedCount" gorm:"column:limited_count;type:bigint;not null;default:0;comment:请求频率或并发限制拒绝次数"`                                   // 请求频率或并发限制拒绝次数
+	SuspendedCount    int64     `json:"suspendedCount" gorm:"column:suspended_count;type:bigint;not null;default:0;comment:Token 停用拒绝次数"`                                // Token 停用拒绝次数
+	UnknownCount      int64     `json:"unknownCount" gorm:"column:unknown_count;type:bigint;not null;default:0;comment:不存在的 Token 认证次数"`                                 // 不存在的 Token 认证次数
+	UpdateTime        time.Time `json:"updateTime,omitempty" gorm:"column:update_time;type:datetime;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;comment:更新时间"` // 更新时间
+}
+
+func (*TokenUsage) TableName() string {
+	return "token_usages"
+}
+
+func init() {
+	registerInjector(func(d *daoInit) {
+		setupTableModel(d, &TokenUsage{})
+	})
+}
+
+type TokenUsageDao struct {
+	DB *gorm.DB
+}
+
+type ITokenUsageDao interface {
+	BatchIncrease(usages []*TokenUsage) error
+	ListByTokenID(workspaceID, tokenID string, startDate, endDate time.Time) ([]*TokenUsage, error)
+	SumByWorkspaceID(workspaceID string, startDate, endDate time.Time) ([]*TokenUsage, error)
+}
+
+func NewTokenUsageDao(db *gorm.DB) ITokenUsageDao {
+	if db == nil {
+		db = GetDB()
+	}
+	return &TokenUsageDao{DB: db}
+}
+
+// BatchIncrease 累加统计次数，记录不存在时创建
+func (d *TokenUsageDao) BatchIncrease(usages []*TokenUsage) error {
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		for _, usage := range usages {
+			err := tx.Clauses(clause.OnConflict{
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"allowed_count":       gorm.Expr("allowed_count + ?", usage.AllowedCount),
+					"expired_count":       gorm.Expr("expired_count + ?", usage.ExpiredCount),
+					"policy_denied_count": gorm.Expr("policy_denied_count + ?", usage.PolicyDeniedCount),
+					"limited_count":       gorm.Expr("limited_count + ?", usage.LimitedCount),
+					"suspended_count":     gorm.Expr("suspended_count + ?", usage.SuspendedCount),
+					"unknown_count":       gorm.Expr("unknown_count + ?", usage.UnknownCount),
+				}),
+			}).Create(usage).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (d *TokenUsageDao) ListByTokenID(workspaceID, tokenID string, startDate, endDate time.Time) ([]*TokenUsage, error) {
+	var usages []*TokenUsage
+	err := d.DB.Where("workspace_id = ? and token_id = ? and usage_date between ? and ?", workspaceID, tokenID, startDate, endDate).
+		Order("usage_date").Find(&usages).Error
+	if err != nil {
+		return nil, err
+	}
+	return usages, nil
+}
+
+// SumByWorkspaceID 按天汇总工作空间下所有 Token 的统计次数
+func (d *TokenUsageDao) SumByWorkspaceID(workspaceID string, startDate, endDate time.Time) ([]*TokenUsage, error) {
+	var usages []*TokenUsage
+	selectFields := `
+	workspace_id,
+	usage_date,
+	sum(allowed_count) as allowed_count,
+	sum(expired_count) as expired_count,
+	sum(policy_denied_count) as policy_denied_count,
+	sum(limited_count) as limited_count,
+	sum(suspended_count) as suspended_count,
+	sum(unknown_count) as unknown_count`
+	err := d.DB.Model(&TokenUsage{}).Select(selectFields).
+		Where("workspace_id = ? and usage_date between ? and ?", workspaceID, startDate, endDate).
+		Group("workspace_id, usage_date").Order("usage_date").Find(&usages).Error
+	if err != nil {
+		return nil, err
+	}
+	return usages, nil
+}
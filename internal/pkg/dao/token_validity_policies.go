@@ -11,15 +11,21 @@ type TokenValidityPolicy struct {
 	ID          uint       `json:"id" gorm:"column:id;primaryKey;autoIncrement;comment:ID"`                                                                                       // ID
 	WorkspaceID string     `json:"workspaceId" gorm:"column:workspace_id;type:varchar(64);not null;index:idx_workspace_id;comment:工作空间ID"`                                        // 工作空间ID
 	TokenID     string     `json:"tokenId" gorm:"column:token_id;type:varchar(64);not null;index:idx_token_id;comment:token ID"`                                                  // token ID
-	PolicyType  string     `json:"policyType" gorm:"column:policy_type;type:enum('DAILY', 'WEEKLY', 'DATERANGE');not null;comment:策略类型"`                                          // 策略类型
+	PolicyType  string     `json:"policyType" gorm:"column:policy_type;type:enum('DAILY', 'WEEKLY', 'DATERANGE', 'CRON');not null;comment:策略类型"`                                  // 策略类型
 	StartTime   *time.Time `json:"startTime" gorm:"column:start_time;type:time;null;comment:开始时间"`                                                                                // 开始时间 08:00:00
 	EndTime     *time.Time `json:"endTime" gorm:"column:end_time;type:time;null;comment:结束时间"`                                                                                    // 结束时间 12:00:00
 	StartDay    string     `json:"startDay" gorm:"column:start_day;type:enum('MONDAY', 'TUESDAY', 'WEDNESDAY', 'THURSDAY', 'FRIDAY', 'SATURDAY', 'SUNDAY', '');null;comment:开始日"` // 开始日
 	EndDay      string     `json:"endDay" gorm:"column:end_day;type:enum('MONDAY', 'TUESDAY', 'WEDNESDAY', 'THURSDAY', 'FRIDAY', 'SATURDAY', 'SUNDAY', '');null;comment:结束日"`     // 结束日
 	StartDate   *time.Time `json:"startDate" gorm:"column:start_date;type:date;null;comment:开始日期"`                                                                                // 开始日期 2023-01-01
 	EndDate     *time.Time `json:"endDate" gorm:"column:end_date;type:date;null;comment:结束日期"`
-	CreateTime  time.Time  `json:"createTime,omitempty" gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;comment:创建时间"`
-	UpdateTime  time.Time  `json:"updateTime,omitempty" gorm:"column:update_time;type:datetime;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;comment:更新时间"` // 结束日期 2023-01-31
+	Timezone    string     `json:"timezone" gorm:"column:timezone;type:varchar(64);not null;default:'';comment:策略时区"` // 策略时区，为空时使用 Token 的时区
+	// WEEKLY 策略从 StartDay 的 StartTime 连续有效到 EndDay 的 EndTime，为 false 时每一天分别有效
+	Continuous bool `json:"continuous" gorm:"column:continuous;type:tinyint(1);not null;default:0;comment:WEEKLY 策略是否连续有效"`
+	// CRON 策略在每次触发后的 CronDuration 秒内有效
+	CronExpr     string    `json:"cronExpr" gorm:"column:cron_expr;type:varchar(255);not null;default:'';comment:CRON 表达式"`
+	CronDuration int       `json:"cronDuration" gorm:"column:cron_duration;type:int;not null;default:0;comment:CRON 策略每次触发后的有效时长，单位秒"`
+	CreateTime   time.Time `json:"createTime,omitempty" gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;comment:创建时间"`
+	UpdateTime   time.Time `json:"updateTime,omitempty" gorm:"column:update_time;type:datetime;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;comment:更新时间"` // 结束日期 2023-01-31
 }
 
 func (*TokenValidityPolicy) TableName() string {
@@ -8,14 +8,21 @@ import (
 	"github.com/cloudwego/hertz/pkg/app/server"
 	"github.com/hertz-contrib/logger/accesslog"
 	"github.com/hertz-contrib/logger/slog"
+	"github.com/redis/go-redis/v9"
 	"golang.org/x/xerrors"
 	"gorm.io/gorm"
 	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/auth-engine/config"
+	"github.com/auth-engine/internal/pkg/authn"
 	"github.com/auth-engine/internal/pkg/dao"
 	"github.com/auth-engine/internal/pkg/global/servers"
+	"github.com/auth-engine/internal/pkg/routers/ctrl"
+	"github.com/auth-engine/internal/pkg/routers/extauthz"
 	"github.com/auth-engine/internal/pkg/routers/routeinit"
+	"github.com/auth-engine/internal/pkg/store"
+	"github.com/auth-engine/internal/pkg/utils"
+	"github.com/auth-engine/pkg/clients"
 	"github.com/auth-engine/pkg/constants"
 	"github.com/auth-engine/pkg/log"
 )
@@ -35,6 +42,44 @@ func DBInit(appConfig *config.AppConfig) (*gorm.DB, error) {
 	return dao.Connect(appConfig)
 }
 
+// RedisInit Redis 初始化，未配置 URL 时返回 nil
+func RedisInit(appConfig *config.AppConfig) (*redis.Client, error) {
+	if appConfig.Redis.URL == "" {
+		return nil, nil
+	}
+	client, err := clients.NewRedisClient(appConfig.Redis.URL)
+	if err != nil {
+		return nil, xerrors.Errorf("RedisInit failed: %w", err)
+	}
+	return client, nil
+}
+
+// StoreInit 共享状态存储初始化，未配置 Redis 时使用进程内存储
+func StoreInit(appConfig *config.AppConfig, redisClient *redis.Client) store.IStore {
+	if redisClient == nil {
+		return store.NewMemoryStore(appConfig.MySQL.CacheMaxEntries, appConfig.MySQL.CacheCleanupInterval)
+	}
+	return store.NewRedisStore(redisClient, appConfig.Redis.KeyPrefix)
+}
+
+// JWTVerifierInit 管理接口 JWT 校验初始化
+func JWTVerifierInit(appConfig *config.AppConfig) (*authn.Verifier, error) {
+	verifier, err := authn.NewVerifier(appConfig.JWT)
+	if err != nil {
+		return nil, xerrors.Errorf("JWTVerifierInit failed: %w", err)
+	}
+	return verifier, nil
+}
+
+// ExportCipherInit Token 导出文件加密初始化
+func ExportCipherInit(appConfig *config.AppConfig) (*utils.ExportCipher, error) {
+	exportCipher, err := utils.NewExportCipher(appConfig.Export)
+	if err != nil {
+		return nil, xerrors.Errorf("ExportCipherInit failed: %w", err)
+	}
+	return exportCipher, nil
+}
+
 // GhippoAuthInit 初始化Ghippo认证
 func GhippoAuthInit(appConfig *config.AppConfig) (types.Interface, error) {
 	// 从配置文件中构建restConfig
@@ -68,7 +113,12 @@ func WebHertzInit(opt *servers.ServerOpt) *server.Hertz {
 	return web
 }
 
+// ExtAuthzServerInit Envoy 外部认证 gRPC 服务初始化，未配置端口时返回 nil
+func ExtAuthzServerInit(appConfig *config.AppConfig, tokenHandler *ctrl.TokenHandler) *servers.ExtAuthzServer {
+	return servers.NewExtAuthzServer(appConfig.ExtAuthz, appConfig.Tracer, extauthz.NewServer(tokenHandler))
+}
+
 // WebServer 初始化
-func WebServerInit(appConfig *config.AppConfig, ghippoSdk types.Interface, routeInit *routeinit.RouteInit, hertzServer *server.Hertz) (*servers.WebServer, error) {
-	return servers.NewWebServer(hertzServer, ghippoSdk, appConfig.Tracer, &routeinit.RouteInit{})
+func WebServerInit(appConfig *config.AppConfig, ghippoSdk types.Interface, routeInit *routeinit.RouteInit, hertzServer *server.Hertz, extAuthzServer *servers.ExtAuthzServer) (*servers.WebServer, error) {
+	return servers.NewWebServer(hertzServer, extAuthzServer, ghippoSdk, appConfig.Tracer, &routeinit.RouteInit{})
 }
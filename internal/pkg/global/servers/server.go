@@ -1,14 +1,21 @@
 package servers
 
 import (
+	"context"
 	"fmt"
+	"net"
 
 	"ghippo.io/api/wssdk/v1alpha1/types"
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/app/server"
 	hertzconfig "github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/hertz-contrib/obs-opentelemetry/provider"
 	hertztracing "github.com/hertz-contrib/obs-opentelemetry/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc"
 
 	"github.com/auth-engine/config"
 	"github.com/auth-engine/internal/pkg/routers/routeinit"
@@ -40,13 +47,45 @@ func NewServerOpt(serverConfig config.Server, tracerConfig config.Tracer) (*Serv
 	return &opt, nil
 }
 
+// ExtAuthzServer Envoy 外部认证 gRPC 服务
+type ExtAuthzServer struct {
+	GRPC *grpc.Server
+	host string
+}
+
+// NewExtAuthzServer 未配置端口时返回 nil，不启动 gRPC 服务
+func NewExtAuthzServer(extAuthzConfig config.ExtAuthz, tracerConfig config.Tracer, authServer authv3.AuthorizationServer) *ExtAuthzServer {
+	if extAuthzConfig.Port == 0 {
+		return nil
+	}
+	var opts []grpc.ServerOption
+	if tracerConfig.Enable {
+		opts = append(opts, grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	}
+	grpcServer := grpc.NewServer(opts...)
+	authv3.RegisterAuthorizationServer(grpcServer, authServer)
+	return &ExtAuthzServer{
+		GRPC: grpcServer,
+		host: fmt.Sprintf("0.0.0.0:%d", extAuthzConfig.Port),
+	}
+}
+
+func (s *ExtAuthzServer) Run() error {
+	listener, err := net.Listen("tcp", s.host)
+	if err != nil {
+		return xerrors.Errorf("Failed to listen %s: %w", s.host, err)
+	}
+	return s.GRPC.Serve(listener)
+}
+
 type WebServer struct {
 	ghippoSdk types.Interface
 	Web       *server.Hertz
+	ExtAuthz  *ExtAuthzServer
 	routeInit *routeinit.RouteInit
 }
 
-func NewWebServer(hertzServer *server.Hertz, ghippoSdk types.Interface, tracerConfig config.Tracer, routeInit *routeinit.RouteInit) (*WebServer, error) {
+func NewWebServer(hertzServer *server.Hertz, extAuthzServer *ExtAuthzServer, ghippoSdk types.Interface, tracerConfig config.Tracer, routeInit *routeinit.RouteInit) (*WebServer, error) {
 	if tracerConfig.Enable {
 		_ = provider.NewOpenTelemetryProvider(
 			provider.WithServiceName(engineService),
@@ -58,11 +97,23 @@ func NewWebServer(hertzServer *server.Hertz, ghippoSdk types.Interface, tracerCo
 	return &WebServer{
 		ghippoSdk: ghippoSdk,
 		Web:       hertzServer,
+		ExtAuthz:  extAuthzServer,
 		routeInit: routeInit,
 	}, nil
 }
 
 func (w *WebServer) Run() {
 	defer w.ghippoSdk.Stop()
+	// gRPC 服务随 Hertz 一起退出，等待处理中的认证请求完成
+	if w.ExtAuthz != nil {
+		go func() {
+			if err := w.ExtAuthz.Run(); err != nil {
+				hlog.Fatalf("ExtAuthz server failed: %v", err)
+			}
+		}()
+		w.Web.OnShutdown = append(w.Web.OnShutdown, func(ctx context.Context) {
+			w.ExtAuthz.GRPC.GracefulStop()
+		})
+	}
 	w.Web.Spin()
 }
@@ -0,0 +1,24 @@
+package models
+
+import "github.com/auth-engine/internal/pkg/dao"
+
+type AuditLogListReq struct {
+	PageParam  dao.PageParam          `json:"pageParam"`  // 分页参数
+	QueryParam dao.AuditLogQueryParam `json:"queryParam"` // 查询参数
+}
+
+// AuditRecord 一次 Token 管理操作，Before 和 After 为操作前后的 Token，用于计算变更内容
+type AuditRecord struct {
+	WorkspaceID string
+	TokenID     string
+	Action      string
+	SourceIP    string
+	Before      any
+	After       any
+}
+
+// AuditDiffItem 单个字段的变更
+type AuditDiffItem struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "github.com/auth-engine/internal/pkg/dao"
+
+// CalendarDateRange 日历中的日期范围，包含开始和结束日期
+type CalendarDateRange struct {
+	StartDate string `json:"startDate"` // 开始日期 2026-10-01
+	EndDate   string `json:"endDate"`   // 结束日期 2026-10-07，为空时与开始日期相同
+	Note      string `json:"note"`      // 备注，如节日名称
+}
+
+type CalendarListReq struct {
+	PageParam  dao.PageParam          `json:"pageParam"`  // 分页参数
+	QueryParam dao.CalendarQueryParam `json:"queryParam"` // 查询参数
+}
+
+// CalendarReq 创建或更新日历，更新时日期范围全部替换
+type CalendarReq struct {
+	Name        string               `json:"name"`        // 日历名称，同一工作空间下唯一
+	Description string               `json:"description"` // 描述
+	Timezone    string               `json:"timezone"`    // 日历时区，IANA 时区名称，如 Asia/Shanghai，为空时使用默认时区
+	Dates       []*CalendarDateRange `json:"dates"`       // 日期范围
+}
+
+type CalendarResp struct {
+	*dao.Calendar
+	Dates []*CalendarDateRange `json:"dates"` // 日期范围
+}
+
+// TokenCalendarsReq 设置 Token 关联的日历，Token 在任一日历的日期中认证失败
+type TokenCalendarsReq struct {
+	CalendarIDs []string `json:"calendarIds"` // 日历ID，为空时取消全部关联
+}
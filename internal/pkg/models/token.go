@@ -1,25 +1,77 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/auth-engine/internal/pkg/dao"
 )
 
 type TokenAuthBody struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	AcquireLease bool   `json:"acquireLease"` // 是否占用一个并发名额，为 true 时返回租约 ID，请求结束后需调用释放接口
+	Model        string `json:"model"`        // 请求的模型，为空时读取 request 中的 model，都为空时按 auth.requireModel 拒绝或不校验模型
+	// 调用方收到的 OpenAI 格式请求体，如 {"model": "gpt-4o", "messages": [...]}，只读取其中的 model
+	Request json.RawMessage `json:"request,omitempty" swaggertype:"object"`
+}
+
+// TokenAuthResp 占用并发名额时的认证结果
+type TokenAuthResp struct {
+	TokenID         string     `json:"tokenId"`                   // token ID
+	MatchedSecret   string     `json:"matchedSecret"`             // 匹配的密钥，CURRENT 或 PREVIOUS（轮换前的密钥）
+	LeaseID         string     `json:"leaseId,omitempty"`         // 租约 ID，token 未限制并发时为空
+	LeaseExpireTime *time.Time `json:"leaseExpireTime,omitempty"` // 租约过期时间，过期后名额自动释放
+}
+
+// TokenAuthResult Token 认证结果，Lease 仅在占用并发名额时返回
+type TokenAuthResult struct {
+	Info      *dao.TokenAuthInfo // 匹配的 Token 认证信息
+	RateLimit *RateLimitResult   // 请求频率限制检查结果
+	Lease     *TokenAuthResp     // 并发租约
+}
+
+// RateLimitResult Token 请求频率限制检查结果
+type RateLimitResult struct {
+	Allowed    bool               // 是否允许本次请求
+	RetryAfter time.Duration      // 被限制时，距离可以再次请求的时间
+	Windows    []*RateLimitWindow // 已配置的限制窗口
+}
+
+// RateLimitWindow 单个限制窗口的额度
+type RateLimitWindow struct {
+	Name      string // 窗口名称，Minute 或 Day
+	Limit     int    // 窗口内请求数上限
+	Remaining int    // 窗口内剩余请求数
+}
+
+// TokenReleaseBody 释放并发名额
+type TokenReleaseBody struct {
+	Token   string `json:"token"`
+	LeaseID string `json:"leaseId"` // 认证时返回的租约 ID
+}
+
+// TokenReleaseResp 释放结果
+type TokenReleaseResp struct {
+	Released bool `json:"released"` // 租约不存在或已过期时为 false
 }
 
 type ValidityPolicy struct {
 	ID         int32   `json:"id"`          // ID
 	TokenID    string  `json:"tokenId"`     // token ID
-	PolicyType string  `json:"policyType" ` // 策略类型，可以是 ‘DAILY’（每天）、‘WEEKLY’（每周）、‘DATE_RANGE’（日期范围）。
-	StartTime  *string `json:"startTime"`   // 开始时间 08:00:00, 对于 ‘DAILY’ 和 ‘WEEKLY’ 类型的策略，定义策略生效的开始和结束时间。
-	EndTime    *string `json:"endTime"`     // 结束时间 12:00:00
+	PolicyType string  `json:"policyType" ` // 策略类型，可以是 ‘DAILY’（每天）、‘WEEKLY’（每周）、‘DATERANGE’（日期范围）、‘CRON’（CRON 表达式），为空时使用 Token 的 policyType。
+	StartTime  *string `json:"startTime"`   // 开始时间 08:00:00, 对于 ‘DAILY’ 和 ‘WEEKLY’ 类型的策略，定义策略生效的开始和结束时间，包含开始时间。
+	EndTime    *string `json:"endTime"`     // 结束时间 12:00:00，不包含结束时间，早于开始时间时跨过零点
 	StartDay   string  `json:"startDay"`    // 开始日, 对于 ‘WEEKLY’ 类型的策略，定义策略生效的开始和结束星期几。
 	EndDay     string  `json:"endDay"`      // 结束日,
-	StartDate  *string `json:"startDate"`   // 开始日期 2023-01-01, 对于 ‘DATE_RANGE’ 类型的策略，定义策略生效的开始和结束日期。
+	StartDate  *string `json:"startDate"`   // 开始日期 2023-01-01, 对于 ‘DATERANGE’ 类型的策略，定义策略生效的开始和结束日期。
 	EndDate    *string `json:"endDate"`     // 结束日期 2023-01-31
+	Timezone   string  `json:"timezone"`    // 策略时区，IANA 时区名称，如 Asia/Shanghai，为空时使用 Token 的时区
+	// 对于指定了时间的 ‘WEEKLY’ 类型的策略，为 true 时从 startDay 的 startTime 连续有效到 endDay 的 endTime，例如 FRIDAY 22:00:00 到 MONDAY 06:00:00 表示周末，
+	// 为 false 时在 startDay 到 endDay 的每一天的 startTime 到 endTime 之间有效，跨过零点时延续到次日的 endTime
+	Continuous bool `json:"continuous,omitempty"`
+	// 对于 ‘CRON’ 类型的策略，在 cronExpr 每次触发后的 cronDuration 内有效，例如 cronExpr: 0 */2 * * *, cronDuration: 15m 表示每两小时有效 15 分钟
+	CronExpr     *string `json:"cronExpr,omitempty"`     // 标准 5 位 CRON 表达式，支持 @daily 等描述符，按策略时区计算
+	CronDuration *string `json:"cronDuration,omitempty"` // 每次触发后的有效时长，如 15m、1h30m
 }
 
 type TokenBaseEntity struct {
@@ -32,12 +84,20 @@ type TokenBaseEntity struct {
 	ExpiredTime          *string `json:"expiredTime,omitempty"` // 过期时间
 	AppScenarioName      string  `json:"appScenarioName"`       // 应用场景名称
 	ModelName            string  `json:"modelName"`             // 模型名称
+	ModelPatterns        string  `json:"modelPatterns"`         // 允许认证的模型，逗号分隔，支持 * 通配符，如 gpt-4o*，为空时只允许 modelName
 	EnvName              string  `json:"envName"`               // 环境名称
 	EnvAlias             string  `json:"envAlias"`              // 环境别名，用于展示
 	MaxConcurrency       int     `json:"maxConcurrency"`        // 最大并发数
+	RequestsPerMinute    int     `json:"requestsPerMinute"`     // 每分钟请求数上限，0 表示不限制
+	RequestsPerDay       int     `json:"requestsPerDay"`        // 每天请求数上限，0 表示不限制
 	EnableValidityPolicy bool    `json:"enableValidityPolicy"`  // 是否启用有效期策略
-	PolicyType           string  `json:"policyType"`            // 策略类型，可以是 ‘DAILY’（每天）、‘WEEKLY’（每周）、‘DATE_RANGE’（日期范围）。
-	Token                string  `json:"token"`                 // token
+	PolicyType           string  `json:"policyType"`            // 策略类型，可以是 ‘DAILY’（每天）、‘WEEKLY’（每周）、‘DATERANGE’（日期范围）、‘CRON’（CRON 表达式）。
+	Timezone             string  `json:"timezone"`              // 有效期策略时区，为空时使用默认时区
+	TokenPrefix          string  `json:"tokenPrefix"`           // token 前缀，用于展示
+	TokenLast4           string  `json:"tokenLast4"`            // token 后四位，用于展示
+	Status               string  `json:"status"`                // Token 状态，ACTIVE 或 SUSPENDED
+	SuspendReason        string  `json:"suspendReason"`         // 停用原因
+	ResumeTime           *string `json:"resumeTime,omitempty"`  // 自动恢复时间
 }
 
 type TokenResp struct {
@@ -45,18 +105,42 @@ type TokenResp struct {
 	UpdateTime           time.Time         `json:"updateTime,omitempty"`
 	UpdateBy             string            `json:"updateBy,omitempty"`
 	CreateBy             string            `json:"createBy,omitempty"`
-	ID                   string            `json:"id"`                    // ID
-	WorkspaceID          string            `json:"workspaceId"`           // 工作空间ID
-	ExpiredTime          *string           `json:"expiredTime,omitempty"` // 过期时间
-	AppScenarioName      string            `json:"appScenarioName"`       // 应用场景名称
-	ModelName            string            `json:"modelName"`             // 模型名称
-	EnvName              string            `json:"envName"`               // 环境名称
-	EnvAlias             string            `json:"envAlias"`              // 环境别名，用于展示
-	MaxConcurrency       int               `json:"maxConcurrency"`        // 最大并发数
-	EnableValidityPolicy bool              `json:"enableValidityPolicy"`  // 是否启用有效期策略
-	PolicyType           string            `json:"policyType"`            // 策略类型，可以是 ‘DAILY’（每天）、‘WEEKLY’（每周）、‘DATE_RANGE’（日期范围）。
-	Token                string            `json:"token"`                 // token
-	ValidityPolicy       []*ValidityPolicy `json:"validityPolicy"`        // 有效期策略
+	ID                   string            `json:"id"`                            // ID
+	WorkspaceID          string            `json:"workspaceId"`                   // 工作空间ID
+	ExpiredTime          *string           `json:"expiredTime,omitempty"`         // 过期时间
+	AppScenarioName      string            `json:"appScenarioName"`               // 应用场景名称
+	ModelName            string            `json:"modelName"`                     // 模型名称
+	ModelPatterns        string            `json:"modelPatterns"`                 // 允许认证的模型，逗号分隔，支持 * 通配符，如 gpt-4o*，为空时只允许 modelName
+	EnvName              string            `json:"envName"`                       // 环境名称
+	EnvAlias             string            `json:"envAlias"`                      // 环境别名，用于展示
+	MaxConcurrency       int               `json:"maxConcurrency"`                // 最大并发数
+	RequestsPerMinute    int               `json:"requestsPerMinute"`             // 每分钟请求数上限，0 表示不限制
+	RequestsPerDay       int               `json:"requestsPerDay"`                // 每天请求数上限，0 表示不限制
+	EnableValidityPolicy bool              `json:"enableValidityPolicy"`          // 是否启用有效期策略
+	PolicyType           string            `json:"policyType"`                    // 策略类型，可以是 ‘DAILY’（每天）、‘WEEKLY’（每周）、‘DATERANGE’（日期范围）、‘CRON’（CRON 表达式）。
+	Timezone             string            `json:"timezone"`                      // 有效期策略时区，为空时使用默认时区
+	TokenPrefix          string            `json:"tokenPrefix"`                   // token 前缀，用于展示
+	TokenLast4           string            `json:"tokenLast4"`                    // token 后四位，用于展示
+	PreviousExpiredTime  *string           `json:"previousExpiredTime,omitempty"` // 轮换前的密钥失效时间，轮换后的宽限期内返回
+	Status               string            `json:"status"`                        // Token 状态，ACTIVE 或 SUSPENDED
+	SuspendReason        string            `json:"suspendReason"`                 // 停用原因
+	SuspendTime          *string           `json:"suspendTime,omitempty"`         // 停用时间
+	ResumeTime           *string           `json:"resumeTime,omitempty"`          // 自动恢复时间
+	ValidityPolicy       []*ValidityPolicy `json:"validityPolicy"`                // 有效期策略
+}
+
+// TokenExportEntity Token 导出文件内容
+type TokenExportEntity struct {
+	TokenResp
+	TokenHash         string `json:"tokenHash"`                   // token 摘要
+	PepperFingerprint string `json:"pepperFingerprint,omitempty"` // 计算摘要使用的 pepper 指纹，导入环境的 pepper 不同时摘要无法认证
+	Token             string `json:"token,omitempty"`             // token 明文，仅用于兼容旧版本的导出文件
+}
+
+// CreateTokenResp Token 创建结果，token 明文只在创建时返回一次
+type CreateTokenResp struct {
+	*dao.TokenEntity
+	Token string `json:"token"` // token 明文
 }
 
 type TokenListReq struct {
@@ -71,10 +155,14 @@ type CreateTokenReq struct {
 	ExpiredTime          *string           `json:"expiredTime"`          // 过期时间
 	AppScenarioName      string            `json:"appScenarioName"`      // 应用场景名称
 	ModelName            string            `json:"modelName"`            // 模型名称
+	ModelPatterns        string            `json:"modelPatterns"`        // 允许认证的模型，逗号分隔，支持 * 通配符，如 gpt-4o*，为空时只允许 modelName
 	EnvName              string            `json:"envName"`              // 环境名称
 	MaxConcurrency       int               `json:"maxConcurrency"`       // 最大并发数
+	RequestsPerMinute    int               `json:"requestsPerMinute"`    // 每分钟请求数上限，0 表示不限制
+	RequestsPerDay       int               `json:"requestsPerDay"`       // 每天请求数上限，0 表示不限制
 	EnableValidityPolicy bool              `json:"enableValidityPolicy"` // 是否启用有效期策略
-	PolicyType           string            `json:"policyType"`           // 策略类型，可以是 ‘DAILY’（每天）、‘WEEKLY’（每周）、‘DATE_RANGE’（日期范围）。
+	PolicyType           string            `json:"policyType"`           // 策略类型，可以是 ‘DAILY’（每天）、‘WEEKLY’（每周）、‘DATERANGE’（日期范围）、‘CRON’（CRON 表达式），为空时使用第一个策略的类型。
+	Timezone             string            `json:"timezone"`             // 有效期策略时区，IANA 时区名称，如 Asia/Shanghai，为空时使用默认时区
 	ValidityPolicy       []*ValidityPolicy `json:"validityPolicy"`       // 有效期策略
 }
 
@@ -83,14 +171,67 @@ type UpdateTokenReq struct {
 	ExpiredTime          *string           `json:"expiredTime"`          // 过期时间           `json:"expiredTime"`          // 过期时间
 	AppScenarioName      string            `json:"appScenarioName"`      // 应用场景名称
 	ModelName            string            `json:"modelName"`            // 模型名称
+	ModelPatterns        string            `json:"modelPatterns"`        // 允许认证的模型，逗号分隔，支持 * 通配符，如 gpt-4o*，为空时只允许 modelName
 	MaxConcurrency       int               `json:"maxConcurrency"`       // 最大并发数
+	RequestsPerMinute    int               `json:"requestsPerMinute"`    // 每分钟请求数上限，0 表示不限制
+	RequestsPerDay       int               `json:"requestsPerDay"`       // 每天请求数上限，0 表示不限制
 	EnableValidityPolicy bool              `json:"enableValidityPolicy"` // 是否启用有效期策略
-	PolicyType           string            `json:"policyType"`           // 策略类型，可以是 ‘DAILY’（每天）、‘WEEKLY’（每周）、‘DATE_RANGE’（日期范围）。
+	PolicyType           string            `json:"policyType"`           // 策略类型，可以是 ‘DAILY’（每天）、‘WEEKLY’（每周）、‘DATERANGE’（日期范围）、‘CRON’（CRON 表达式），为空时使用第一个策略的类型。
+	Timezone             string            `json:"timezone"`             // 有效期策略时区，IANA 时区名称，如 Asia/Shanghai，为空时使用默认时区
 	ValidityPolicy       []*ValidityPolicy `json:"validityPolicy"`       // 有效期策略
 }
 
+// SuspendTokenReq 停用 Token
+type SuspendTokenReq struct {
+	Reason     string  `json:"reason"`     // 停用原因，可选
+	ResumeTime *string `json:"resumeTime"` // 自动恢复时间，格式为 2006-01-02 15:04:05，为空时需要手动恢复
+}
+
+// RotateTokenReq 轮换 Token 密钥
+type RotateTokenReq struct {
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds"` // 轮换前的密钥继续有效的秒数，为空时使用配置 auth.rotationGracePeriod，为 0 时立即失效
+}
+
 type ExportTokenFileReq struct {
 	WorkspaceID string `json:"workspaceId"`
 	EnvName     string `json:"envName"`
 	EnvAlias    string `json:"envAlias"`
+	Passphrase  string `json:"passphrase"` // 导出口令，不为空时使用口令派生的密钥加密，导入时需要提供相同的口令
+}
+
+// ExportTokenBundleReq 批量导出工作空间下的 Token
+type ExportTokenBundleReq struct {
+	QueryParam dao.TokenQueryParam `json:"queryParam"` // 过滤条件，为空时导出工作空间下的全部 Token
+	Passphrase string              `json:"passphrase"` // 导出口令，同 ExportTokenFileReq
+}
+
+// TokenExportBundle 批量导出文件内容
+type TokenExportBundle struct {
+	WorkspaceID string               `json:"workspaceId"` // 导出的工作空间ID
+	ExportTime  string               `json:"exportTime"`  // 导出时间
+	Tokens      []*TokenExportEntity `json:"tokens"`
+}
+
+// ImportTokenResult 批量导入时单个 Token 的处理结果
+type ImportTokenResult struct {
+	Index           int    `json:"index"` // 在导出文件中的序号，从 0 开始
+	AppScenarioName string `json:"appScenarioName"`
+	ModelName       string `json:"modelName"`
+	EnvName         string `json:"envName"`
+	Status          string `json:"status"`              // CREATED、UPDATED、SKIPPED、CONFLICT 或 ERROR
+	TokenID         string `json:"tokenId,omitempty"`   // 新建、覆盖或已存在的 Token ID，试运行时新建的 Token 没有 ID
+	RenamedTo       string `json:"renamedTo,omitempty"` // 按 rename 策略重命名后的应用场景名称
+	Message         string `json:"message,omitempty"`   // 跳过、冲突或失败的原因
+}
+
+// ImportTokenBundleResp 批量导入结果
+type ImportTokenBundleResp struct {
+	DryRun   bool                 `json:"dryRun"` // 是否为试运行，试运行时不写入数据
+	Total    int                  `json:"total"`
+	Created  int                  `json:"created"`
+	Updated  int                  `json:"updated"`
+	Skipped  int                  `json:"skipped"`
+	Conflict int                  `json:"conflict"`
+	Error    int                  `json:"error"`
+	Results  []*ImportTokenResult `json:"results"`
 }
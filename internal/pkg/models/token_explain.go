@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// ExplainTokenReq 解释 Token 在指定时间的认证结果
+type ExplainTokenReq struct {
+	Time        string `json:"time"`        // 评估时间，格式为 2006-01-02 15:04:05，按默认时区解析，为空时使用当前时间
+	Transitions int    `json:"transitions"` // 返回评估时间后一周内的生效和失效时间数量，默认 10，最大 100
+}
+
+// ExplainTokenResp 认证结果和评估过程，按认证接口的顺序依次检查状态、过期时间、有效期策略和日历
+type ExplainTokenResp struct {
+	TokenID              string                `json:"tokenId"`              // token ID
+	Time                 time.Time             `json:"time"`                 // 评估时间，使用默认时区
+	Timezone             string                `json:"timezone"`             // 默认时区，策略和日历未指定时区时使用
+	Allowed              bool                  `json:"allowed"`              // 是否认证通过，不包含请求频率和并发限制
+	Reason               string                `json:"reason"`               // 认证失败的原因，与认证接口返回的错误一致
+	Status               string                `json:"status"`               // 评估时间的 Token 状态，停用的 Token 到达自动恢复时间后视为启用
+	ResumeTime           *time.Time            `json:"resumeTime"`           // 停用的 Token 自动恢复时间
+	Expired              bool                  `json:"expired"`              // 评估时间是否已过期
+	ExpiredTime          *time.Time            `json:"expiredTime"`          // token 过期时间
+	EnableValidityPolicy bool                  `json:"enableValidityPolicy"` // 是否启用有效期策略，未启用时不检查策略
+	Policies             []*PolicyTrace        `json:"policies"`             // 每个策略的评估结果，任一策略通过即可
+	Calendars            []*CalendarTrace      `json:"calendars"`            // 每个关联日历的评估结果，命中任一日历即认证失败
+	Schedule             []*ScheduleTransition `json:"schedule"`             // 评估时间后一周内认证结果的变化时间
+}
+
+// PolicyTrace 单个有效期策略的评估结果
+type PolicyTrace struct {
+	PolicyType string `json:"policyType"` // 策略类型
+	Timezone   string `json:"timezone"`   // 策略时区，为空时使用默认时区
+	Rule       string `json:"rule"`       // 策略内容，如 WEEKLY MONDAY-FRIDAY 09:00:00-18:00:00
+	LocalTime  string `json:"localTime"`  // 评估时间在策略时区的本地时间，如 2026-10-12 20:00:00 MONDAY
+	Matched    bool   `json:"matched"`    // 是否通过
+	Reason     string `json:"reason"`     // 通过或不通过的原因
+}
+
+// CalendarTrace 单个关联日历的评估结果
+type CalendarTrace struct {
+	ID        string `json:"id"`        // 日历ID
+	Name      string `json:"name"`      // 日历名称
+	Timezone  string `json:"timezone"`  // 日历时区，为空时使用默认时区
+	LocalDate string `json:"localDate"` // 评估时间在日历时区的日期
+	Blocked   bool   `json:"blocked"`   // 是否在日历的日期中
+}
+
+// ScheduleTransition 认证结果的变化，Active 为 true 时从 Time 开始认证通过，为 false 时从 Time 开始认证失败
+type ScheduleTransition struct {
+	Time   time.Time `json:"time"`   // 变化时间，使用默认时区
+	Active bool      `json:"active"` // 变化后是否认证通过
+}
@@ -0,0 +1,27 @@
+package models
+
+// TokenUsageReq 认证次数统计查询参数，日期格式为 2006-01-02，默认查询最近 7 天
+type TokenUsageReq struct {
+	StartDate string `json:"startDate" query:"startDate"` // 开始日期，包含
+	EndDate   string `json:"endDate" query:"endDate"`     // 结束日期，包含
+}
+
+// TokenUsageResp 每天的认证次数统计
+type TokenUsageResp struct {
+	UsageDate         string `json:"usageDate"`         // 统计日期
+	AllowedCount      int64  `json:"allowedCount"`      // 认证通过次数
+	ExpiredCount      int64  `json:"expiredCount"`      // Token 过期拒绝次数
+	PolicyDeniedCount int64  `json:"policyDeniedCount"` // 有效期策略拒绝次数
+	LimitedCount      int64  `json:"limitedCount"`      // 请求频率或并发限制拒绝次数
+	SuspendedCount    int64  `json:"suspendedCount"`    // Token 停用拒绝次数
+	UnknownCount      int64  `json:"unknownCount"`      // 不存在的 Token 认证次数，只在服务级统计中不为 0
+	TotalCount        int64  `json:"totalCount"`        // 认证总次数
+}
+
+// TokenUsageSummary 统计结果，包含每天的明细和区间合计
+type TokenUsageSummary struct {
+	StartDate string            `json:"startDate"` // 开始日期
+	EndDate   string            `json:"endDate"`   // 结束日期
+	Total     *TokenUsageResp   `json:"total"`     // 区间合计，usageDate 为空
+	Daily     []*TokenUsageResp `json:"daily"`     // 每天的统计
+}
@@ -0,0 +1,54 @@
+package ctrl
+
+import (
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"golang.org/x/xerrors"
+
+	"github.com/auth-engine/internal/pkg/authn"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/routers/ctrl/common"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/pkg/constants"
+)
+
+type AuditLogHandler struct {
+	AuditService services.IAuditLogService
+}
+
+func NewAuditLogHandler(as services.IAuditLogService, cs services.IClientService, verifier *authn.Verifier, r *server.Hertz) *AuditLogHandler {
+	handler := &AuditLogHandler{AuditService: as}
+	router := r.Group("/apis/auth.engine.io/v1")
+	router.Use(common.VerifyAuthorization(verifier))
+	wsRouter := router.Group("/workspaces/:workspaceId", common.VerifyWorkspace(cs))
+	wsRouter.POST("/auditLogs/list", common.RequirePermission(cs, constants.PermissionTokenView), common.Handle(handler.List))
+	return handler
+}
+
+// List 工作空间下的 Token 操作审计日志
+// @Summary  Token 操作审计日志列表
+// @Tags 审计日志
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param data body models.AuditLogListReq true "issue params"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/auditLogs/list [post]
+// @Success 200 object models.DataResult[models.IPage[dao.AuditLog]] "成功后返回"
+// @Security Bearer
+func (h *AuditLogHandler) List(_ context.Context, c *common.CustomReqContext) (any, error) {
+	request := &models.AuditLogListReq{}
+	if err := c.Bind(request); err != nil {
+		return nil, xerrors.Errorf("Failed to bind body: %w", err)
+	}
+	workspaceID := c.Param("workspaceId")
+	if workspaceID == "" {
+		return nil, common.NewCtrlError(400, xerrors.New("Workspace ID is required."))
+	}
+	pageParam := c.GetOrDefaultPageParam(&request.PageParam)
+	count, res, err := h.AuditService.QueryPageList(workspaceID, *pageParam, request.QueryParam)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to query audit log list, Err: %w", err)
+	}
+	return common.BuildPageResp(res, count, request.PageParam), nil
+}
@@ -0,0 +1,352 @@
+package ctrl
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"golang.org/x/xerrors"
+
+	"github.com/auth-engine/internal/pkg/authn"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/routers/ctrl/common"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/internal/pkg/utils"
+	"github.com/auth-engine/pkg/constants"
+)
+
+type CalendarHandler struct {
+	CalendarService services.ICalendarService
+	TokenService    services.ITokenService
+	AuditService    services.IAuditLogService
+}
+
+func NewCalendarHandler(
+	calendarService services.ICalendarService, ts services.ITokenService, as services.IAuditLogService, cs services.IClientService,
+	verifier *authn.Verifier, r *server.Hertz,
+) *CalendarHandler {
+	handler := &CalendarHandler{CalendarService: calendarService, TokenService: ts, AuditService: as}
+	router := r.Group("/apis/auth.engine.io/v1")
+	router.Use(common.VerifyAuthorization(verifier))
+	wsRouter := router.Group("/workspaces/:workspaceId", common.VerifyWorkspace(cs))
+	view := common.RequirePermission(cs, constants.PermissionTokenView)
+	edit := common.RequirePermission(cs, constants.PermissionTokenEdit)
+	manage := common.RequirePermission(cs, constants.PermissionTokenManage)
+	wsRouter.POST("/calendars/list", view, common.Handle(handler.List))
+	wsRouter.POST("/calendars/add", edit, common.Handle(handler.CreateCalendar))
+	wsRouter.GET("/calendars/:calendarId", view, common.Handle(handler.GetCalendar))
+	wsRouter.PUT("/calendars/:calendarId", edit, common.Handle(handler.UpdateCalendar))
+	wsRouter.DELETE("/calendars/:calendarId", manage, common.Handle(handler.DeleteCalendar))
+	wsRouter.GET("/tokens/:tokenId/calendars", view, common.Handle(handler.GetTokenCalendars))
+	wsRouter.PUT("/tokens/:tokenId/calendars", edit, common.Handle(handler.SetTokenCalendars))
+	return handler
+}
+
+// CheckCalendar 检查日历名称、时区和日期范围是否合法
+func CheckCalendar(request *models.CalendarReq) *common.Error {
+	if request.Name == "" {
+		return common.NewCtrlError(400, xerrors.New("Name is required."))
+	}
+	if utf8.RuneCountInString(request.Name) > 64 {
+		return common.NewCtrlError(400, xerrors.New("Name must not exceed 64 characters."))
+	}
+	if utf8.RuneCountInString(request.Description) > 255 {
+		return common.NewCtrlError(400, xerrors.New("Description must not exceed 255 characters."))
+	}
+	if err := CheckTimezone(request.Timezone); err != nil {
+		return err
+	}
+	if len(request.Dates) > constants.CalendarMaxDates {
+		return common.NewCtrlError(400, xerrors.Errorf("dates must not exceed %d.", constants.CalendarMaxDates))
+	}
+	for _, date := range request.Dates {
+		if date == nil {
+			return common.NewCtrlError(400, xerrors.New("dates is invalid."))
+		}
+		if utf8.RuneCountInString(date.Note) > 255 {
+			return common.NewCtrlError(400, xerrors.New("Note must not exceed 255 characters."))
+		}
+		startOk, startDate := utils.CheckDateFormat(date.StartDate)
+		if !startOk {
+			return common.NewCtrlError(400, xerrors.Errorf("startDate %s is invalid.", date.StartDate))
+		}
+		if date.EndDate == "" {
+			continue
+		}
+		endOk, endDate := utils.CheckDateFormat(date.EndDate)
+		if !endOk {
+			return common.NewCtrlError(400, xerrors.Errorf("endDate %s is invalid.", date.EndDate))
+		}
+		if endDate.Before(startDate) {
+			return common.NewCtrlError(400, xerrors.Errorf("endDate %s is before startDate %s.", date.EndDate, date.StartDate))
+		}
+	}
+	return nil
+}
+
+// AuthBlackoutCalendars 检查 currentTime 是否在 Token 关联的任一日历的日期中，返回命中的日历，未命中时返回 nil
+// 日历指定了时区时按日历时区计算日期，否则使用 currentTime 的时区，日期范围包含结束日期当天
+func AuthBlackoutCalendars(currentTime time.Time, calendars []*models.CalendarResp) *models.CalendarResp {
+	for _, calendar := range calendars {
+		now := currentTime
+		if calendar.Timezone != "" {
+			location, err := utils.LoadLocation(calendar.Timezone)
+			if err != nil {
+				hlog.Errorf("load calendar timezone %s failed, CalendarID: %s, Err: %v", calendar.Timezone, calendar.ID, err)
+				continue
+			}
+			now = now.In(location)
+		}
+		today := now.Format(constants.DateRangeTimeFormat)
+		for _, date := range calendar.Dates {
+			endDate := date.EndDate
+			if endDate == "" {
+				endDate = date.StartDate
+			}
+			// 日期格式固定为 2006-01-02，可以直接按字符串比较
+			if date.StartDate <= today && today <= endDate {
+				return calendar
+			}
+		}
+	}
+	return nil
+}
+
+// findCalendarError 日历不存在时返回 404
+func findCalendarError(err error) error {
+	if xerrors.Is(err, services.ErrCalendarNotFound) {
+		return common.NewCtrlError(404, xerrors.New("Calendar not found."))
+	}
+	if xerrors.Is(err, services.ErrCalendarNameExists) {
+		return common.NewCtrlError(409, xerrors.New("Calendar name already exists."))
+	}
+	return err
+}
+
+// List 工作空间下的日历列表
+// @Summary  日历列表
+// @Tags 日历管理
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param data body models.CalendarListReq true "issue params"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/calendars/list [post]
+// @Success 200 object models.DataResult[models.IPage[models.CalendarResp]] "成功后返回"
+// @Security Bearer
+func (h *CalendarHandler) List(_ context.Context, c *common.CustomReqContext) (any, error) {
+	request := &models.CalendarListReq{}
+	if err := c.Bind(request); err != nil {
+		return nil, xerrors.Errorf("Failed to bind body: %w", err)
+	}
+	workspaceID := c.Param("workspaceId")
+	if workspaceID == "" {
+		return nil, common.NewCtrlError(400, xerrors.New("Workspace ID is required."))
+	}
+	pageParam := c.GetOrDefaultPageParam(&request.PageParam)
+	count, res, err := h.CalendarService.QueryPageList(workspaceID, *pageParam, request.QueryParam)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to query calendar list, Err: %w", err)
+	}
+	return common.BuildPageResp(res, count, request.PageParam), nil
+}
+
+// GetCalendar 日历详情
+// @Summary  日历详情
+// @Tags 日历管理
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param calendarId path string true "日历ID"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/calendars/:calendarId [get]
+// @Success 200 object models.DataResult[models.CalendarResp] "成功后返回"
+// @Security Bearer
+func (h *CalendarHandler) GetCalendar(_ context.Context, c *common.CustomReqContext) (any, error) {
+	workspaceID, calendarID := c.Params2("workspaceId", "calendarId")
+	if calendarID == "" {
+		return nil, common.NewCtrlError(400, xerrors.New("Calendar ID is required."))
+	}
+	calendar, err := h.CalendarService.Get(workspaceID, calendarID)
+	if err != nil {
+		return nil, findCalendarError(err)
+	}
+	return calendar, nil
+}
+
+// CreateCalendar 日历创建
+// @Summary  日历创建，Token 关联日历后在日历中的日期认证失败
+// @Tags 日历管理
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param data body models.CalendarReq true "issue params"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/calendars/add [post]
+// @Success 200 object models.DataResult[models.CalendarResp] "成功后返回"
+// @Security Bearer
+func (h *CalendarHandler) CreateCalendar(_ context.Context, c *common.CustomReqContext) (any, error) {
+	userInfo, exists := c.GetUser()
+	if !exists {
+		return nil, xerrors.Errorf("get userInfo failed")
+	}
+	request := &models.CalendarReq{}
+	if err := c.Bind(request); err != nil {
+		return nil, xerrors.Errorf("Failed to bind body: %w", err)
+	}
+	workspaceID := c.Param("workspaceId")
+	if workspaceID == "" {
+		return nil, common.NewCtrlError(400, xerrors.New("Workspace ID is required."))
+	}
+	if err := CheckCalendar(request); err != nil {
+		return nil, err
+	}
+	calendar, err := h.CalendarService.Create(workspaceID, request, userInfo)
+	if err != nil {
+		return nil, findCalendarError(err)
+	}
+	return calendar, nil
+}
+
+// UpdateCalendar 日历更新
+// @Summary  日历更新，日期范围全部替换，关联了该日历的 Token 立即生效
+// @Tags 日历管理
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param calendarId path string true "日历ID"
+// @Param data body models.CalendarReq true "issue params"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/calendars/:calendarId [put]
+// @Success 200 object models.DataResult[models.CalendarResp] "成功后返回"
+// @Security Bearer
+func (h *CalendarHandler) UpdateCalendar(_ context.Context, c *common.CustomReqContext) (any, error) {
+	userInfo, exists := c.GetUser()
+	if !exists {
+		return nil, xerrors.Errorf("get userInfo failed")
+	}
+	request := &models.CalendarReq{}
+	if err := c.Bind(request); err != nil {
+		return nil, xerrors.Errorf("Failed to bind body: %w", err)
+	}
+	workspaceID, calendarID := c.Params2("workspaceId", "calendarId")
+	if calendarID == "" {
+		return nil, common.NewCtrlError(400, xerrors.New("Calendar ID is required."))
+	}
+	if err := CheckCalendar(request); err != nil {
+		return nil, err
+	}
+	calendar, err := h.CalendarService.Update(workspaceID, calendarID, request, userInfo)
+	if err != nil {
+		return nil, findCalendarError(err)
+	}
+	return calendar, nil
+}
+
+// DeleteCalendar 日历删除
+// @Summary  日历删除，同时取消 Token 的关联
+// @Tags 日历管理
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param calendarId path string true "日历ID"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/calendars/:calendarId [delete]
+// @Success 200 object models.DataResult[string] "成功后返回"
+// @Security Bearer
+func (h *CalendarHandler) DeleteCalendar(_ context.Context, c *common.CustomReqContext) (any, error) {
+	workspaceID, calendarID := c.Params2("workspaceId", "calendarId")
+	if calendarID == "" {
+		return nil, common.NewCtrlError(400, xerrors.New("Calendar ID is required."))
+	}
+	if err := h.CalendarService.Delete(workspaceID, calendarID); err != nil {
+		return nil, findCalendarError(err)
+	}
+	return "delete calendar success", nil
+}
+
+// GetTokenCalendars Token 关联的日历
+// @Summary  Token 关联的日历
+// @Tags 日历管理
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param tokenId path string true "Token ID"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/tokens/:tokenId/calendars [get]
+// @Success 200 object models.DataResult[[]models.CalendarResp] "成功后返回"
+// @Security Bearer
+func (h *CalendarHandler) GetTokenCalendars(_ context.Context, c *common.CustomReqContext) (any, error) {
+	workspaceID, tokenID := c.Params2("workspaceId", "tokenId")
+	if tokenID == "" {
+		return nil, common.NewCtrlError(400, xerrors.New("Token ID is required."))
+	}
+	if _, err := h.TokenService.FindTokenEntity(workspaceID, tokenID); err != nil {
+		return nil, findTokenError(err)
+	}
+	calendars, err := h.CalendarService.ListTokenCalendars(tokenID)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to list token calendars, Err: %w", err)
+	}
+	return calendars, nil
+}
+
+// SetTokenCalendars 设置 Token 关联的日历
+// @Summary  设置 Token 关联的日历，Token 在任一日历的日期中认证失败，calendarIds 为空时取消全部关联
+// @Tags 日历管理
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param tokenId path string true "Token ID"
+// @Param data body models.TokenCalendarsReq true "issue params"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/tokens/:tokenId/calendars [put]
+// @Success 200 object models.DataResult[[]models.CalendarResp] "成功后返回"
+// @Security Bearer
+func (h *CalendarHandler) SetTokenCalendars(_ context.Context, c *common.CustomReqContext) (any, error) {
+	userInfo, exists := c.GetUser()
+	if !exists {
+		return nil, xerrors.Errorf("get userInfo failed")
+	}
+	request := &models.TokenCalendarsReq{}
+	if err := c.Bind(request); err != nil {
+		return nil, xerrors.Errorf("Failed to bind body: %w", err)
+	}
+	workspaceID, tokenID := c.Params2("workspaceId", "tokenId")
+	if tokenID == "" {
+		return nil, common.NewCtrlError(400, xerrors.New("Token ID is required."))
+	}
+	tokenEntity, err := h.TokenService.FindTokenEntity(workspaceID, tokenID)
+	if err != nil {
+		return nil, findTokenError(err)
+	}
+	before, err := h.CalendarService.ListTokenCalendars(tokenID)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to list token calendars, Err: %w", err)
+	}
+	calendars, err := h.CalendarService.SetTokenCalendars(workspaceID, tokenID, request.CalendarIDs)
+	if xerrors.Is(err, services.ErrCalendarNotFound) {
+		return nil, common.NewCtrlError(400, xerrors.New("calendarIds contains calendar not found in workspace."))
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to set token calendars, Err: %w", err)
+	}
+	// 认证信息中缓存了关联的日历数量
+	h.TokenService.InvalidateAuthCache(tokenEntity)
+	record := &models.AuditRecord{
+		WorkspaceID: workspaceID,
+		TokenID:     tokenID,
+		Action:      constants.AuditActionSetCalendars,
+		Before:      calendarNames(before),
+		After:       calendarNames(calendars),
+		SourceIP:    c.RemoteIP(),
+	}
+	if err := h.AuditService.Record(userInfo, record); err != nil {
+		hlog.Errorf("Failed to record audit log, Action: %s, TokenID: %s, Err: %v", record.Action, record.TokenID, err)
+	}
+	return calendars, nil
+}
+
+// calendarNames 审计日志中记录关联的日历，key 为日历ID，value 为日历名称
+func calendarNames(calendars []*models.CalendarResp) map[string]string {
+	names := make(map[string]string, len(calendars))
+	for _, calendar := range calendars {
+		names[calendar.ID] = calendar.Name
+	}
+	return names
+}
@@ -3,13 +3,17 @@ package common
 import (
 	"context"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"ghippo.io/api/wssdk/v1alpha1/types"
+	"golang.org/x/xerrors"
+
+	"github.com/auth-engine/internal/pkg/authn"
 	"github.com/auth-engine/internal/pkg/models"
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"github.com/cloudwego/hertz/pkg/common/utils"
-	"github.com/golang-jwt/jwt/v5"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -17,7 +21,8 @@ const (
 	AuthResultKey = "authorization"
 )
 
-func VerifyAuthorization() app.HandlerFunc {
+// VerifyAuthorization 校验 Bearer JWT 的签名、签发者、受众和有效期，校验通过后将用户信息写入上下文
+func VerifyAuthorization(verifier *authn.Verifier) app.HandlerFunc {
 	return func(ctx context.Context, c *app.RequestContext) {
 		authHeader := c.Request.Header.Get("Authorization")
 		if authHeader == "" {
@@ -34,22 +39,15 @@ func VerifyAuthorization() app.HandlerFunc {
 		}
 		token := parts[1]
 
-		// 解析JWT
-		parsedToken, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+		// 校验JWT
+		claims, err := verifier.Verify(ctx, token)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, utils.H{"error": "Failed to parse JWT"})
-			return
-		}
-		// 从token.Claims中获取sub和preferred_username字段
-		claims, ok := parsedToken.Claims.(jwt.MapClaims)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, utils.H{"error": "Failed to get token claims"})
+			hlog.CtxErrorf(ctx, "Invalid authorization token: %v", err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, utils.H{"error": "Unauthorized: Invalid authorization token."})
 			return
 		}
-		sub, _ := claims["sub"].(string)
-		preferredUsername, _ := claims["preferred_username"].(string)
 		// 将结果存储在上下文中
-		c.Set(AuthResultKey, models.AuthResult{Sub: sub, PreferredUsername: preferredUsername})
+		c.Set(AuthResultKey, models.AuthResult{Sub: claims.Subject, PreferredUsername: claims.PreferredUsername})
 
 		md := metadata.New(map[string]string{})
 		md.Set("authorization", authHeader)
@@ -59,3 +57,32 @@ func VerifyAuthorization() app.HandlerFunc {
 		c.Next(ctx)
 	}
 }
+
+// WorkspaceLister 列出当前用户可见的工作空间，由 services.IClientService 实现
+type WorkspaceLister interface {
+	ListVisibleWorkspaces(ctx context.Context) ([]types.Workspace, error)
+}
+
+// VerifyWorkspace 校验路径中的 workspaceId 在当前用户可见的工作空间中，不可见时返回 404
+// 需要在 VerifyAuthorization 之后使用，查询可见工作空间时使用请求携带的 JWT
+func VerifyWorkspace(lister WorkspaceLister) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		workspaceID := c.Param("workspaceId")
+		workspaces, err := lister.ListVisibleWorkspaces(ctx)
+		if err != nil {
+			hlog.CtxErrorf(ctx, "Failed to list visible workspaces, Err: %v", err)
+			resp := BuildErrResp(xerrors.Errorf("Failed to list visible workspaces, Err: %w", err))
+			c.AbortWithStatusJSON(resp.Code, resp)
+			return
+		}
+		for _, ws := range workspaces {
+			if strconv.Itoa(ws.WorkspaceId) == workspaceID {
+				c.Next(ctx)
+				return
+			}
+		}
+		hlog.CtxErrorf(ctx, "Workspace %s is not visible to the caller", workspaceID)
+		resp := BuildErrResp(NewCtrlError(http.StatusNotFound, xerrors.New("Not Found: Workspace not found.")))
+		c.AbortWithStatusJSON(resp.Code, resp)
+	}
+}
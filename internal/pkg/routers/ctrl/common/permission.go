@@ -0,0 +1,75 @@
+package common
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"golang.org/x/xerrors"
+
+	"github.com/auth-engine/pkg/constants"
+)
+
+// WorkspaceRoleKey 当前用户在路径工作空间中的角色，RequirePermission 校验通过后写入上下文
+const WorkspaceRoleKey = "workspaceRole"
+
+// rolePermissions Ghippo 工作空间角色对应的 Token 管理权限
+var rolePermissions = map[string][]string{
+	constants.WorkspaceRoleAdmin:  {constants.PermissionTokenView, constants.PermissionTokenEdit, constants.PermissionTokenManage},
+	constants.WorkspaceRoleEditor: {constants.PermissionTokenView, constants.PermissionTokenEdit},
+	constants.WorkspaceRoleViewer: {constants.PermissionTokenView},
+}
+
+// rolePriority 用户有多个角色时取权限最大的角色
+var rolePriority = []string{constants.WorkspaceRoleAdmin, constants.WorkspaceRoleEditor, constants.WorkspaceRoleViewer}
+
+// WorkspaceRoleGetter 查询当前用户在工作空间中的角色，由 services.IClientService 实现
+type WorkspaceRoleGetter interface {
+	GetWorkspaceRoles(ctx context.Context, workspaceID string) ([]string, error)
+}
+
+// HasPermission 角色是否拥有权限
+func HasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission 校验当前用户在路径工作空间中的角色拥有 permission，否则返回 403
+// 需要在 VerifyAuthorization 之后使用
+func RequirePermission(getter WorkspaceRoleGetter, permission string) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		workspaceID := c.Param("workspaceId")
+		roles, err := getter.GetWorkspaceRoles(ctx, workspaceID)
+		if err != nil {
+			hlog.CtxErrorf(ctx, "Failed to get workspace roles, workspace: %s, Err: %v", workspaceID, err)
+			resp := BuildErrResp(xerrors.Errorf("Failed to get workspace roles, Err: %w", err))
+			c.AbortWithStatusJSON(resp.Code, resp)
+			return
+		}
+		role := highestRole(roles)
+		if !HasPermission(role, permission) {
+			hlog.CtxErrorf(ctx, "Permission %s denied, workspace: %s, roles: %v", permission, workspaceID, roles)
+			resp := BuildErrResp(NewCtrlError(http.StatusForbidden, xerrors.New("Forbidden: Permission denied.")))
+			c.AbortWithStatusJSON(resp.Code, resp)
+			return
+		}
+		c.Set(WorkspaceRoleKey, role)
+		c.Next(ctx)
+	}
+}
+
+func highestRole(roles []string) string {
+	for _, role := range rolePriority {
+		for _, r := range roles {
+			if r == role {
+				return role
+			}
+		}
+	}
+	return ""
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"net"
 	"strconv"
 
 	"github.com/cloudwego/hertz/pkg/app"
@@ -47,6 +48,19 @@ func (c *CustomReqContext) GetOrDefaultPageParam(page *dao.PageParam) *dao.PageP
 	return page
 }
 
+// RemoteIP 连接的对端地址，不读取 X-Forwarded-For 等客户端可以伪造的请求头，用于审计日志
+func (c *CustomReqContext) RemoteIP() string {
+	addr := c.RemoteAddr()
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
 func (c *CustomReqContext) GetUser() (*models.UserInfo, bool) {
 	// get AuthResult from ctx
 	authResult, exists := c.Get(AuthResultKey)
@@ -62,5 +76,7 @@ func (c *CustomReqContext) GetUser() (*models.UserInfo, bool) {
 		ID:       result.Sub,
 		Username: result.PreferredUsername,
 	}
+	// 经过 RequirePermission 的请求携带当前用户在工作空间中的角色
+	userInfo.PlatformRole = c.GetString(WorkspaceRoleKey)
 	return userInfo, true
 }
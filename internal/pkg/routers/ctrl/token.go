@@ -7,9 +7,13 @@ import (
 	"io"
 	"net/http"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/auth-engine/config"
+	"github.com/auth-engine/internal/pkg/authn"
 	"github.com/auth-engine/internal/pkg/dao"
 	"github.com/auth-engine/internal/pkg/models"
 	"github.com/auth-engine/internal/pkg/routers/ctrl/common"
@@ -25,26 +29,61 @@ import (
 
 type TokenHandler struct {
 	TokenService services.ITokenService
-	AppConfig    *config.AppConfig
+	UsageService services.ITokenUsageService
+	AuditService services.IAuditLogService
+	// CalendarService Token 关联的节假日和封网日历，认证时在日历中的日期拒绝
+	CalendarService services.ICalendarService
+	ExportCipher    *utils.ExportCipher
+	AppConfig       *config.AppConfig
+	// Now 返回当前时间，用于有效期策略认证，测试时可以替换
+	Now func() time.Time
+	// Location 有效期策略默认时区，Token 和策略都未指定时区时使用，为空时使用服务器时区
+	Location *time.Location
 }
 
-func NewTokenHandler(ts services.ITokenService, appConfig *config.AppConfig, r *server.Hertz) *TokenHandler {
-	handler := &TokenHandler{TokenService: ts, AppConfig: appConfig}
+func NewTokenHandler(
+	ts services.ITokenService, us services.ITokenUsageService, as services.IAuditLogService, calendarService services.ICalendarService,
+	cs services.IClientService, verifier *authn.Verifier, exportCipher *utils.ExportCipher, appConfig *config.AppConfig, r *server.Hertz,
+) *TokenHandler {
+	handler := &TokenHandler{
+		TokenService: ts, UsageService: us, AuditService: as, CalendarService: calendarService,
+		ExportCipher: exportCipher, AppConfig: appConfig, Now: time.Now,
+	}
+	if appConfig.Auth.Timezone != "" {
+		location, err := utils.LoadLocation(appConfig.Auth.Timezone)
+		if err != nil {
+			hlog.Errorf("load auth.timezone %s failed, use server timezone: %v", appConfig.Auth.Timezone, err)
+		}
+		handler.Location = location
+	}
 	authRouter := r.Group("/apis/auth.engine.io")
 	authRouter.GET("/ping", handler.Ping)
 	authRouter.POST("/token/auth", common.Handle(handler.TokenAuth))
+	authRouter.GET("/token/forward-auth", handler.ForwardAuth)
+	authRouter.POST("/token/release", common.Handle(handler.TokenRelease))
 
 	router := r.Group("/apis/auth.engine.io/v1")
-	router.Use(common.VerifyAuthorization())
+	router.Use(common.VerifyAuthorization(verifier))
 	router.GET("/envs", common.Handle(handler.ListEnvs))
-	router.POST("/workspaces/:workspaceId/tokens/allEnv/list", common.Handle(handler.AllEnvList))
-	router.POST("/workspaces/:workspaceId/tokens/list", common.Handle(handler.List))
-	router.POST("/workspaces/:workspaceId/tokens/add", common.Handle(handler.CreateToken))
-	router.GET("/workspaces/:workspaceId/tokens/:tokenId", common.Handle(handler.GetToken))
-	router.PUT("/workspaces/:workspaceId/tokens/:tokenId", common.Handle(handler.UpdateToken))
-	router.DELETE("/workspaces/:workspaceId/tokens/:tokenId", common.Handle(handler.DeleteToken))
-	router.POST("/workspaces/:workspaceId/tokens/:tokenId/export", common.Handle(handler.ExportTokenFile))
-	router.POST("/workspaces/:workspaceId/tokens/import", common.Handle(handler.ImportTokenFile))
+	wsRouter := router.Group("/workspaces/:workspaceId", common.VerifyWorkspace(cs))
+	// viewer 只能查看，editor 可以创建和更新，admin 可以删除、导出和轮换
+	view := common.RequirePermission(cs, constants.PermissionTokenView)
+	edit := common.RequirePermission(cs, constants.PermissionTokenEdit)
+	manage := common.RequirePermission(cs, constants.PermissionTokenManage)
+	wsRouter.POST("/tokens/allEnv/list", view, common.Handle(handler.AllEnvList))
+	wsRouter.POST("/tokens/list", view, common.Handle(handler.List))
+	wsRouter.POST("/tokens/add", edit, common.Handle(handler.CreateToken))
+	wsRouter.GET("/tokens/:tokenId", view, common.Handle(handler.GetToken))
+	wsRouter.PUT("/tokens/:tokenId", edit, common.Handle(handler.UpdateToken))
+	wsRouter.DELETE("/tokens/:tokenId", manage, common.Handle(handler.DeleteToken))
+	wsRouter.POST("/tokens/:tokenId/export", manage, common.Handle(handler.ExportTokenFile))
+	wsRouter.POST("/tokens/:tokenId/rotate", manage, common.Handle(handler.RotateToken))
+	wsRouter.POST("/tokens/:tokenId/suspend", edit, common.Handle(handler.SuspendToken))
+	wsRouter.POST("/tokens/:tokenId/resume", edit, common.Handle(handler.ResumeToken))
+	wsRouter.POST("/tokens/:tokenId/explain", view, common.Handle(handler.ExplainToken))
+	wsRouter.POST("/tokens/import", edit, common.Handle(handler.ImportTokenFile))
+	wsRouter.POST("/tokens/bundle/export", manage, common.Handle(handler.ExportTokenBundle))
+	wsRouter.POST("/tokens/bundle/import", edit, common.Handle(handler.ImportTokenBundle))
 	return handler
 }
 
@@ -59,28 +98,71 @@ func (h *TokenHandler) Ping(_ context.Context, c *app.RequestContext) {
 	c.JSON(http.StatusOK, res)
 }
 
+// audit 记录 Token 管理操作，操作已经完成，记录失败时只打印日志
+func (h *TokenHandler) audit(c *common.CustomReqContext, userInfo *models.UserInfo, record *models.AuditRecord) {
+	record.SourceIP = c.RemoteIP()
+	if err := h.AuditService.Record(userInfo, record); err != nil {
+		hlog.Errorf("Failed to record audit log, Action: %s, TokenID: %s, Err: %v", record.Action, record.TokenID, err)
+	}
+}
+
+// auditToken 审计日志中记录的 Token 和有效期策略
+type auditToken struct {
+	dao.TokenEntity
+	ValidityPolicy []models.ValidityPolicy `json:"validityPolicy"`
+}
+
+// auditTokenWithPolicy 查询 Token 当前的有效期策略用于审计，策略每次更新时重新创建，不记录策略 ID，查询失败时只记录 Token
+func (h *TokenHandler) auditTokenWithPolicy(tokenEntity dao.TokenEntity) *auditToken {
+	record := &auditToken{TokenEntity: tokenEntity}
+	resp, err := h.TokenService.Get(tokenEntity.WorkspaceID, tokenEntity.ID)
+	if err != nil {
+		hlog.Errorf("Failed to get token validity policy for audit log, TokenID: %s, Err: %v", tokenEntity.ID, err)
+		return record
+	}
+	for _, policy := range resp.ValidityPolicy {
+		item := *policy
+		item.ID, item.TokenID = 0, ""
+		record.ValidityPolicy = append(record.ValidityPolicy, item)
+	}
+	return record
+}
+
 // CheckValidityPolicy 检查 validity_policy 是否合法
+// policyType 为默认策略类型，策略未指定 policyType 时使用，同一个 Token 可以同时包含不同类型的策略
 func CheckValidityPolicy(policyType string, policys []*models.ValidityPolicy) *common.Error {
-	// policy_type: 策略类型，可以是 DAILY（每天）、WEEKLY（每周）、DATE_RANGE（日期范围）
-	// 对于 ‘DAILY’ 和 ‘WEEKLY’ 类型的策略，定义策略生效的开始和结束时间，例如：startTime: 09:00, endTime: 18:00
-	// 对于 ‘WEEKLY’ 类型的策略，定义策略生效的开始和结束日期，例如：startDay: MONDAY, endDay: FRIDAY
-	// 对于 ‘DATE_RANGE’ 类型的策略，定义策略生效的开始和结束日期，例如：startDate: 2022-01-01, endDate: 2022-12-31
+	// policy_type: 策略类型，可以是 DAILY（每天）、WEEKLY（每周）、DATERANGE（日期范围）
+	// 对于 ‘DAILY’ 和 ‘WEEKLY’ 类型的策略，定义策略生效的开始和结束时间，例如：startTime: 09:00:00, endTime: 18:00:00，
+	// 包含开始时间，不包含结束时间，开始时间晚于结束时间时跨过零点，例如 22:00:00 到 06:00:00，开始和结束时间不能相同
+	// 对于 ‘WEEKLY’ 类型的策略，定义策略生效的开始和结束日期，例如：startDay: MONDAY, endDay: FRIDAY，开始日晚于结束日时跨周，例如 SATURDAY 到 MONDAY，
+	// 指定时间时在其中每一天的开始和结束时间之间生效，跨过零点时每一天的时间段延续到次日，
+	// continuous 为 true 时从 startDay 的 startTime 连续生效到 endDay 的 endTime，例如 FRIDAY 22:00:00 到 MONDAY 06:00:00 表示周末
+	// 对于 ‘CRON’ 类型的策略，定义 CRON 表达式和每次触发后的有效时长，例如：cronExpr: 0 */2 * * *, cronDuration: 15m
+	// 对于 ‘DATERANGE’ 类型的策略，定义策略生效的开始和结束日期，例如：startDate: 2022-01-01, endDate: 2022-12-31，包含结束日期当天
+	if policyType != "" && !isValidPolicyType(policyType) {
+		return common.NewCtrlError(400, xerrors.New("policyType is invalid."))
+	}
 	for _, policy := range policys {
-		switch policyType {
+		if policy == nil {
+			return common.NewCtrlError(400, xerrors.New("validityPolicy is invalid."))
+		}
+		if err := CheckTimezone(policy.Timezone); err != nil {
+			return err
+		}
+		itemType := policy.PolicyType
+		if itemType == "" {
+			itemType = policyType
+		}
+		if policy.Continuous && (itemType != constants.WeeklyPolicyType || policy.StartTime == nil) {
+			return common.NewCtrlError(400, xerrors.New("continuous is only supported by WEEKLY policy with startTime and endTime."))
+		}
+		switch itemType {
 		case constants.DaliyPolicyType:
 			if policy.StartTime == nil || policy.EndTime == nil {
 				return common.NewCtrlError(400, xerrors.New("startTime and endTime is required."))
 			}
-			check, startTime := utils.CheckTimeFormat(*policy.StartTime)
-			if !check {
-				return common.NewCtrlError(400, xerrors.New("startTime is invalid."))
-			}
-			check, endTime := utils.CheckTimeFormat(*policy.EndTime)
-			if !check {
-				return common.NewCtrlError(400, xerrors.New("endTime is invalid."))
-			}
-			if startTime.After(endTime) {
-				return common.NewCtrlError(400, xerrors.New("startTime must be before endTime."))
+			if err := checkPolicyTimes(*policy.StartTime, *policy.EndTime); err != nil {
+				return err
 			}
 		case constants.WeeklyPolicyType:
 			if policy.StartDay == "" || policy.EndDay == "" {
@@ -89,89 +171,238 @@ func CheckValidityPolicy(policyType string, policys []*models.ValidityPolicy) *c
 			if _, ok := constants.WeeklyDayMap[policy.StartDay]; !ok {
 				return common.NewCtrlError(400, xerrors.New("startDay is invalid."))
 			}
-			if policy.StartTime != nil && policy.EndTime != nil {
-				check, startTime := utils.CheckTimeFormat(*policy.StartTime)
-				if !check {
-					return common.NewCtrlError(400, xerrors.New("startTime is invalid."))
-				}
-				check, endTime := utils.CheckTimeFormat(*policy.StartTime)
-				if !check {
-					return common.NewCtrlError(400, xerrors.New("endTime is invalid."))
-				}
-				if startTime.After(endTime) {
-					return common.NewCtrlError(400, xerrors.New("startTime must be before endTime."))
+			if _, ok := constants.WeeklyDayMap[policy.EndDay]; !ok {
+				return common.NewCtrlError(400, xerrors.New("endDay is invalid."))
+			}
+			// 开始和结束时间可选，不指定时整天有效
+			if (policy.StartTime == nil) != (policy.EndTime == nil) {
+				return common.NewCtrlError(400, xerrors.New("startTime and endTime must be set together."))
+			}
+			if policy.StartTime != nil {
+				if err := checkPolicyTimes(*policy.StartTime, *policy.EndTime); err != nil {
+					return err
 				}
 			}
 		case constants.DateRangePolicyType:
 			if policy.StartDate == nil || policy.EndDate == nil {
 				return common.NewCtrlError(400, xerrors.New("startDate and endDate is required."))
 			}
-			check, startDate := utils.CheckTimeFormat(*policy.StartDate)
+			check, startDate := utils.CheckDateFormat(*policy.StartDate)
 			if !check {
 				return common.NewCtrlError(400, xerrors.New("startDate is invalid."))
 			}
-			check, endDate := utils.CheckTimeFormat(*policy.EndDate)
+			check, endDate := utils.CheckDateFormat(*policy.EndDate)
 			if !check {
 				return common.NewCtrlError(400, xerrors.New("endDate is invalid."))
 			}
 			if startDate.After(endDate) {
 				return common.NewCtrlError(400, xerrors.New("startDate must be before endDate."))
 			}
+		case constants.CronPolicyType:
+			if policy.CronExpr == nil || *policy.CronExpr == "" || policy.CronDuration == nil || *policy.CronDuration == "" {
+				return common.NewCtrlError(400, xerrors.New("cronExpr and cronDuration is required."))
+			}
+			schedule, err := utils.ParseCron(*policy.CronExpr)
+			if err != nil {
+				return common.NewCtrlError(400, xerrors.Errorf("cronExpr is invalid: %v", err))
+			}
+			// 例如 0 0 30 2 *，永远不会触发
+			if schedule.Next(time.Now()).IsZero() {
+				return common.NewCtrlError(400, xerrors.New("cronExpr never fires."))
+			}
+			duration, err := time.ParseDuration(*policy.CronDuration)
+			if err != nil || duration < time.Second || duration > constants.CronPolicyMaxDuration {
+				return common.NewCtrlError(400, xerrors.Errorf("cronDuration is invalid, must be between 1s and %s.", constants.CronPolicyMaxDuration))
+			}
+		default:
+			return common.NewCtrlError(400, xerrors.New("policyType is invalid."))
 		}
 	}
 	return nil
 }
 
+// CheckTimezone 检查时区是否为合法的 IANA 时区名称，为空时使用默认时区
+func CheckTimezone(timezone string) *common.Error {
+	if timezone == "" {
+		return nil
+	}
+	if _, err := utils.LoadLocation(timezone); err != nil {
+		return common.NewCtrlError(400, xerrors.Errorf("timezone %s is invalid.", timezone))
+	}
+	return nil
+}
+
+func isValidPolicyType(policyType string) bool {
+	switch policyType {
+	case constants.DaliyPolicyType, constants.WeeklyPolicyType, constants.DateRangePolicyType, constants.CronPolicyType:
+		return true
+	}
+	return false
+}
+
+// checkPolicyTimes 检查开始和结束时间的格式，开始时间晚于结束时间时跨过零点
+func checkPolicyTimes(startTime, endTime string) *common.Error {
+	if check, _ := utils.CheckTimeFormat(startTime); !check {
+		return common.NewCtrlError(400, xerrors.New("startTime is invalid."))
+	}
+	if check, _ := utils.CheckTimeFormat(endTime); !check {
+		return common.NewCtrlError(400, xerrors.New("endTime is invalid."))
+	}
+	// 相同时无法区分空区间和全天，全天有效的策略不需要指定时间
+	if startTime == endTime {
+		return common.NewCtrlError(400, xerrors.New("startTime and endTime must be different."))
+	}
+	return nil
+}
+
 // AuthValidityPolicy 认证，当前时间是否在 validity_policy 中
-// tokenAuthInfos: validity_policy 列表
-// currentTime:当前时间
+// tokenAuthInfos: validity_policy 列表，每条记录对应一个策略，策略类型可以不同
+// currentTime:当前时间，所在时区为默认时区，记录指定了时区时转换到该时区后比较
 // return: true/false, true表示 currentTime 在 validity_policy 中，false表示不在
 func AuthValidityPolicy(currentTime time.Time, tokenAuthInfos []*dao.TokenAuthInfo) bool {
 	// 检查 currentTime 是否在有效时间范围内，只要有一个策略通过即可
 	for _, info := range tokenAuthInfos {
-		switch info.PolicyType {
-		case constants.DaliyPolicyType:
-			// DAILY 策略，检查当前时间是否在 startTime 和 endTime 之间
-			_, currentTimeFormat := utils.CheckTimeFormat(currentTime.Format(constants.DaliyTimeFormat))
-			currentTimeFormat = currentTimeFormat.AddDate(1, 0, 0)
-			if currentTimeFormat.After(*info.StartTime) && currentTimeFormat.Before(*info.EndTime) {
-				return true
-			}
-		case constants.WeeklyPolicyType:
-			// WEEKLY 策略，检查当前时间是否在 startDay 和 endDay 之间
-			currentWeek := int(currentTime.Weekday())
-			// 周日是0，变成7，表示（周一 -> 周日）
-			if currentWeek == 0 {
-				currentWeek = 7
-			}
-			// StartDay 和 EndDay 是字符串，如果是空，直接跳过
-			if info.StartDay == "" || info.EndDay == "" {
-				continue
-			}
-			startDayWeek := constants.WeeklyDayMap[info.StartDay]
-			endDayWeek := constants.WeeklyDayMap[info.EndDay]
-			if startDayWeek <= currentWeek && currentWeek <= endDayWeek {
-				if info.StartTime == nil || info.EndTime == nil {
-					return true
-				} else {
-					// 如果 startTime 和 endTime 不为空，则检查当前时间是否在 startTime 和 endTime 之间
-					_, currentTimeFormat := utils.CheckTimeFormat(currentTime.Format(constants.DaliyTimeFormat))
-					currentTimeFormat = currentTimeFormat.AddDate(1, 0, 0)
-					if currentTimeFormat.After(*info.StartTime) && currentTimeFormat.Before(*info.EndTime) {
-						return true
-					}
-				}
-			}
-		case constants.DateRangePolicyType:
-			// DATE_RANGE 策略，检查当前时间是否在 startDate 和 endDate 之间
-			if currentTime.After(*info.StartDate) && currentTime.Before(*info.EndDate) {
-				return true
-			}
+		now, err := policyLocalTime(currentTime, info)
+		if err != nil {
+			hlog.Errorf("load validity policy timezone %s failed: %v", info.Timezone, err)
+			continue
+		}
+		if matchPolicy(now, info) {
+			return true
+		}
+	}
+	return false
+}
+
+// policyLocalTime 按策略所在时区的本地时间比较，夏令时切换由时区数据处理，策略未指定时区时使用 currentTime 的时区
+func policyLocalTime(currentTime time.Time, info *dao.TokenAuthInfo) (time.Time, error) {
+	if info.Timezone == "" {
+		return currentTime, nil
+	}
+	location, err := utils.LoadLocation(info.Timezone)
+	if err != nil {
+		return currentTime, err
+	}
+	return currentTime.In(location), nil
+}
+
+// matchPolicy 检查策略所在时区的本地时间 now 是否在单个策略中
+func matchPolicy(now time.Time, info *dao.TokenAuthInfo) bool {
+	switch info.PolicyType {
+	case constants.DaliyPolicyType:
+		// DAILY 策略，检查当前时间是否在 startTime 和 endTime 之间
+		return inPolicyTimeRange(now, info.StartTime, info.EndTime)
+	case constants.WeeklyPolicyType:
+		// WEEKLY 策略，检查当前时间是否在 startDay 和 endDay 之间
+		return inWeeklyPolicy(now, info)
+	case constants.DateRangePolicyType:
+		// DATERANGE 策略，检查当前日期是否在 startDate 和 endDate 之间，包含结束日期当天
+		if info.StartDate == nil || info.EndDate == nil {
+			return false
 		}
+		currentDate := dateOf(now)
+		return !currentDate.Before(dateOf(*info.StartDate)) && !currentDate.After(dateOf(*info.EndDate))
+	case constants.CronPolicyType:
+		// CRON 策略，检查最近一次触发后是否还在有效时长内
+		return inCronPolicy(now, info.CronExpr, info.CronDuration)
 	}
 	return false
 }
 
+// inPolicyTimeRange 只比较时分秒，包含开始时间，不包含结束时间，开始时间晚于结束时间时跨过零点
+func inPolicyTimeRange(currentTime time.Time, startTime, endTime *time.Time) bool {
+	if startTime == nil || endTime == nil {
+		return false
+	}
+	return inCyclicRange(secondsOfDay(currentTime), secondsOfDay(*startTime), secondsOfDay(*endTime))
+}
+
+// inWeeklyPolicy 检查当前时间是否在 WEEKLY 策略中
+// 未指定时间时 startDay 到 endDay 整天有效，指定时间时在其中每一天的 startTime 到 endTime 之间有效，
+// 跨过零点时每一天的时间段延续到次日的 endTime，Continuous 为 true 时从 startDay 的 startTime 连续有效到 endDay 的 endTime
+func inWeeklyPolicy(currentTime time.Time, info *dao.TokenAuthInfo) bool {
+	// StartDay 和 EndDay 为空或不合法时，直接跳过
+	startDay, ok := constants.WeeklyDayMap[info.StartDay]
+	if !ok {
+		return false
+	}
+	endDay, ok := constants.WeeklyDayMap[info.EndDay]
+	if !ok {
+		return false
+	}
+	currentDay := weekdayOf(currentTime)
+	if info.StartTime == nil || info.EndTime == nil {
+		return inDayRange(currentDay, startDay, endDay)
+	}
+	startTime, endTime, seconds := secondsOfDay(*info.StartTime), secondsOfDay(*info.EndTime), secondsOfDay(currentTime)
+	if info.Continuous {
+		current := (currentDay-1)*secondsPerDay + seconds
+		return inCyclicRange(current, (startDay-1)*secondsPerDay+startTime, (endDay-1)*secondsPerDay+endTime)
+	}
+	if startTime > endTime {
+		// 零点前属于当天的时间段，零点后属于前一天的时间段
+		previousDay := (currentDay+5)%7 + 1
+		return (seconds >= startTime && inDayRange(currentDay, startDay, endDay)) ||
+			(seconds < endTime && inDayRange(previousDay, startDay, endDay))
+	}
+	return inDayRange(currentDay, startDay, endDay) && inCyclicRange(seconds, startTime, endTime)
+}
+
+// inDayRange 检查当前是否在 startDay 和 endDay 之间，包含开始日和结束日，开始日晚于结束日时跨周
+func inDayRange(currentDay, startDay, endDay int) bool {
+	if startDay <= endDay {
+		return startDay <= currentDay && currentDay <= endDay
+	}
+	return currentDay >= startDay || currentDay <= endDay
+}
+
+// inCyclicRange 检查 current 是否在 [start, end) 中，start 大于 end 时跨周期，
+// start 等于 end 时为整个周期，新建的策略不允许开始和结束时间相同，只有历史数据会出现
+func inCyclicRange(current, start, end int) bool {
+	switch {
+	case start < end:
+		return start <= current && current < end
+	case start > end:
+		return current >= start || current < end
+	default:
+		return true
+	}
+}
+
+// inCronPolicy 检查 (currentTime - duration, currentTime] 中是否有触发时间，即包含触发时间，不包含有效期结束时间
+func inCronPolicy(currentTime time.Time, cronExpr string, duration int) bool {
+	if cronExpr == "" || duration <= 0 {
+		return false
+	}
+	schedule, err := utils.ParseCron(cronExpr)
+	if err != nil {
+		hlog.Errorf("parse validity policy cron %s failed: %v", cronExpr, err)
+		return false
+	}
+	// Next 返回晚于给定时间的第一次触发时间
+	fireTime := schedule.Next(currentTime.Add(-time.Duration(duration) * time.Second))
+	return !fireTime.IsZero() && !fireTime.After(currentTime)
+}
+
+const secondsPerDay = 24 * 60 * 60
+
+// weekdayOf 返回星期几，周一是 1，周日是 7，与 constants.WeeklyDayMap 一致
+func weekdayOf(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+func secondsOfDay(t time.Time) int {
+	return t.Hour()*3600 + t.Minute()*60 + t.Second()
+}
+
+// dateOf 只保留年月日，忽略时区差异
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
 // TokenAuth Token 认证
 // @Summary  Token 认证
 // @Tags Token 管理
@@ -179,43 +410,325 @@ func AuthValidityPolicy(currentTime time.Time, tokenAuthInfos []*dao.TokenAuthIn
 // @Produce  json
 // @Param data body models.TokenAuthBody true "issue params"
 // @Router /apis/auth-engine.io/token/auth [post]
-// @Success 200 object models.DataResult[string] "成功后返回"
+// @Success 200 object models.DataResult[models.TokenAuthResp] "成功后返回，acquireLease 为 true 时返回租约信息，否则返回 success"
+// @Failure 429 object models.DataResult[string] "请求数超过 requestsPerMinute / requestsPerDay，或 acquireLease 为 true 且并发数已达到 maxConcurrency"
 // @Security Bearer
-func (h *TokenHandler) TokenAuth(_ context.Context, c *common.CustomReqContext) (any, error) {
+func (h *TokenHandler) TokenAuth(ctx context.Context, c *common.CustomReqContext) (any, error) {
 	request := &models.TokenAuthBody{}
 	if err := c.Bind(request); err != nil {
 		return nil, xerrors.Errorf("Failed to bind body: %w", err)
 	}
+	result, err := h.Authenticate(ctx, request)
+	if result != nil {
+		setRateLimitHeaders(c, result.RateLimit)
+		c.Header("X-Auth-Matched-Secret", result.Info.MatchedSecret)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if result.Lease != nil {
+		return result.Lease, nil
+	}
+	return "success", nil
+}
+
+// ForwardAuth 反向代理转发认证，兼容 nginx auth_request 和 Traefik ForwardAuth
+// 从 Authorization（Bearer）或 auth.forwardAuthHeader 配置的请求头中读取 Token，只返回状态码，不返回响应体，
+// 认证通过时在响应头中返回 Token 信息，由代理复制到上游请求中。nginx auth_request 只识别 2xx、401 和 403，其他状态码按 500 处理
+// @Summary  转发认证
+// @Tags Token 管理
+// @Param Authorization header string false "Bearer sk-..."
+// @Param X-Api-Key header string false "Authorization 不是 Bearer 格式时使用，请求头名称可通过 auth.forwardAuthHeader 配置"
+// @Param X-Requested-Model header string false "请求的模型，为空时不校验模型，配置 auth.requireModel 时为空则拒绝"
+// @Router /apis/auth.engine.io/token/forward-auth [get]
+// @Success 200 "认证通过，响应头 X-Auth-Token-Id、X-Auth-Workspace-Id、X-Auth-App、X-Auth-Model、X-Auth-Env、X-Auth-Matched-Secret 为 Token 信息"
+// @Failure 401 "未提供 Token"
+// @Failure 403 "Token 无效、已停用、已过期、不在有效期内或不允许请求的模型"
+// @Failure 429 "请求数超过 requestsPerMinute / requestsPerDay"
+func (h *TokenHandler) ForwardAuth(ctx context.Context, c *app.RequestContext) {
+	result, err := h.Authenticate(ctx, &models.TokenAuthBody{
+		Token: TokenFromHeaders(c.Request.Header.Get("Authorization"), c.Request.Header.Get(h.forwardAuthHeader())),
+		Model: c.Request.Header.Get(constants.RequestedModelHeader),
+	})
+	if result != nil {
+		for key, value := range RateLimitHeaders(result.RateLimit) {
+			c.Header(key, value)
+		}
+	}
+	if err != nil {
+		code := http.StatusInternalServerError
+		if ctrlErr, ok := err.(*common.Error); ok {
+			code = ctrlErr.Code
+		}
+		if code == http.StatusInternalServerError {
+			hlog.Errorf("Forward auth failed: %+v", err)
+		}
+		c.AbortWithStatus(code)
+		return
+	}
+	info := result.Info
+	c.Header("X-Auth-Token-Id", info.TokenID)
+	c.Header("X-Auth-Workspace-Id", info.WorkspaceID)
+	c.Header("X-Auth-App", info.AppScenarioName)
+	c.Header("X-Auth-Model", info.ModelName)
+	c.Header("X-Auth-Env", info.EnvName)
+	c.Header("X-Auth-Matched-Secret", info.MatchedSecret)
+	c.Status(http.StatusOK)
+}
+
+// forwardAuthHeader 转发认证读取 Token 的请求头，未配置时使用 X-Api-Key
+func (h *TokenHandler) forwardAuthHeader() string {
+	if h.AppConfig == nil || h.AppConfig.Auth.ForwardAuthHeader == "" {
+		return "X-Api-Key"
+	}
+	return h.AppConfig.Auth.ForwardAuthHeader
+}
+
+// requireModel 认证请求是否必须携带模型
+func (h *TokenHandler) requireModel() bool {
+	return h.AppConfig != nil && h.AppConfig.Auth.RequireModel
+}
+
+// TokenFromHeaders Authorization 为 Bearer 格式时使用其中的 Token，否则使用 API Key 请求头的值
+func TokenFromHeaders(authorization, apiKey string) string {
+	if scheme, token, ok := strings.Cut(authorization, " "); ok && strings.EqualFold(scheme, "bearer") {
+		return strings.TrimSpace(token)
+	}
+	return strings.TrimSpace(apiKey)
+}
+
+// Authenticate 校验 Token 并记录认证次数，HTTP 认证接口、转发认证和 Envoy 外部认证共用，
+// 通过频率限制检查后即使认证失败（429）也返回认证结果，用于设置响应头
+func (h *TokenHandler) Authenticate(ctx context.Context, request *models.TokenAuthBody) (*models.TokenAuthResult, error) {
 	token := request.Token
 	if token == "" {
 		hlog.Error("Token is empty")
 		return nil, common.NewCtrlError(401, xerrors.New("Token is empty."))
 	}
-	tokenAuthInfos, err := h.TokenService.GetTokenAuthInfo(token)
+	tokenAuthInfos, err := h.TokenService.GetTokenAuthInfo(ctx, token)
 	if err != nil {
 		return nil, common.NewCtrlError(500, xerrors.Errorf("Internal Server Error: Failed to get token auth info, Err: %w", err))
 	}
 	if len(tokenAuthInfos) == 0 {
 		hlog.Error("Token is invalid, no token info found")
+		h.UsageService.Record("", "", constants.UsageUnknown)
 		return nil, common.NewCtrlError(403, xerrors.New("Forbidden: Token is invalid."))
 	}
+	tokenID, workspaceID := tokenAuthInfos[0].TokenID, tokenAuthInfos[0].WorkspaceID
 	// 获取当前时间,
-	currentTime := time.Now()
+	currentTime := h.Now()
+	if dao.EffectiveStatus(tokenAuthInfos[0].Status, tokenAuthInfos[0].ResumeTime, currentTime) == constants.TokenStatusSuspended {
+		hlog.Errorf("Token is suspended, TokenID: %s", tokenID)
+		h.UsageService.Record(tokenID, workspaceID, constants.UsageSuspended)
+		return nil, common.NewCtrlError(403, xerrors.New("Forbidden: Token is suspended."))
+	}
 	expiredTime := tokenAuthInfos[0].ExpiredTime
 	if expiredTime != nil && currentTime.After(*expiredTime) {
 		hlog.Error("Token is expired")
+		h.UsageService.Record(tokenID, workspaceID, constants.UsageExpired)
 		return nil, common.NewCtrlError(403, xerrors.New("Forbidden: Token is expired."))
 	}
 	// 生效策略开启，检查 currentTime 是否在有效时间范围内
 	if tokenAuthInfos[0].EnableValidityPolicy {
-		policyPass := AuthValidityPolicy(currentTime, tokenAuthInfos)
+		policyPass := AuthValidityPolicy(currentTime.In(h.location()), tokenAuthInfos)
 		if !policyPass {
 			hlog.Error("Token is invalid, no policy pass")
+			h.UsageService.Record(tokenID, workspaceID, constants.UsagePolicyDenied)
 			return nil, common.NewCtrlError(403, xerrors.New("Forbidden: Token is invalid for no policy pass"))
 		}
 	}
-	hlog.Infof("Token Auth success, Token: %s", token)
-	return "success", nil
+	// 日历与有效期策略无关，Token 关联了日历时在日历中的日期始终拒绝，没有关联日历时不查询
+	if tokenAuthInfos[0].CalendarCount > 0 {
+		calendars, err := h.CalendarService.GetTokenCalendars(ctx, tokenID)
+		if err != nil {
+			return nil, common.NewCtrlError(500, xerrors.Errorf("Internal Server Error: Failed to get token calendars, Err: %w", err))
+		}
+		if calendar := AuthBlackoutCalendars(currentTime.In(h.location()), calendars); calendar != nil {
+			hlog.Errorf("Token is blocked by calendar, TokenID: %s, Calendar: %s", tokenID, calendar.Name)
+			h.UsageService.Record(tokenID, workspaceID, constants.UsagePolicyDenied)
+			return nil, common.NewCtrlError(403, xerrors.Errorf("Forbidden: Token is blocked by calendar %s.", calendar.Name))
+		}
+	}
+	// 请求未携带模型时按 auth.requireModel 拒绝或不校验，模型不匹配时不占用请求频率额度
+	model := request.Model
+	if model == "" {
+		model = ModelFromRequestBody(request.Request)
+	}
+	if model == "" && h.requireModel() {
+		hlog.Errorf("Token auth request has no model, TokenID: %s", tokenID)
+		h.UsageService.Record(tokenID, workspaceID, constants.UsagePolicyDenied)
+		return nil, common.NewCtrlError(403, xerrors.New("Forbidden: Model is required."))
+	}
+	if model != "" && !MatchModel(model, tokenAuthInfos[0].ModelName, tokenAuthInfos[0].ModelPatterns) {
+		hlog.Errorf("Token is not allowed for model, TokenID: %s, Model: %s", tokenID, model)
+		h.UsageService.Record(tokenID, workspaceID, constants.UsagePolicyDenied)
+		return nil, common.NewCtrlError(403, xerrors.Errorf("Forbidden: Token is not allowed for model %s.", model))
+	}
+	rateLimit, err := h.TokenService.CheckRateLimit(ctx, tokenAuthInfos[0], currentTime)
+	if err != nil {
+		return nil, common.NewCtrlError(500, xerrors.Errorf("Internal Server Error: Failed to check rate limit, Err: %w", err))
+	}
+	result := &models.TokenAuthResult{Info: tokenAuthInfos[0], RateLimit: rateLimit}
+	matchedSecret := tokenAuthInfos[0].MatchedSecret
+	if !rateLimit.Allowed {
+		hlog.Errorf("Token rate limit exceeded, TokenID: %s", tokenID)
+		h.UsageService.Record(tokenID, workspaceID, constants.UsageLimited)
+		return result, common.NewCtrlError(429, xerrors.New("Too Many Requests: Token rate limit exceeded."))
+	}
+	if request.AcquireLease {
+		resp, err := h.TokenService.AcquireLease(ctx, tokenID, tokenAuthInfos[0].MaxConcurrency)
+		if err != nil {
+			// 没有拿到并发名额的请求不计入请求频率额度
+			if refundErr := h.TokenService.RefundRateLimit(ctx, tokenAuthInfos[0], rateLimit, currentTime); refundErr != nil {
+				hlog.Errorf("Failed to refund rate limit, TokenID: %s, Err: %v", tokenID, refundErr)
+			}
+			if xerrors.Is(err, services.ErrConcurrencyLimitExceeded) {
+				hlog.Errorf("Token concurrency limit exceeded, TokenID: %s", tokenID)
+				h.UsageService.Record(tokenID, workspaceID, constants.UsageLimited)
+				return result, common.NewCtrlError(429, xerrors.New("Too Many Requests: Token concurrency limit exceeded."))
+			}
+			return result, common.NewCtrlError(500, xerrors.Errorf("Internal Server Error: Failed to acquire lease, Err: %w", err))
+		}
+		resp.MatchedSecret = matchedSecret
+		hlog.Infof("Token Auth success, TokenID: %s, LeaseID: %s, MatchedSecret: %s", resp.TokenID, resp.LeaseID, matchedSecret)
+		h.UsageService.Record(tokenID, workspaceID, constants.UsageAllowed)
+		result.Lease = resp
+		return result, nil
+	}
+	hlog.Infof("Token Auth success, TokenID: %s, MatchedSecret: %s", tokenID, matchedSecret)
+	h.UsageService.Record(tokenID, workspaceID, constants.UsageAllowed)
+	return result, nil
+}
+
+// ModelFromRequestBody 读取 OpenAI 格式请求体中的 model，不是 JSON 对象时返回空
+func ModelFromRequestBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var request struct {
+		Model string `json:"model"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return ""
+	}
+	return request.Model
+}
+
+// MatchModel 请求的模型与 Token 的模型名称相同，或匹配任一模型规则时通过
+func MatchModel(model, modelName, modelPatterns string) bool {
+	if model == modelName {
+		return true
+	}
+	for _, pattern := range strings.Split(modelPatterns, ",") {
+		if pattern != "" && matchWildcard(pattern, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWildcard * 匹配任意长度的字符，如 gpt-4o* 匹配 gpt-4o 和 gpt-4o-mini
+func matchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		index := strings.Index(s, part)
+		if index < 0 {
+			return false
+		}
+		s = s[index+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
+
+// NormalizeModelPatterns 去掉模型规则中的空白和空规则
+func NormalizeModelPatterns(modelPatterns string) string {
+	var patterns []string
+	for _, pattern := range strings.Split(modelPatterns, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return strings.Join(patterns, ",")
+}
+
+// CheckModelPatterns 检查规范化后的模型规则，规则中不能包含空白
+func CheckModelPatterns(modelPatterns string) *common.Error {
+	if len(modelPatterns) > constants.ModelPatternsMaxLength {
+		return common.NewCtrlError(400, xerrors.Errorf("modelPatterns must not exceed %d characters.", constants.ModelPatternsMaxLength))
+	}
+	if strings.ContainsAny(modelPatterns, " \t\r\n") {
+		return common.NewCtrlError(400, xerrors.New("modelPatterns must not contain whitespace."))
+	}
+	return nil
+}
+
+// location 有效期策略默认时区
+func (h *TokenHandler) location() *time.Location {
+	if h.Location == nil {
+		return time.Local
+	}
+	return h.Location
+}
+
+// setRateLimitHeaders 返回剩余请求数，被限制时返回 Retry-After（秒）
+func setRateLimitHeaders(c *common.CustomReqContext, rateLimit *models.RateLimitResult) {
+	for key, value := range RateLimitHeaders(rateLimit) {
+		c.Header(key, value)
+	}
+}
+
+// RateLimitHeaders 频率限制响应头，Envoy 外部认证返回相同的响应头
+func RateLimitHeaders(rateLimit *models.RateLimitResult) map[string]string {
+	headers := make(map[string]string, 2*len(rateLimit.Windows)+1)
+	for _, window := range rateLimit.Windows {
+		headers["X-RateLimit-Limit-"+window.Name] = strconv.Itoa(window.Limit)
+		headers["X-RateLimit-Remaining-"+window.Name] = strconv.Itoa(window.Remaining)
+	}
+	if !rateLimit.Allowed {
+		headers["Retry-After"] = strconv.Itoa(int(rateLimit.RetryAfter.Seconds()))
+	}
+	return headers
+}
+
+// TokenRelease 释放 Token 认证时占用的并发名额
+// @Summary  释放并发名额
+// @Tags Token 管理
+// @Accept  json
+// @Produce  json
+// @Param data body models.TokenReleaseBody true "issue params"
+// @Router /apis/auth-engine.io/token/release [post]
+// @Success 200 object models.DataResult[models.TokenReleaseResp] "成功后返回"
+// @Security Bearer
+func (h *TokenHandler) TokenRelease(ctx context.Context, c *common.CustomReqContext) (any, error) {
+	request := &models.TokenReleaseBody{}
+	if err := c.Bind(request); err != nil {
+		return nil, xerrors.Errorf("Failed to bind body: %w", err)
+	}
+	if request.Token == "" {
+		return nil, common.NewCtrlError(401, xerrors.New("Token is empty."))
+	}
+	if request.LeaseID == "" {
+		return nil, common.NewCtrlError(400, xerrors.New("Lease ID is required."))
+	}
+	tokenAuthInfos, err := h.TokenService.GetTokenAuthInfo(ctx, request.Token)
+	if err != nil {
+		return nil, common.NewCtrlError(500, xerrors.Errorf("Internal Server Error: Failed to get token auth info, Err: %w", err))
+	}
+	if len(tokenAuthInfos) == 0 {
+		return nil, common.NewCtrlError(403, xerrors.New("Forbidden: Token is invalid."))
+	}
+	released, err := h.TokenService.ReleaseLease(ctx, tokenAuthInfos[0].TokenID, request.LeaseID)
+	if err != nil {
+		return nil, common.NewCtrlError(500, xerrors.Errorf("Internal Server Error: Failed to release lease, Err: %w", err))
+	}
+	return &models.TokenReleaseResp{Released: released}, nil
 }
 
 // ListEnvs 获取环境列表
@@ -249,6 +762,9 @@ func (h *TokenHandler) List(_ context.Context, c *common.CustomReqContext) (any,
 	}
 	// 设置当前部署环境名称
 	request.QueryParam.EnvName = config.CurrentEnvName
+	if err := checkStatusParam(request.QueryParam.Status); err != nil {
+		return nil, err
+	}
 	workspaceID := c.Param("workspaceId")
 	pageParam := c.GetOrDefaultPageParam(&request.PageParam)
 	count, res, err := h.TokenService.QueryPageList(workspaceID, *pageParam, request.OrderParam, request.QueryParam)
@@ -258,6 +774,23 @@ func (h *TokenHandler) List(_ context.Context, c *common.CustomReqContext) (any,
 	return common.BuildPageResp(res, count, request.PageParam), nil
 }
 
+// findTokenError Token 不存在或不属于路径中的工作空间时返回 404
+func findTokenError(err error) error {
+	if xerrors.Is(err, services.ErrTokenNotFound) {
+		return common.NewCtrlError(404, xerrors.New("Not Found: Token not found."))
+	}
+	return xerrors.Errorf("Failed to get token, Err: %w", err)
+}
+
+// checkStatusParam 校验列表查询的 Token 状态，为空时不过滤
+func checkStatusParam(status string) error {
+	switch status {
+	case "", constants.TokenStatusActive, constants.TokenStatusSuspended:
+		return nil
+	}
+	return common.NewCtrlError(400, xerrors.New("status must be ACTIVE or SUSPENDED."))
+}
+
 // AllEnvList 所有环境Token列表
 // @Summary  所有环境Token列表
 // @Tags Token 管理
@@ -273,6 +806,9 @@ func (h *TokenHandler) AllEnvList(_ context.Context, c *common.CustomReqContext)
 	if err := c.Bind(request); err != nil {
 		return nil, xerrors.Errorf("Failed to bind body: %w", err)
 	}
+	if err := checkStatusParam(request.QueryParam.Status); err != nil {
+		return nil, err
+	}
 	workspaceID := c.Param("workspaceId")
 	pageParam := c.GetOrDefaultPageParam(&request.PageParam)
 	count, res, err := h.TokenService.QueryPageList(workspaceID, *pageParam, request.OrderParam, request.QueryParam)
@@ -293,13 +829,13 @@ func (h *TokenHandler) AllEnvList(_ context.Context, c *common.CustomReqContext)
 // @Success 200 object models.DataResult[models.TokenResp] "成功后返回"
 // @Security Bearer
 func (h *TokenHandler) GetToken(_ context.Context, c *common.CustomReqContext) (any, error) {
-	tokenID := c.Param("tokenId")
+	workspaceID, tokenID := c.Params2("workspaceId", "tokenId")
 	if tokenID == "" {
 		return nil, common.NewCtrlError(400, xerrors.New("Token ID is required."))
 	}
-	tokenEntity, err := h.TokenService.Get(tokenID)
+	tokenEntity, err := h.TokenService.Get(workspaceID, tokenID)
 	if err != nil {
-		return nil, xerrors.Errorf("Failed to get token, Err: %w", err)
+		return nil, findTokenError(err)
 	}
 	return tokenEntity, nil
 }
@@ -312,7 +848,7 @@ func (h *TokenHandler) GetToken(_ context.Context, c *common.CustomReqContext) (
 // @Param workspaceId path string true "工作空间ID"
 // @Param data body models.CreateTokenReq true "issue params"
 // @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/tokens/add [post]
-// @Success 200 object models.DataResult[dao.TokenEntity] "成功后返回"
+// @Success 200 object models.DataResult[models.CreateTokenResp] "成功后返回，token 明文只返回这一次"
 // @Security Bearer
 func (h *TokenHandler) CreateToken(_ context.Context, c *common.CustomReqContext) (any, error) {
 	userInfo, exists := c.GetUser()
@@ -329,6 +865,9 @@ func (h *TokenHandler) CreateToken(_ context.Context, c *common.CustomReqContext
 	if request.ModelName == "" {
 		return nil, common.NewCtrlError(400, xerrors.New("Model Name is required."))
 	}
+	if request.RequestsPerMinute < 0 || request.RequestsPerDay < 0 {
+		return nil, common.NewCtrlError(400, xerrors.New("requestsPerMinute and requestsPerDay must not be negative."))
+	}
 	workspaceID := c.Param("workspaceId")
 	if workspaceID == "" {
 		return nil, common.NewCtrlError(400, xerrors.New("Workspace ID is required."))
@@ -346,18 +885,20 @@ func (h *TokenHandler) CreateToken(_ context.Context, c *common.CustomReqContext
 		expiredTimeStr := expiredTime.Format(constants.TimeFormat)
 		request.ExpiredTime = &expiredTimeStr
 	}
+	request.ModelPatterns = NormalizeModelPatterns(request.ModelPatterns)
+	if err := CheckModelPatterns(request.ModelPatterns); err != nil {
+		return nil, err
+	}
 	// policy_type: 策略类型，可以是 DAILY（每天）、WEEKLY（每周）、DATE_RANGE（日期范围）
+	if err := CheckTimezone(request.Timezone); err != nil {
+		return nil, err
+	}
 	if request.EnableValidityPolicy {
-		policyType := request.PolicyType
-		if policyType == "" || len(request.ValidityPolicy) == 0 {
-			return nil, common.NewCtrlError(400, xerrors.New("policyType and validityPolicy is required."))
+		if len(request.ValidityPolicy) == 0 {
+			return nil, common.NewCtrlError(400, xerrors.New("validityPolicy is required."))
 		}
-		// 当前只做 DAILY 类型
-		if policyType != constants.DaliyPolicyType {
-			return nil, common.NewCtrlError(400, xerrors.New("policyType is invalid."))
-		}
-		// 校验策略
-		if err := CheckValidityPolicy(policyType, request.ValidityPolicy); err != nil {
+		// 校验策略，每个策略可以单独指定类型，未指定时使用 policyType
+		if err := CheckValidityPolicy(request.PolicyType, request.ValidityPolicy); err != nil {
 			return nil, err
 		}
 	}
@@ -372,10 +913,16 @@ func (h *TokenHandler) CreateToken(_ context.Context, c *common.CustomReqContext
 	if exists {
 		return nil, common.NewCtrlError(409, xerrors.New("Token with appScenarioName and modelName already exists."))
 	}
-	tokenEntity, err := h.TokenService.Create(request, userInfo, "")
+	tokenEntity, err := h.TokenService.Create(request, userInfo, nil)
 	if err != nil {
 		return nil, xerrors.Errorf("Failed to create token, Err: %w", err)
 	}
+	h.audit(c, userInfo, &models.AuditRecord{
+		WorkspaceID: workspaceID,
+		TokenID:     tokenEntity.ID,
+		Action:      constants.AuditActionCreate,
+		After:       tokenEntity.TokenEntity,
+	})
 	return tokenEntity, nil
 }
 
@@ -398,7 +945,7 @@ func (h *TokenHandler) UpdateToken(_ context.Context, c *common.CustomReqContext
 	if err := c.Bind(request); err != nil {
 		return nil, xerrors.Errorf("Failed to bind body: %w", err)
 	}
-	tokenID := c.Param("tokenId")
+	workspaceID, tokenID := c.Params2("workspaceId", "tokenId")
 	if tokenID == "" {
 		return nil, common.NewCtrlError(400, xerrors.New("Token ID is required."))
 	}
@@ -408,6 +955,9 @@ func (h *TokenHandler) UpdateToken(_ context.Context, c *common.CustomReqContext
 	if request.ModelName == "" {
 		return nil, common.NewCtrlError(400, xerrors.New("Model Name is required."))
 	}
+	if request.RequestsPerMinute < 0 || request.RequestsPerDay < 0 {
+		return nil, common.NewCtrlError(400, xerrors.New("requestsPerMinute and requestsPerDay must not be negative."))
+	}
 	// 校验过期时间
 	if request.ExpiredTime != nil {
 		ok, expiredTime := utils.CheckDateTimeFormat(*request.ExpiredTime)
@@ -420,24 +970,26 @@ func (h *TokenHandler) UpdateToken(_ context.Context, c *common.CustomReqContext
 		expiredTimeStr := expiredTime.Format(constants.TimeFormat)
 		request.ExpiredTime = &expiredTimeStr
 	}
+	request.ModelPatterns = NormalizeModelPatterns(request.ModelPatterns)
+	if err := CheckModelPatterns(request.ModelPatterns); err != nil {
+		return nil, err
+	}
 	// policy_type: 策略类型，可以是 DAILY（每天）、WEEKLY（每周）、DATE_RANGE（日期范围）
+	if err := CheckTimezone(request.Timezone); err != nil {
+		return nil, err
+	}
 	if request.EnableValidityPolicy {
-		policyType := request.PolicyType
-		if policyType == "" || len(request.ValidityPolicy) == 0 {
-			return nil, common.NewCtrlError(400, xerrors.New("policyType and validityPolicy is required."))
+		if len(request.ValidityPolicy) == 0 {
+			return nil, common.NewCtrlError(400, xerrors.New("validityPolicy is required."))
 		}
-		// 当前只做 DAILY 类型
-		if policyType != constants.DaliyPolicyType {
-			return nil, common.NewCtrlError(400, xerrors.New("policyType is invalid."))
-		}
-		// 校验策略
-		if err := CheckValidityPolicy(policyType, request.ValidityPolicy); err != nil {
+		// 校验策略，每个策略可以单独指定类型，未指定时使用 policyType
+		if err := CheckValidityPolicy(request.PolicyType, request.ValidityPolicy); err != nil {
 			return nil, err
 		}
 	}
-	tokenQuery, err := h.TokenService.FindTokenEntity(tokenID)
+	tokenQuery, err := h.TokenService.FindTokenEntity(workspaceID, tokenID)
 	if err != nil {
-		return nil, xerrors.Errorf("failed to get token: %v", err)
+		return nil, findTokenError(err)
 	}
 	// check token exists
 	exists, err = h.TokenService.CheckTokenExists(request.AppScenarioName, request.ModelName, tokenQuery.EnvName, tokenID)
@@ -447,10 +999,18 @@ func (h *TokenHandler) UpdateToken(_ context.Context, c *common.CustomReqContext
 	if exists {
 		return nil, common.NewCtrlError(409, xerrors.New("Token with appScenarioName and modelName already exists."))
 	}
+	before := h.auditTokenWithPolicy(*tokenQuery)
 	tokenEntity, err := h.TokenService.Update(tokenID, request, tokenQuery, userInfo)
 	if err != nil {
 		return nil, xerrors.Errorf("Failed to update token, Err: %w", err)
 	}
+	h.audit(c, userInfo, &models.AuditRecord{
+		WorkspaceID: tokenEntity.WorkspaceID,
+		TokenID:     tokenID,
+		Action:      constants.AuditActionUpdate,
+		Before:      before,
+		After:       h.auditTokenWithPolicy(*tokenEntity),
+	})
 	return tokenEntity, nil
 }
 
@@ -464,17 +1024,179 @@ func (h *TokenHandler) UpdateToken(_ context.Context, c *common.CustomReqContext
 // @Success 200 object models.DataResult[string] "成功后返回"
 // @Security Bearer
 func (h *TokenHandler) DeleteToken(_ context.Context, c *common.CustomReqContext) (any, error) {
-	tokenID := c.Param("tokenId")
+	userInfo, exists := c.GetUser()
+	if !exists {
+		return nil, xerrors.Errorf("get userInfo failed")
+	}
+	workspaceID, tokenID := c.Params2("workspaceId", "tokenId")
 	if tokenID == "" {
 		return nil, common.NewCtrlError(400, xerrors.New("Token ID is required."))
 	}
-	err := h.TokenService.Delete(tokenID)
+	before, err := h.TokenService.FindTokenEntity(workspaceID, tokenID)
+	if err != nil {
+		return nil, findTokenError(err)
+	}
+	err = h.TokenService.Delete(workspaceID, tokenID)
 	if err != nil {
 		return nil, xerrors.Errorf("Failed to delete token exists, Err: %w", err)
 	}
+	if _, err := h.CalendarService.SetTokenCalendars(workspaceID, tokenID, nil); err != nil {
+		hlog.Errorf("Failed to unbind token calendars, TokenID: %s, Err: %v", tokenID, err)
+	}
+	h.audit(c, userInfo, &models.AuditRecord{
+		WorkspaceID: before.WorkspaceID,
+		TokenID:     tokenID,
+		Action:      constants.AuditActionDelete,
+		Before:      before,
+	})
 	return "deelte token success", nil
 }
 
+// RotateToken  Token密钥轮换
+// @Summary  Token密钥轮换，生成新的密钥，轮换前的密钥在宽限期内仍然有效
+// @Tags Token 管理
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param tokenId path string true "Token ID"
+// @Param data body models.RotateTokenReq false "rotate params"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/tokens/:tokenId/rotate [post]
+// @Success 200 object models.DataResult[models.CreateTokenResp] "成功后返回"
+// @Security Bearer
+func (h *TokenHandler) RotateToken(_ context.Context, c *common.CustomReqContext) (any, error) {
+	userInfo, exists := c.GetUser()
+	if !exists {
+		return nil, xerrors.Errorf("get userInfo failed")
+	}
+	request := &models.RotateTokenReq{}
+	if err := c.Bind(request); err != nil {
+		return nil, xerrors.Errorf("Failed to bind body: %w", err)
+	}
+	workspaceID, tokenID := c.Params2("workspaceId", "tokenId")
+	if tokenID == "" {
+		return nil, common.NewCtrlError(400, xerrors.New("Token ID is required."))
+	}
+	gracePeriod := h.AppConfig.Auth.RotationGracePeriod
+	if request.GracePeriodSeconds != nil {
+		if *request.GracePeriodSeconds < 0 {
+			return nil, common.NewCtrlError(400, xerrors.New("gracePeriodSeconds must not be negative."))
+		}
+		gracePeriod = time.Duration(*request.GracePeriodSeconds) * time.Second
+	}
+	tokenQuery, err := h.TokenService.FindTokenEntity(workspaceID, tokenID)
+	if err != nil {
+		return nil, findTokenError(err)
+	}
+	before := *tokenQuery
+	resp, err := h.TokenService.Rotate(tokenQuery, gracePeriod, userInfo)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to rotate token, Err: %w", err)
+	}
+	h.audit(c, userInfo, &models.AuditRecord{
+		WorkspaceID: before.WorkspaceID,
+		TokenID:     tokenID,
+		Action:      constants.AuditActionRotate,
+		Before:      &before,
+		After:       resp.TokenEntity,
+	})
+	return resp, nil
+}
+
+// SuspendToken  Token停用
+// @Summary  Token停用，停用后认证返回 403，可设置自动恢复时间
+// @Tags Token 管理
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param tokenId path string true "Token ID"
+// @Param data body models.SuspendTokenReq false "suspend params"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/tokens/:tokenId/suspend [post]
+// @Success 200 object models.DataResult[dao.TokenEntity] "成功后返回"
+// @Security Bearer
+func (h *TokenHandler) SuspendToken(_ context.Context, c *common.CustomReqContext) (any, error) {
+	userInfo, exists := c.GetUser()
+	if !exists {
+		return nil, xerrors.Errorf("get userInfo failed")
+	}
+	request := &models.SuspendTokenReq{}
+	if err := c.Bind(request); err != nil {
+		return nil, xerrors.Errorf("Failed to bind body: %w", err)
+	}
+	workspaceID, tokenID := c.Params2("workspaceId", "tokenId")
+	if tokenID == "" {
+		return nil, common.NewCtrlError(400, xerrors.New("Token ID is required."))
+	}
+	if utf8.RuneCountInString(request.Reason) > 255 {
+		return nil, common.NewCtrlError(400, xerrors.New("Reason must not exceed 255 characters."))
+	}
+	var resumeTime *time.Time
+	if request.ResumeTime != nil && *request.ResumeTime != "" {
+		ok, t := utils.CheckDateTimeFormat(*request.ResumeTime)
+		if !ok {
+			return nil, common.NewCtrlError(400, xerrors.New("ResumeTime is invalid."))
+		}
+		if t.Before(time.Now()) {
+			return nil, common.NewCtrlError(400, xerrors.New("ResumeTime is before now."))
+		}
+		resumeTime = &t
+	}
+	tokenQuery, err := h.TokenService.FindTokenEntity(workspaceID, tokenID)
+	if err != nil {
+		return nil, findTokenError(err)
+	}
+	before := *tokenQuery
+	tokenEntity, err := h.TokenService.Suspend(tokenQuery, request.Reason, resumeTime, userInfo)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to suspend token, Err: %w", err)
+	}
+	h.audit(c, userInfo, &models.AuditRecord{
+		WorkspaceID: tokenEntity.WorkspaceID,
+		TokenID:     tokenID,
+		Action:      constants.AuditActionSuspend,
+		Before:      &before,
+		After:       tokenEntity,
+	})
+	return tokenEntity, nil
+}
+
+// ResumeToken  Token恢复
+// @Summary  Token恢复，恢复停用的 Token
+// @Tags Token 管理
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param tokenId path string true "Token ID"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/tokens/:tokenId/resume [post]
+// @Success 200 object models.DataResult[dao.TokenEntity] "成功后返回"
+// @Security Bearer
+func (h *TokenHandler) ResumeToken(_ context.Context, c *common.CustomReqContext) (any, error) {
+	userInfo, exists := c.GetUser()
+	if !exists {
+		return nil, xerrors.Errorf("get userInfo failed")
+	}
+	workspaceID, tokenID := c.Params2("workspaceId", "tokenId")
+	if tokenID == "" {
+		return nil, common.NewCtrlError(400, xerrors.New("Token ID is required."))
+	}
+	tokenQuery, err := h.TokenService.FindTokenEntity(workspaceID, tokenID)
+	if err != nil {
+		return nil, findTokenError(err)
+	}
+	before := *tokenQuery
+	tokenEntity, err := h.TokenService.Resume(tokenQuery, userInfo)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to resume token, Err: %w", err)
+	}
+	h.audit(c, userInfo, &models.AuditRecord{
+		WorkspaceID: tokenEntity.WorkspaceID,
+		TokenID:     tokenID,
+		Action:      constants.AuditActionResume,
+		Before:      &before,
+		After:       tokenEntity,
+	})
+	return tokenEntity, nil
+}
+
 // ExportTokenFile  Token导出
 // @Summary  Token导出
 // @Tags Token 管理
@@ -482,10 +1204,15 @@ func (h *TokenHandler) DeleteToken(_ context.Context, c *common.CustomReqContext
 // @Produce  json
 // @Param workspaceId path string true "工作空间ID"
 // @Param tokenId path string true "Token ID"
+// @Param data body models.ExportTokenFileReq true "导出参数"
 // @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/tokens/:tokenId/export [post]
 // @Success 200 object models.DataResult[string] "成功后返回"
 // @Security Bearer
 func (h *TokenHandler) ExportTokenFile(ctx context.Context, c *common.CustomReqContext) (any, error) {
+	userInfo, exists := c.GetUser()
+	if !exists {
+		return nil, xerrors.Errorf("get userInfo failed")
+	}
 	request := &models.ExportTokenFileReq{}
 	if err := c.Bind(request); err != nil {
 		return nil, xerrors.Errorf("Failed to bind body: %w", err)
@@ -493,21 +1220,24 @@ func (h *TokenHandler) ExportTokenFile(ctx context.Context, c *common.CustomReqC
 	if request.EnvName == "" {
 		return nil, common.NewCtrlError(400, xerrors.New("EnvName is required."))
 	}
-	tokenID := c.Param("tokenId")
+	if request.Passphrase != "" && utf8.RuneCountInString(request.Passphrase) < constants.ExportPassphraseMinLen {
+		return nil, common.NewCtrlError(400, xerrors.Errorf("Passphrase must be at least %d characters.", constants.ExportPassphraseMinLen))
+	}
+	workspaceID, tokenID := c.Params2("workspaceId", "tokenId")
 	if tokenID == "" {
 		return nil, common.NewCtrlError(400, xerrors.New("Token ID is required."))
 	}
-	tokenEntity, err := h.TokenService.Get(tokenID)
+	tokenEntity, err := h.TokenService.GetExportEntity(workspaceID, tokenID)
 	if err != nil {
-		return nil, xerrors.Errorf("Failed to get token, Err: %w", err)
+		return nil, findTokenError(err)
 	}
 	// 将token转换为JSON
 	jsonData, err := json.Marshal(tokenEntity)
 	if err != nil {
 		return nil, xerrors.Errorf("Failed to marshal token, Err: %w", err)
 	}
-	// 加密JSON数据
-	encryptedData, err := utils.EncryptData(jsonData)
+	// 指定口令时使用口令加密，否则使用主密钥加密JSON数据
+	encryptedData, err := h.encryptTokenFile(jsonData, request.Passphrase)
 	if err != nil {
 		return nil, xerrors.Errorf("Failed to encrypt data, Err: %w", err)
 	}
@@ -522,6 +1252,11 @@ func (h *TokenHandler) ExportTokenFile(ctx context.Context, c *common.CustomReqC
 	// Write the encrypted data to the response
 	// 返回加密后的二进制数据
 	c.Data(http.StatusOK, "application/octet-stream", encryptedData)
+	h.audit(c, userInfo, &models.AuditRecord{
+		WorkspaceID: tokenEntity.WorkspaceID,
+		TokenID:     tokenID,
+		Action:      constants.AuditActionExport,
+	})
 	return nil, nil
 }
 
@@ -531,6 +1266,9 @@ func (h *TokenHandler) ExportTokenFile(ctx context.Context, c *common.CustomReqC
 // @Accept  multipart/form-data
 // @Produce json
 // @Param files formData file true "文件"
+// @Param passphrase formData string false "导出时指定的口令"
+// @Param mode formData string false "导入模式，dryRun 时只返回导入结果，不写入数据"
+// @Param conflictStrategy formData string false "冲突处理策略：fail（默认）、skip、overwrite 或 rename，overwrite 需要管理权限"
 // @Router  /apis/auth.engine.io/v1/workspaces/:workspaceId/tokens/import [POST]
 // @Success 200 object models.DataResult[string] "成功后返回"
 func (h *TokenHandler) ImportTokenFile(ctx context.Context, c *common.CustomReqContext) (any, error) {
@@ -539,86 +1277,488 @@ func (h *TokenHandler) ImportTokenFile(ctx context.Context, c *common.CustomReqC
 		return nil, xerrors.Errorf("get userInfo failed")
 	}
 	workspaceID := c.Param("workspaceId")
-	file, _ := c.FormFile("file")
-	if file.Filename == "" {
-		return nil, common.NewCtrlError(http.StatusBadRequest, xerrors.New("文件名为空,不允许上传！"))
+	opts, err := parseImportOptions(c)
+	if err != nil {
+		return nil, err
 	}
-	if file.Size == 0 {
-		return nil, common.NewCtrlError(http.StatusBadRequest, xerrors.New(file.Filename+"文件为空,不允许上传！"))
+	decryptedData, err := h.readTokenFile(c)
+	if err != nil {
+		return nil, err
 	}
-	if file.Size > constants.ImportTokenFileMaxSize {
-		return nil, common.NewCtrlError(http.StatusBadRequest, xerrors.New(file.Filename+"文件过大,只允许上传512MB以内的文件！"))
+	// 解析JSON数据
+	var tokenEntity *models.TokenExportEntity
+	err = json.Unmarshal(decryptedData, &tokenEntity)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to unmarshal token, Err: %w", err)
 	}
-	suffix := filepath.Ext(file.Filename)
-	if suffix != ".txt" {
-		return nil, common.NewCtrlError(http.StatusBadRequest, xerrors.Errorf("不支持的文件类型: %s", suffix))
+	if tokenEntity == nil {
+		return nil, common.NewCtrlError(400, xerrors.New("Token is required."))
 	}
-	openFile, err := file.Open()
+	result, tokenRes, err := h.importTokenEntity(c, userInfo, workspaceID, tokenEntity, opts)
 	if err != nil {
-		return nil, xerrors.Errorf("Failed to open file, Err: %w", err)
+		return nil, err
 	}
-	defer openFile.Close()
-	// 读取文件内容
-	fileBytes, err := io.ReadAll(openFile)
+	if result.Status == constants.ImportResultConflict && opts.strategy == constants.ConflictStrategyFail {
+		return nil, common.NewCtrlError(409, xerrors.New(result.Message))
+	}
+	// 新建时与之前一样返回新建的 Token，其他情况返回导入结果
+	if tokenRes != nil {
+		return tokenRes, nil
+	}
+	return result, nil
+}
+
+// ExportTokenBundle 批量导出工作空间下的 Token
+// @Summary  批量导出Token
+// @Tags Token 管理
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param data body models.ExportTokenBundleReq true "导出参数"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/tokens/bundle/export [post]
+// @Success 200 object models.DataResult[string] "成功后返回"
+// @Security Bearer
+func (h *TokenHandler) ExportTokenBundle(ctx context.Context, c *common.CustomReqContext) (any, error) {
+	userInfo, exists := c.GetUser()
+	if !exists {
+		return nil, xerrors.Errorf("get userInfo failed")
+	}
+	request := &models.ExportTokenBundleReq{}
+	if err := c.Bind(request); err != nil {
+		return nil, xerrors.Errorf("Failed to bind body: %w", err)
+	}
+	if err := checkStatusParam(request.QueryParam.Status); err != nil {
+		return nil, err
+	}
+	if request.Passphrase != "" && utf8.RuneCountInString(request.Passphrase) < constants.ExportPassphraseMinLen {
+		return nil, common.NewCtrlError(400, xerrors.Errorf("Passphrase must be at least %d characters.", constants.ExportPassphraseMinLen))
+	}
+	workspaceID := c.Param("workspaceId")
+	tokens, err := h.TokenService.ListExportEntities(workspaceID, request.QueryParam)
 	if err != nil {
-		return nil, xerrors.Errorf("Failed to read file, Err: %w", err)
+		return nil, xerrors.Errorf("Failed to list tokens, Err: %w", err)
 	}
-	// 解密数据
-	decryptedData, err := utils.DecryptData(fileBytes)
+	if len(tokens) == 0 {
+		return nil, common.NewCtrlError(400, xerrors.New("No tokens matched."))
+	}
+	jsonData, err := json.Marshal(&models.TokenExportBundle{
+		WorkspaceID: workspaceID,
+		ExportTime:  time.Now().Format(constants.TimeFormat),
+		Tokens:      tokens,
+	})
 	if err != nil {
-		return nil, xerrors.Errorf("Failed to decrypt data, Err: %w", err)
+		return nil, xerrors.Errorf("Failed to marshal tokens, Err: %w", err)
 	}
-	// 解析JSON数据
-	var tokenEntity *models.TokenResp
-	err = json.Unmarshal(decryptedData, &tokenEntity)
+	encryptedData, err := h.encryptTokenFile(jsonData, request.Passphrase)
 	if err != nil {
-		return nil, xerrors.Errorf("Failed to unmarshal token, Err: %w", err)
+		return nil, xerrors.Errorf("Failed to encrypt data, Err: %w", err)
+	}
+	// 文件名格式为：日期-workspaceId-tokens.txt，与单个 Token 的导出文件使用相同的后缀
+	filename := fmt.Sprintf("%s-%s-tokens.txt", time.Now().Format("2006-01-02"), workspaceID)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, "application/octet-stream", encryptedData)
+	for _, token := range tokens {
+		h.audit(c, userInfo, &models.AuditRecord{
+			WorkspaceID: workspaceID,
+			TokenID:     token.ID,
+			Action:      constants.AuditActionExport,
+		})
+	}
+	return nil, nil
+}
+
+// ImportTokenBundle 批量导入Token，逐个处理导出文件中的 Token 并返回每个 Token 的处理结果
+// @Summary  批量导入Token
+// @Tags Token 管理
+// @Accept  multipart/form-data
+// @Produce json
+// @Param workspaceId path string true "工作空间ID"
+// @Param files formData file true "文件"
+// @Param passphrase formData string false "导出时指定的口令"
+// @Param mode formData string false "导入模式，dryRun 时只返回导入结果，不写入数据"
+// @Param conflictStrategy formData string false "冲突处理策略：fail（默认）、skip、overwrite 或 rename，overwrite 需要管理权限"
+// @Router  /apis/auth.engine.io/v1/workspaces/:workspaceId/tokens/bundle/import [POST]
+// @Success 200 object models.DataResult[models.ImportTokenBundleResp] "成功后返回"
+// @Security Bearer
+func (h *TokenHandler) ImportTokenBundle(ctx context.Context, c *common.CustomReqContext) (any, error) {
+	userInfo, exists := c.GetUser()
+	if !exists {
+		return nil, xerrors.Errorf("get userInfo failed")
+	}
+	workspaceID := c.Param("workspaceId")
+	opts, err := parseImportOptions(c)
+	if err != nil {
+		return nil, err
+	}
+	decryptedData, err := h.readTokenFile(c)
+	if err != nil {
+		return nil, err
+	}
+	var bundle models.TokenExportBundle
+	if err := json.Unmarshal(decryptedData, &bundle); err != nil {
+		return nil, common.NewCtrlError(400, xerrors.Errorf("Failed to unmarshal tokens, Err: %w", err))
+	}
+	// 单个 Token 的导出文件按只包含一个 Token 处理
+	if bundle.Tokens == nil {
+		var tokenEntity models.TokenExportEntity
+		if err := json.Unmarshal(decryptedData, &tokenEntity); err != nil {
+			return nil, common.NewCtrlError(400, xerrors.Errorf("Failed to unmarshal token, Err: %w", err))
+		}
+		bundle.Tokens = []*models.TokenExportEntity{&tokenEntity}
+	}
+	resp := &models.ImportTokenBundleResp{
+		DryRun:  opts.dryRun,
+		Total:   len(bundle.Tokens),
+		Results: make([]*models.ImportTokenResult, 0, len(bundle.Tokens)),
+	}
+	for i, tokenEntity := range bundle.Tokens {
+		var result *models.ImportTokenResult
+		switch {
+		case tokenEntity == nil:
+			result = &models.ImportTokenResult{Status: constants.ImportResultError, Message: "Token is required."}
+		case tokenEntity.EnvName != config.CurrentEnvName:
+			// 导出文件中其他环境的 Token 跳过
+			result = &models.ImportTokenResult{
+				AppScenarioName: tokenEntity.AppScenarioName,
+				ModelName:       tokenEntity.ModelName,
+				EnvName:         tokenEntity.EnvName,
+				Status:          constants.ImportResultSkipped,
+				Message:         "Env Name is not match.",
+			}
+		default:
+			if result, _, err = h.importTokenEntity(c, userInfo, workspaceID, tokenEntity, opts); err != nil {
+				result.Status = constants.ImportResultError
+				result.Message = err.Error()
+			}
+		}
+		result.Index = i
+		switch result.Status {
+		case constants.ImportResultCreated:
+			resp.Created++
+		case constants.ImportResultUpdated:
+			resp.Updated++
+		case constants.ImportResultSkipped:
+			resp.Skipped++
+		case constants.ImportResultConflict:
+			resp.Conflict++
+		default:
+			resp.Error++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp, nil
+}
+
+// importOptions 导入模式和冲突处理策略
+type importOptions struct {
+	dryRun   bool
+	strategy string
+	names    map[string]struct{} // 试运行时本次导入中将要创建的 Token 名称
+}
+
+func importName(appScenarioName, modelName, envName string) string {
+	return appScenarioName + "\x00" + modelName + "\x00" + envName
+}
+
+// reserve 记录试运行时将要创建的 Token 名称
+func (o *importOptions) reserve(appScenarioName, modelName, envName string) {
+	if o.names == nil {
+		o.names = map[string]struct{}{}
+	}
+	o.names[importName(appScenarioName, modelName, envName)] = struct{}{}
+}
+
+// reserved 判断名称是否已被本次导入中前面的 Token 使用
+func (o *importOptions) reserved(appScenarioName, modelName, envName string) bool {
+	_, ok := o.names[importName(appScenarioName, modelName, envName)]
+	return ok
+}
+
+// parseImportOptions 读取表单或查询参数中的导入模式和冲突处理策略
+func parseImportOptions(c *common.CustomReqContext) (*importOptions, error) {
+	opts := &importOptions{strategy: string(c.FormValue("conflictStrategy"))}
+	switch mode := string(c.FormValue("mode")); mode {
+	case "":
+	case constants.ImportModeDryRun:
+		opts.dryRun = true
+	default:
+		return nil, common.NewCtrlError(400, xerrors.Errorf("mode is invalid: %s", mode))
+	}
+	switch opts.strategy {
+	case "":
+		opts.strategy = constants.ConflictStrategyFail
+	case constants.ConflictStrategyFail, constants.ConflictStrategySkip, constants.ConflictStrategyOverwrite, constants.ConflictStrategyRename:
+	default:
+		return nil, common.NewCtrlError(400, xerrors.Errorf("conflictStrategy is invalid: %s", opts.strategy))
+	}
+	// 覆盖会替换已有 Token 的密钥，与轮换相同需要管理权限
+	if opts.strategy == constants.ConflictStrategyOverwrite &&
+		!common.HasPermission(c.GetString(common.WorkspaceRoleKey), constants.PermissionTokenManage) {
+		return nil, common.NewCtrlError(403, xerrors.New("Forbidden: conflictStrategy overwrite requires token manage permission."))
+	}
+	return opts, nil
+}
+
+// importTokenEntity 导入单个 Token，返回处理结果，新建 Token 时同时返回新建的 Token，试运行时不写入数据
+// 密钥已被当前工作空间下同名 Token 使用时视为已导入，只有 overwrite 策略会更新；密钥被其他 Token 使用时无法导入
+// 名称已存在时按冲突处理策略处理，overwrite 只覆盖当前工作空间下的 Token
+func (h *TokenHandler) importTokenEntity(
+	c *common.CustomReqContext, userInfo *models.UserInfo, workspaceID string, tokenEntity *models.TokenExportEntity, opts *importOptions,
+) (*models.ImportTokenResult, *models.CreateTokenResp, error) {
+	result := &models.ImportTokenResult{
+		AppScenarioName: tokenEntity.AppScenarioName,
+		ModelName:       tokenEntity.ModelName,
+		EnvName:         tokenEntity.EnvName,
+	}
+	request, digest, ctrlErr := h.buildImportRequest(workspaceID, tokenEntity)
+	if ctrlErr != nil {
+		return result, nil, ctrlErr
+	}
+	existing, err := h.TokenService.FindByTokenHash(digest.TokenHash)
+	if err != nil && !xerrors.Is(err, services.ErrTokenNotFound) {
+		return result, nil, xerrors.Errorf("Failed to check token exists, Err: %w", err)
+	}
+	if existing != nil {
+		sameToken := existing.WorkspaceID == workspaceID && existing.AppScenarioName == request.AppScenarioName &&
+			existing.ModelName == request.ModelName && existing.EnvName == request.EnvName
+		switch {
+		case sameToken && opts.strategy == constants.ConflictStrategyOverwrite:
+			return h.overwriteImportedToken(c, userInfo, existing, request, digest, opts, result)
+		case sameToken:
+			result.TokenID = existing.ID
+			result.Status = constants.ImportResultSkipped
+			result.Message = "Token already imported."
+		case opts.strategy == constants.ConflictStrategySkip:
+			result.Status = constants.ImportResultSkipped
+			result.Message = "Token secret is already used by another token."
+		default:
+			result.Status = constants.ImportResultConflict
+			result.Message = "Token secret is already used by another token."
+		}
+		return result, nil, nil
+	}
+	existing, err = h.TokenService.FindByName(request.AppScenarioName, request.ModelName, request.EnvName)
+	if err != nil && !xerrors.Is(err, services.ErrTokenNotFound) {
+		return result, nil, xerrors.Errorf("Failed to check token exists, Err: %w", err)
+	}
+	// 试运行时前面的 Token 没有写入数据库，本次导入中将要创建的名称同样视为已存在
+	if existing == nil && opts.reserved(request.AppScenarioName, request.ModelName, request.EnvName) {
+		existing = &dao.TokenEntity{WorkspaceID: workspaceID}
+	}
+	if existing != nil {
+		switch {
+		case opts.strategy == constants.ConflictStrategyOverwrite && existing.WorkspaceID == workspaceID:
+			return h.overwriteImportedToken(c, userInfo, existing, request, digest, opts, result)
+		case opts.strategy == constants.ConflictStrategyRename:
+			name, err := h.renameImportedToken(request, opts)
+			if err != nil {
+				return result, nil, err
+			}
+			request.AppScenarioName = name
+			result.RenamedTo = name
+		case opts.strategy == constants.ConflictStrategySkip:
+			result.Status = constants.ImportResultSkipped
+			result.Message = "Token with appScenarioName and modelName already exists."
+			return result, nil, nil
+		default:
+			// 不返回其他工作空间下的 Token ID
+			if existing.WorkspaceID == workspaceID {
+				result.TokenID = existing.ID
+			}
+			result.Status = constants.ImportResultConflict
+			result.Message = "Token with appScenarioName and modelName already exists."
+			return result, nil, nil
+		}
+	}
+	result.Status = constants.ImportResultCreated
+	if opts.dryRun {
+		opts.reserve(request.AppScenarioName, request.ModelName, request.EnvName)
+		return result, nil, nil
+	}
+	tokenRes, err := h.TokenService.Create(request, userInfo, digest)
+	if err != nil {
+		return result, nil, xerrors.Errorf("Failed to create token, Err: %w", err)
+	}
+	h.audit(c, userInfo, &models.AuditRecord{
+		WorkspaceID: workspaceID,
+		TokenID:     tokenRes.ID,
+		Action:      constants.AuditActionImport,
+		After:       tokenRes.TokenEntity,
+	})
+	result.TokenID = tokenRes.ID
+	return result, tokenRes, nil
+}
+
+// overwriteImportedToken 按 overwrite 策略使用导入的内容覆盖已有 Token
+func (h *TokenHandler) overwriteImportedToken(
+	c *common.CustomReqContext, userInfo *models.UserInfo, existing *dao.TokenEntity, request *models.CreateTokenReq,
+	digest *dao.TokenDigest, opts *importOptions, result *models.ImportTokenResult,
+) (*models.ImportTokenResult, *models.CreateTokenResp, error) {
+	result.TokenID = existing.ID
+	result.Status = constants.ImportResultUpdated
+	if opts.dryRun {
+		return result, nil, nil
+	}
+	before := *existing
+	tokenEntity, err := h.TokenService.Overwrite(existing, request, digest, userInfo)
+	if err != nil {
+		return result, nil, xerrors.Errorf("Failed to overwrite token, Err: %w", err)
+	}
+	h.audit(c, userInfo, &models.AuditRecord{
+		WorkspaceID: tokenEntity.WorkspaceID,
+		TokenID:     tokenEntity.ID,
+		Action:      constants.AuditActionImport,
+		Before:      &before,
+		After:       tokenEntity,
+	})
+	return result, nil, nil
+}
+
+// renameImportedToken 按 rename 策略在应用场景名称后追加序号，返回第一个未被使用的名称
+func (h *TokenHandler) renameImportedToken(request *models.CreateTokenReq, opts *importOptions) (string, error) {
+	for i := 2; i <= constants.ImportRenameMaxAttempts; i++ {
+		name := fmt.Sprintf("%s-%d", request.AppScenarioName, i)
+		if opts.reserved(name, request.ModelName, request.EnvName) {
+			continue
+		}
+		_, err := h.TokenService.FindByName(name, request.ModelName, request.EnvName)
+		if xerrors.Is(err, services.ErrTokenNotFound) {
+			return name, nil
+		}
+		if err != nil {
+			return "", xerrors.Errorf("Failed to check token exists, Err: %w", err)
+		}
+	}
+	return "", common.NewCtrlError(409, xerrors.New("No available appScenarioName to rename."))
+}
+
+// buildImportRequest 校验导出文件中的 Token 并转换为创建参数
+func (h *TokenHandler) buildImportRequest(workspaceID string, tokenEntity *models.TokenExportEntity) (*models.CreateTokenReq, *dao.TokenDigest, *common.Error) {
+	// 新版本导出文件只包含摘要，摘要与 pepper 相关，pepper 不同的环境之间不能迁移；旧版本导出文件包含明文，导入时重新计算摘要
+	var digest *dao.TokenDigest
+	switch {
+	case tokenEntity.TokenHash != "":
+		if tokenEntity.PepperFingerprint != h.TokenService.PepperFingerprint() {
+			return nil, nil, common.NewCtrlError(400, xerrors.New("Token pepper does not match, tokens can only be imported into an environment with the same TOKEN_PEPPER."))
+		}
+		digest = &dao.TokenDigest{
+			TokenHash:   tokenEntity.TokenHash,
+			TokenPrefix: tokenEntity.TokenPrefix,
+			TokenLast4:  tokenEntity.TokenLast4,
+		}
+	case tokenEntity.Token != "":
+		digest = h.TokenService.NewTokenDigest(tokenEntity.Token)
+	default:
+		return nil, nil, common.NewCtrlError(400, xerrors.New("Token is required."))
 	}
 	// 校验参数
 	request := &models.CreateTokenReq{
 		WorkspaceID:          workspaceID,
 		AppScenarioName:      tokenEntity.AppScenarioName,
 		ModelName:            tokenEntity.ModelName,
+		ModelPatterns:        tokenEntity.ModelPatterns,
 		ExpiredTime:          tokenEntity.ExpiredTime,
 		EnvName:              tokenEntity.EnvName,
 		MaxConcurrency:       tokenEntity.MaxConcurrency,
+		RequestsPerMinute:    tokenEntity.RequestsPerMinute,
+		RequestsPerDay:       tokenEntity.RequestsPerDay,
 		EnableValidityPolicy: tokenEntity.EnableValidityPolicy,
 		PolicyType:           tokenEntity.PolicyType,
+		Timezone:             tokenEntity.Timezone,
 		ValidityPolicy:       tokenEntity.ValidityPolicy,
 	}
 	if request.AppScenarioName == "" {
-		return nil, common.NewCtrlError(400, xerrors.New("App Scenario Name is required."))
+		return nil, nil, common.NewCtrlError(400, xerrors.New("App Scenario Name is required."))
 	}
 	if request.ModelName == "" {
-		return nil, common.NewCtrlError(400, xerrors.New("Model Name is required."))
+		return nil, nil, common.NewCtrlError(400, xerrors.New("Model Name is required."))
+	}
+	if request.RequestsPerMinute < 0 || request.RequestsPerDay < 0 {
+		return nil, nil, common.NewCtrlError(400, xerrors.New("requestsPerMinute and requestsPerDay must not be negative."))
 	}
 	if request.EnvName != config.CurrentEnvName {
-		return nil, common.NewCtrlError(400, xerrors.New("Env Name is not match."))
+		return nil, nil, common.NewCtrlError(400, xerrors.New("Env Name is not match."))
+	}
+	request.ModelPatterns = NormalizeModelPatterns(request.ModelPatterns)
+	if err := CheckModelPatterns(request.ModelPatterns); err != nil {
+		return nil, nil, err
+	}
+	if err := CheckTimezone(request.Timezone); err != nil {
+		return nil, nil, err
 	}
 	if request.EnableValidityPolicy {
-		policyType := request.PolicyType
-		if policyType == "" || len(request.ValidityPolicy) == 0 {
-			return nil, common.NewCtrlError(400, xerrors.New("policyType and validityPolicy is required."))
-		}
-		// 当前只做 DAILY 类型
-		if policyType != constants.DaliyPolicyType {
-			return nil, common.NewCtrlError(400, xerrors.New("policyType is invalid."))
+		if len(request.ValidityPolicy) == 0 {
+			return nil, nil, common.NewCtrlError(400, xerrors.New("validityPolicy is required."))
 		}
-		if err := CheckValidityPolicy(policyType, request.ValidityPolicy); err != nil {
-			return nil, err
+		if err := CheckValidityPolicy(request.PolicyType, request.ValidityPolicy); err != nil {
+			return nil, nil, err
 		}
 	}
-	// check token exists
-	exists, err = h.TokenService.CheckTokenExists(request.AppScenarioName, request.ModelName, request.EnvName, "")
+	return request, digest, nil
+}
+
+// readTokenFile 读取并解密上传的导出文件
+func (h *TokenHandler) readTokenFile(c *common.CustomReqContext) ([]byte, error) {
+	file, err := c.FormFile("file")
 	if err != nil {
-		return nil, xerrors.Errorf("Failed to check token exists, Err: %w", err)
+		return nil, common.NewCtrlError(http.StatusBadRequest, xerrors.New("文件不能为空！"))
 	}
-	if exists {
-		return nil, common.NewCtrlError(409, xerrors.New("Token with appScenarioName and modelName already exists."))
+	if file.Filename == "" {
+		return nil, common.NewCtrlError(http.StatusBadRequest, xerrors.New("文件名为空,不允许上传！"))
+	}
+	if file.Size == 0 {
+		return nil, common.NewCtrlError(http.StatusBadRequest, xerrors.New(file.Filename+"文件为空,不允许上传！"))
 	}
-	tokenRes, err := h.TokenService.Create(request, userInfo, tokenEntity.Token)
+	if file.Size > constants.ImportTokenFileMaxSize {
+		return nil, common.NewCtrlError(http.StatusBadRequest, xerrors.New(file.Filename+"文件过大,只允许上传512MB以内的文件！"))
+	}
+	suffix := filepath.Ext(file.Filename)
+	if suffix != ".txt" {
+		return nil, common.NewCtrlError(http.StatusBadRequest, xerrors.Errorf("不支持的文件类型: %s", suffix))
+	}
+	openFile, err := file.Open()
 	if err != nil {
-		return nil, xerrors.Errorf("Failed to create token, Err: %w", err)
+		return nil, xerrors.Errorf("Failed to open file, Err: %w", err)
+	}
+	defer openFile.Close()
+	// 读取文件内容
+	fileBytes, err := io.ReadAll(openFile)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to read file, Err: %w", err)
+	}
+	// 解密数据，口令加密的文件需要提供导出时的口令
+	return h.decryptTokenFile(fileBytes, string(c.FormValue("passphrase")))
+}
+
+// encryptTokenFile 加密导出文件，指定口令时使用口令加密，否则使用主密钥加密
+func (h *TokenHandler) encryptTokenFile(data []byte, passphrase string) ([]byte, error) {
+	if passphrase != "" {
+		return utils.EncryptWithPassphrase(data, passphrase)
+	}
+	return h.ExportCipher.Encrypt(data)
+}
+
+// decryptTokenFile 解密导出文件，口令加密的文件使用口令解密，其他文件使用配置的密钥解密
+func (h *TokenHandler) decryptTokenFile(fileBytes []byte, passphrase string) ([]byte, error) {
+	if !utils.IsPassphraseProtected(fileBytes) {
+		decryptedData, _, err := h.ExportCipher.Decrypt(fileBytes)
+		if xerrors.Is(err, utils.ErrUnknownExportKey) {
+			return nil, common.NewCtrlError(http.StatusBadRequest, xerrors.Errorf("文件加密密钥未配置: %w", err))
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("Failed to decrypt data, Err: %w", err)
+		}
+		return decryptedData, nil
+	}
+	if passphrase == "" {
+		return nil, common.NewCtrlError(http.StatusBadRequest, xerrors.New("文件已使用口令加密，请输入导出时的口令"))
+	}
+	decryptedData, err := utils.DecryptWithPassphrase(fileBytes, passphrase)
+	if xerrors.Is(err, utils.ErrIncorrectPassphrase) {
+		return nil, common.NewCtrlError(http.StatusBadRequest, xerrors.New("口令错误"))
+	}
+	if err != nil {
+		return nil, common.NewCtrlError(http.StatusBadRequest, xerrors.Errorf("Failed to decrypt data, Err: %w", err))
 	}
-	return tokenRes, nil
+	return decryptedData, nil
 }
@@ -0,0 +1,329 @@
+package ctrl
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"golang.org/x/xerrors"
+
+	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/routers/ctrl/common"
+	"github.com/auth-engine/internal/pkg/utils"
+	"github.com/auth-engine/pkg/constants"
+)
+
+// explainMaxCronFirings 计算生效和失效时间时单个 CRON 策略最多展开的触发次数，每分钟触发一次时一周为 10080 次
+const explainMaxCronFirings = 20000
+
+// ExplainToken  解释 Token 认证结果
+// @Summary  解释 Token 在指定时间的认证结果，返回每个策略和日历的评估过程，以及之后一周内的生效和失效时间
+// @Tags Token 管理
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param tokenId path string true "Token ID"
+// @Param data body models.ExplainTokenReq false "explain params"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/tokens/:tokenId/explain [post]
+// @Success 200 object models.DataResult[models.ExplainTokenResp] "成功后返回"
+// @Security Bearer
+func (h *TokenHandler) ExplainToken(_ context.Context, c *common.CustomReqContext) (any, error) {
+	request := &models.ExplainTokenReq{}
+	if err := c.Bind(request); err != nil {
+		return nil, xerrors.Errorf("Failed to bind body: %w", err)
+	}
+	workspaceID, tokenID := c.Params2("workspaceId", "tokenId")
+	if tokenID == "" {
+		return nil, common.NewCtrlError(400, xerrors.New("Token ID is required."))
+	}
+	location := h.location()
+	currentTime := h.Now().In(location)
+	if request.Time != "" {
+		t, err := time.ParseInLocation(constants.TimeFormat, request.Time, location)
+		if err != nil {
+			return nil, common.NewCtrlError(400, xerrors.Errorf("time %s is invalid.", request.Time))
+		}
+		currentTime = t
+	}
+	transitions := request.Transitions
+	if transitions == 0 {
+		transitions = constants.ExplainDefaultTransitions
+	}
+	if transitions < 0 || transitions > constants.ExplainMaxTransitions {
+		return nil, common.NewCtrlError(400, xerrors.Errorf("transitions must be between 1 and %d.", constants.ExplainMaxTransitions))
+	}
+	tokenAuthInfos, err := h.TokenService.GetTokenAuthInfoByID(workspaceID, tokenID)
+	if err != nil {
+		return nil, findTokenError(err)
+	}
+	calendars, err := h.CalendarService.ListTokenCalendars(tokenID)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to list token calendars, Err: %w", err)
+	}
+	resp := ExplainAuth(currentTime, tokenAuthInfos, calendars)
+	resp.Timezone = location.String()
+	resp.Schedule = AuthSchedule(currentTime, tokenAuthInfos, calendars, transitions)
+	return resp, nil
+}
+
+// ExplainAuth 按认证接口的顺序评估 Token 在 currentTime 的认证结果，currentTime 所在时区为默认时区
+func ExplainAuth(currentTime time.Time, tokenAuthInfos []*dao.TokenAuthInfo, calendars []*models.CalendarResp) *models.ExplainTokenResp {
+	info := tokenAuthInfos[0]
+	resp := &models.ExplainTokenResp{
+		TokenID:              info.TokenID,
+		Time:                 currentTime,
+		Status:               dao.EffectiveStatus(info.Status, info.ResumeTime, currentTime),
+		ResumeTime:           info.ResumeTime,
+		Expired:              info.ExpiredTime != nil && currentTime.After(*info.ExpiredTime),
+		ExpiredTime:          info.ExpiredTime,
+		EnableValidityPolicy: info.EnableValidityPolicy,
+		Policies:             []*models.PolicyTrace{},
+		Calendars:            []*models.CalendarTrace{},
+	}
+	// 未启用有效期策略时不检查策略，左连接得到的记录不是策略
+	if info.EnableValidityPolicy {
+		for _, policyInfo := range tokenAuthInfos {
+			resp.Policies = append(resp.Policies, tracePolicy(currentTime, policyInfo))
+		}
+	}
+	for _, calendar := range calendars {
+		resp.Calendars = append(resp.Calendars, traceCalendar(currentTime, calendar))
+	}
+	resp.Reason = authDenyReason(currentTime, tokenAuthInfos, calendars)
+	resp.Allowed = resp.Reason == ""
+	return resp
+}
+
+// AuthSchedule 计算 from 之后一周内认证结果的变化时间，最多返回 limit 个
+// 认证结果只会在策略、日历的边界时间以及过期和自动恢复时间变化，依次评估这些时间点即可
+func AuthSchedule(
+	from time.Time, tokenAuthInfos []*dao.TokenAuthInfo, calendars []*models.CalendarResp, limit int,
+) []*models.ScheduleTransition {
+	to := from.Add(constants.ExplainScheduleWindow)
+	candidates := scheduleCandidates(from, to, tokenAuthInfos, calendars)
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Before(candidates[j])
+	})
+	active := authDenyReason(from, tokenAuthInfos, calendars) == ""
+	transitions := make([]*models.ScheduleTransition, 0, limit)
+	var last time.Time
+	for _, candidate := range candidates {
+		if !candidate.After(from) || candidate.After(to) || candidate.Equal(last) {
+			continue
+		}
+		last = candidate
+		if allowed := authDenyReason(candidate, tokenAuthInfos, calendars) == ""; allowed != active {
+			active = allowed
+			// 过期时间之后才认证失败，候选时间比过期时间晚 1 纳秒，展示时截断到秒
+			transitions = append(transitions, &models.ScheduleTransition{Time: candidate.Truncate(time.Second).In(from.Location()), Active: active})
+			if len(transitions) == limit {
+				break
+			}
+		}
+	}
+	return transitions
+}
+
+// authDenyReason 按认证接口的顺序检查 Token 在 currentTime 是否认证通过，返回与认证接口一致的错误，通过时返回空
+// 不包含请求频率和并发限制
+func authDenyReason(currentTime time.Time, tokenAuthInfos []*dao.TokenAuthInfo, calendars []*models.CalendarResp) string {
+	info := tokenAuthInfos[0]
+	if dao.EffectiveStatus(info.Status, info.ResumeTime, currentTime) == constants.TokenStatusSuspended {
+		return "Forbidden: Token is suspended."
+	}
+	if info.ExpiredTime != nil && currentTime.After(*info.ExpiredTime) {
+		return "Forbidden: Token is expired."
+	}
+	if info.EnableValidityPolicy && !AuthValidityPolicy(currentTime, tokenAuthInfos) {
+		return "Forbidden: Token is invalid for no policy pass"
+	}
+	if calendar := AuthBlackoutCalendars(currentTime, calendars); calendar != nil {
+		return fmt.Sprintf("Forbidden: Token is blocked by calendar %s.", calendar.Name)
+	}
+	return ""
+}
+
+func tracePolicy(currentTime time.Time, info *dao.TokenAuthInfo) *models.PolicyTrace {
+	trace := &models.PolicyTrace{PolicyType: info.PolicyType, Timezone: info.Timezone, Rule: policyRule(info)}
+	now, err := policyLocalTime(currentTime, info)
+	if err != nil {
+		trace.Reason = fmt.Sprintf("timezone %s is invalid.", info.Timezone)
+		return trace
+	}
+	trace.LocalTime = now.Format(constants.TimeFormat) + " " + strings.ToUpper(now.Weekday().String())
+	trace.Matched = matchPolicy(now, info)
+	switch {
+	case info.PolicyType == constants.CronPolicyType:
+		trace.Reason = cronPolicyReason(now, info)
+	case trace.Matched:
+		trace.Reason = fmt.Sprintf("%s is within %s.", trace.LocalTime, trace.Rule)
+	default:
+		trace.Reason = fmt.Sprintf("%s is outside %s.", trace.LocalTime, trace.Rule)
+	}
+	return trace
+}
+
+// policyRule 策略内容，如 DAILY 09:00:00-18:00:00、WEEKLY MONDAY-FRIDAY、DATERANGE 2026-01-01~2026-12-31、CRON 0 9 * * * for 1h0m0s
+func policyRule(info *dao.TokenAuthInfo) string {
+	switch info.PolicyType {
+	case constants.DaliyPolicyType:
+		return fmt.Sprintf("DAILY %s-%s", formatPolicyTime(info.StartTime, constants.DaliyTimeFormat), formatPolicyTime(info.EndTime, constants.DaliyTimeFormat))
+	case constants.WeeklyPolicyType:
+		rule := fmt.Sprintf("WEEKLY %s-%s", info.StartDay, info.EndDay)
+		if info.StartTime != nil && info.EndTime != nil {
+			rule += fmt.Sprintf(" %s-%s", info.StartTime.Format(constants.DaliyTimeFormat), info.EndTime.Format(constants.DaliyTimeFormat))
+		}
+		if info.Continuous {
+			rule += " continuous"
+		}
+		return rule
+	case constants.DateRangePolicyType:
+		return fmt.Sprintf("DATERANGE %s~%s",
+			formatPolicyTime(info.StartDate, constants.DateRangeTimeFormat), formatPolicyTime(info.EndDate, constants.DateRangeTimeFormat))
+	case constants.CronPolicyType:
+		return fmt.Sprintf("CRON %s for %s", info.CronExpr, time.Duration(info.CronDuration)*time.Second)
+	}
+	return info.PolicyType
+}
+
+func formatPolicyTime(t *time.Time, layout string) string {
+	if t == nil {
+		return "?"
+	}
+	return t.Format(layout)
+}
+
+// cronPolicyReason CRON 策略的评估原因，包含最近一次触发时间或下一次触发时间
+func cronPolicyReason(now time.Time, info *dao.TokenAuthInfo) string {
+	schedule, err := utils.ParseCron(info.CronExpr)
+	if err != nil {
+		return fmt.Sprintf("cronExpr %s is invalid.", info.CronExpr)
+	}
+	duration := time.Duration(info.CronDuration) * time.Second
+	if fireTime := lastFireTime(schedule, now.Add(-duration), now); !fireTime.IsZero() {
+		return fmt.Sprintf("last fired at %s, active until %s.",
+			fireTime.Format(constants.TimeFormat), fireTime.Add(duration).Format(constants.TimeFormat))
+	}
+	nextTime := schedule.Next(now)
+	if nextTime.IsZero() {
+		return "cronExpr never fires."
+	}
+	return fmt.Sprintf("not fired in the last %s, next fires at %s.", duration, nextTime.Format(constants.TimeFormat))
+}
+
+// lastFireTime 返回 (after, before] 中最后一次触发时间，没有触发时返回零值
+// cron 只能计算下一次触发时间，Next 随参数单调不减，按秒二分查找 Next 不晚于 before 的最大参数
+func lastFireTime(schedule cron.Schedule, after, before time.Time) time.Time {
+	fireTime := schedule.Next(after)
+	if fireTime.IsZero() || fireTime.After(before) {
+		return time.Time{}
+	}
+	low, high := fireTime, before
+	for high.Sub(low) > time.Second {
+		mid := low.Add(high.Sub(low) / 2)
+		if next := schedule.Next(mid); !next.IsZero() && !next.After(before) {
+			low = next
+		} else {
+			high = mid
+		}
+	}
+	if next := schedule.Next(low); !next.IsZero() && !next.After(before) {
+		return next
+	}
+	return low
+}
+
+func traceCalendar(currentTime time.Time, calendar *models.CalendarResp) *models.CalendarTrace {
+	trace := &models.CalendarTrace{ID: calendar.ID, Name: calendar.Name, Timezone: calendar.Timezone}
+	now := currentTime
+	if calendar.Timezone != "" {
+		location, err := utils.LoadLocation(calendar.Timezone)
+		if err != nil {
+			return trace
+		}
+		now = currentTime.In(location)
+	}
+	trace.LocalDate = now.Format(constants.DateRangeTimeFormat)
+	trace.Blocked = AuthBlackoutCalendars(currentTime, []*models.CalendarResp{calendar}) != nil
+	return trace
+}
+
+// scheduleCandidates 认证结果可能变化的时间点：每天零点、策略的开始和结束时间、CRON 的触发和有效期结束时间、过期和自动恢复时间
+func scheduleCandidates(from, to time.Time, tokenAuthInfos []*dao.TokenAuthInfo, calendars []*models.CalendarResp) []time.Time {
+	var candidates []time.Time
+	// addDays 按时区添加范围内每天的零点和指定的时间，多算前后各一天，夏令时切换由 time.Date 处理
+	addDays := func(location *time.Location, clocks ...*time.Time) {
+		start := from.In(location)
+		days := int(to.Sub(from)/(24*time.Hour)) + 1
+		for day := -1; day <= days; day++ {
+			year, month, date := start.AddDate(0, 0, day).Date()
+			candidates = append(candidates, time.Date(year, month, date, 0, 0, 0, 0, location))
+			for _, clock := range clocks {
+				if clock != nil {
+					candidates = append(candidates, time.Date(year, month, date, clock.Hour(), clock.Minute(), clock.Second(), 0, location))
+				}
+			}
+		}
+	}
+	loadLocation := func(timezone string) (*time.Location, bool) {
+		if timezone == "" {
+			return from.Location(), true
+		}
+		location, err := utils.LoadLocation(timezone)
+		return location, err == nil
+	}
+	info := tokenAuthInfos[0]
+	if info.EnableValidityPolicy {
+		for _, policyInfo := range tokenAuthInfos {
+			location, ok := loadLocation(policyInfo.Timezone)
+			if !ok {
+				continue
+			}
+			if policyInfo.PolicyType == constants.CronPolicyType {
+				candidates = append(candidates, cronCandidates(from.In(location), to, policyInfo)...)
+				continue
+			}
+			addDays(location, policyInfo.StartTime, policyInfo.EndTime)
+		}
+	}
+	for _, calendar := range calendars {
+		if location, ok := loadLocation(calendar.Timezone); ok {
+			addDays(location)
+		}
+	}
+	if info.ExpiredTime != nil {
+		candidates = append(candidates, info.ExpiredTime.Add(time.Nanosecond))
+	}
+	if info.ResumeTime != nil {
+		candidates = append(candidates, *info.ResumeTime)
+	}
+	return candidates
+}
+
+// cronCandidates CRON 策略在 (from, to] 中的触发时间和有效期结束时间，以及 from 之前最后一次触发的有效期结束时间
+// from 所在时区为策略时区
+func cronCandidates(from, to time.Time, info *dao.TokenAuthInfo) []time.Time {
+	schedule, err := utils.ParseCron(info.CronExpr)
+	if err != nil || info.CronDuration <= 0 {
+		return nil
+	}
+	duration := time.Duration(info.CronDuration) * time.Second
+	var candidates []time.Time
+	// 更早的触发的有效期都被最后一次触发覆盖
+	if fireTime := lastFireTime(schedule, from.Add(-duration), from); !fireTime.IsZero() {
+		candidates = append(candidates, fireTime.Add(duration))
+	}
+	fireTime := from
+	for i := 0; i < explainMaxCronFirings; i++ {
+		fireTime = schedule.Next(fireTime)
+		if fireTime.IsZero() || fireTime.After(to) {
+			break
+		}
+		candidates = append(candidates, fireTime, fireTime.Add(duration))
+	}
+	return candidates
+}
@@ -0,0 +1,155 @@
+package ctrl
+
+import (
+	"context"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"golang.org/x/xerrors"
+
+	"github.com/auth-engine/internal/pkg/authn"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/routers/ctrl/common"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/pkg/constants"
+)
+
+const (
+	defaultUsageDays = 7   // 默认查询最近 7 天
+	maxUsageDays     = 366 // 最多查询 366 天
+)
+
+type UsageHandler struct {
+	UsageService services.ITokenUsageService
+}
+
+func NewUsageHandler(us services.ITokenUsageService, cs services.IClientService, verifier *authn.Verifier, r *server.Hertz) *UsageHandler {
+	handler := &UsageHandler{UsageService: us}
+	router := r.Group("/apis/auth.engine.io/v1")
+	router.Use(common.VerifyAuthorization(verifier))
+	wsRouter := router.Group("/workspaces/:workspaceId", common.VerifyWorkspace(cs))
+	view := common.RequirePermission(cs, constants.PermissionTokenView)
+	manage := common.RequirePermission(cs, constants.PermissionTokenManage)
+	wsRouter.GET("/tokens/:tokenId/usage", view, common.Handle(handler.GetTokenUsage))
+	wsRouter.GET("/usage", view, common.Handle(handler.GetWorkspaceUsage))
+	// 不存在的 Token 的统计不属于任何工作空间，只允许工作空间管理员查看
+	wsRouter.GET("/usage/unknown", manage, common.Handle(handler.GetUnknownUsage))
+	// 服务退出前写入内存中的统计
+	r.OnShutdown = append(r.OnShutdown, func(_ context.Context) {
+		if err := us.Flush(); err != nil {
+			hlog.Errorf("flush token usage on shutdown failed: %v", err)
+		}
+	})
+	return handler
+}
+
+// GetTokenUsage Token 认证次数统计
+// @Summary  Token 认证次数统计
+// @Tags Token 管理
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param tokenId path string true "Token ID"
+// @Param startDate query string false "开始日期，如 2024-01-01，默认 7 天前"
+// @Param endDate query string false "结束日期，如 2024-01-07，默认今天"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/tokens/:tokenId/usage [get]
+// @Success 200 object models.DataResult[models.TokenUsageSummary] "成功后返回"
+// @Security Bearer
+func (h *UsageHandler) GetTokenUsage(_ context.Context, c *common.CustomReqContext) (any, error) {
+	workspaceID, tokenID := c.Params2("workspaceId", "tokenId")
+	if tokenID == "" {
+		return nil, common.NewCtrlError(400, xerrors.New("Token ID is required."))
+	}
+	startDate, endDate, ctrlErr := bindUsageDateRange(c)
+	if ctrlErr != nil {
+		return nil, ctrlErr
+	}
+	summary, err := h.UsageService.QueryTokenUsage(workspaceID, tokenID, startDate, endDate)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to query token usage, Err: %w", err)
+	}
+	return summary, nil
+}
+
+// GetWorkspaceUsage 工作空间下所有 Token 的认证次数统计
+// @Summary  工作空间认证次数统计
+// @Tags Token 管理
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param startDate query string false "开始日期，如 2024-01-01，默认 7 天前"
+// @Param endDate query string false "结束日期，如 2024-01-07，默认今天"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/usage [get]
+// @Success 200 object models.DataResult[models.TokenUsageSummary] "成功后返回"
+// @Security Bearer
+func (h *UsageHandler) GetWorkspaceUsage(_ context.Context, c *common.CustomReqContext) (any, error) {
+	workspaceID := c.Param("workspaceId")
+	if workspaceID == "" {
+		return nil, common.NewCtrlError(400, xerrors.New("Workspace ID is required."))
+	}
+	startDate, endDate, ctrlErr := bindUsageDateRange(c)
+	if ctrlErr != nil {
+		return nil, ctrlErr
+	}
+	summary, err := h.UsageService.QueryWorkspaceUsage(workspaceID, startDate, endDate)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to query workspace usage, Err: %w", err)
+	}
+	return summary, nil
+}
+
+// GetUnknownUsage 不存在的 Token 的认证次数统计，不属于任何工作空间，需要路径工作空间的管理权限
+// @Summary  不存在的 Token 认证次数统计
+// @Tags Token 管理
+// @Accept  json
+// @Produce  json
+// @Param workspaceId path string true "工作空间ID"
+// @Param startDate query string false "开始日期，如 2024-01-01，默认 7 天前"
+// @Param endDate query string false "结束日期，如 2024-01-07，默认今天"
+// @Router /apis/auth.engine.io/v1/workspaces/:workspaceId/usage/unknown [get]
+// @Success 200 object models.DataResult[models.TokenUsageSummary] "成功后返回"
+// @Security Bearer
+func (h *UsageHandler) GetUnknownUsage(_ context.Context, c *common.CustomReqContext) (any, error) {
+	startDate, endDate, ctrlErr := bindUsageDateRange(c)
+	if ctrlErr != nil {
+		return nil, ctrlErr
+	}
+	summary, err := h.UsageService.QueryUnknownUsage(startDate, endDate)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to query unknown token usage, Err: %w", err)
+	}
+	return summary, nil
+}
+
+// bindUsageDateRange 解析查询日期范围，未指定时默认最近 7 天
+func bindUsageDateRange(c *common.CustomReqContext) (time.Time, time.Time, *common.Error) {
+	request := &models.TokenUsageReq{}
+	if err := c.BindQuery(request); err != nil {
+		return time.Time{}, time.Time{}, common.NewCtrlError(400, xerrors.Errorf("Failed to bind query: %w", err))
+	}
+	year, month, day := time.Now().Date()
+	endDate := time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	if request.EndDate != "" {
+		date, err := time.ParseInLocation(constants.DateRangeTimeFormat, request.EndDate, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, common.NewCtrlError(400, xerrors.New("endDate is invalid."))
+		}
+		endDate = date
+	}
+	startDate := endDate.AddDate(0, 0, 1-defaultUsageDays)
+	if request.StartDate != "" {
+		date, err := time.ParseInLocation(constants.DateRangeTimeFormat, request.StartDate, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, common.NewCtrlError(400, xerrors.New("startDate is invalid."))
+		}
+		startDate = date
+	}
+	if startDate.After(endDate) {
+		return time.Time{}, time.Time{}, common.NewCtrlError(400, xerrors.New("startDate must be before endDate."))
+	}
+	if endDate.Sub(startDate) >= maxUsageDays*24*time.Hour {
+		return time.Time{}, time.Time{}, common.NewCtrlError(400, xerrors.New("date range must not exceed 366 days."))
+	}
+	return startDate, endDate, nil
+}
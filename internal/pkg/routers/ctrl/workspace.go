@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/auth-engine/config"
+	"github.com/auth-engine/internal/pkg/authn"
 	"github.com/auth-engine/internal/pkg/models"
 	"github.com/auth-engine/internal/pkg/routers/ctrl/common"
 	"github.com/auth-engine/internal/pkg/services"
@@ -18,10 +19,10 @@ type WorkspaceHandler struct {
 	AppConfig      *config.AppConfig
 }
 
-func NewWorkspaceHandler(iClientService services.IClientService, r *server.Hertz) *WorkspaceHandler {
+func NewWorkspaceHandler(iClientService services.IClientService, verifier *authn.Verifier, r *server.Hertz) *WorkspaceHandler {
 	handler := &WorkspaceHandler{IClientService: iClientService}
 	router := r.Group("/apis/auth.engine.io/v1/auth")
-	router.Use(common.VerifyAuthorization())
+	router.Use(common.VerifyAuthorization(verifier))
 	router.GET("/workspaces/list", common.Handle(handler.ListVisibleWorkspaces))
 	return handler
 }
@@ -0,0 +1,135 @@
+package extauthz
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/routers/ctrl"
+	"github.com/auth-engine/internal/pkg/routers/ctrl/common"
+	"github.com/auth-engine/pkg/constants"
+)
+
+// 认证通过后转发给上游服务的请求头，会覆盖客户端传入的同名请求头
+const (
+	HeaderTokenID         = "x-auth-token-id"
+	HeaderWorkspaceID     = "x-auth-workspace-id"
+	HeaderMatchedSecret   = "x-auth-matched-secret"
+	HeaderAppScenarioName = "x-auth-app-scenario-name"
+	HeaderModelName       = "x-auth-model-name"
+	HeaderEnvName         = "x-auth-env-name"
+)
+
+// Server Envoy 外部认证服务（envoy.service.auth.v3.Authorization），与 Token 认证接口使用相同的认证逻辑，
+// Envoy 没有请求结束的回调，因此不占用并发名额
+type Server struct {
+	authv3.UnimplementedAuthorizationServer
+	TokenHandler *ctrl.TokenHandler
+}
+
+func NewServer(tokenHandler *ctrl.TokenHandler) *Server {
+	return &Server{TokenHandler: tokenHandler}
+}
+
+// Check 从 Authorization（Bearer）或 x-api-key 请求头中读取 Token 进行认证，
+// 请求的模型从 x-requested-model 请求头读取，未设置时读取请求体中的 model（需在 Envoy 中开启 with_request_body），
+// 服务内部错误时返回 gRPC 错误，由 Envoy 的 failure_mode_allow 决定是否放行
+func (s *Server) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	// Envoy 传入的请求头名称均为小写
+	httpRequest := req.GetAttributes().GetRequest().GetHttp()
+	headers := httpRequest.GetHeaders()
+	request := &models.TokenAuthBody{
+		Token:   ctrl.TokenFromHeaders(headers["authorization"], headers["x-api-key"]),
+		Model:   headers[strings.ToLower(constants.RequestedModelHeader)],
+		Request: []byte(httpRequest.GetBody()),
+	}
+	if len(request.Request) == 0 {
+		request.Request = httpRequest.GetRawBody()
+	}
+	result, err := s.TokenHandler.Authenticate(ctx, request)
+	if err != nil {
+		return deniedResponse(result, err)
+	}
+	info := result.Info
+	return &authv3.CheckResponse{
+		Status: status.New(codes.OK, "").Proto(),
+		HttpResponse: &authv3.CheckResponse_OkResponse{
+			OkResponse: &authv3.OkHttpResponse{
+				Headers: headerOptions(map[string]string{
+					HeaderTokenID:         info.TokenID,
+					HeaderWorkspaceID:     info.WorkspaceID,
+					HeaderMatchedSecret:   info.MatchedSecret,
+					HeaderAppScenarioName: info.AppScenarioName,
+					HeaderModelName:       info.ModelName,
+					HeaderEnvName:         info.EnvName,
+				}),
+				ResponseHeadersToAdd: headerOptions(ctrl.RateLimitHeaders(result.RateLimit)),
+			},
+		},
+	}, nil
+}
+
+// deniedResponse 认证失败时返回与 Token 认证接口相同的状态码和响应体
+func deniedResponse(result *models.TokenAuthResult, err error) (*authv3.CheckResponse, error) {
+	code := http.StatusInternalServerError
+	if ctrlErr, ok := err.(*common.Error); ok {
+		code = ctrlErr.Code
+	}
+	var grpcCode codes.Code
+	switch code {
+	case http.StatusUnauthorized:
+		grpcCode = codes.Unauthenticated
+	case http.StatusForbidden:
+		grpcCode = codes.PermissionDenied
+	case http.StatusTooManyRequests:
+		grpcCode = codes.ResourceExhausted
+	default:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	body, marshalErr := json.Marshal(common.BuildErrResp(err))
+	if marshalErr != nil {
+		return nil, status.Error(codes.Internal, marshalErr.Error())
+	}
+	headers := map[string]string{"content-type": "application/json"}
+	if result != nil {
+		for key, value := range ctrl.RateLimitHeaders(result.RateLimit) {
+			headers[key] = value
+		}
+	}
+	return &authv3.CheckResponse{
+		Status: status.New(grpcCode, err.Error()).Proto(),
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{
+				Status:  &typev3.HttpStatus{Code: typev3.StatusCode(code)},
+				Headers: headerOptions(headers),
+				Body:    string(body),
+			},
+		},
+	}, nil
+}
+
+// headerOptions 按名称排序，已存在的同名请求头会被覆盖
+func headerOptions(headers map[string]string) []*corev3.HeaderValueOption {
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	options := make([]*corev3.HeaderValueOption, 0, len(keys))
+	for _, key := range keys {
+		options = append(options, &corev3.HeaderValueOption{
+			Header:       &corev3.HeaderValue{Key: key, Value: headers[key]},
+			AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+		})
+	}
+	return options
+}
@@ -11,6 +11,9 @@ func NewInit(
 	_ *ctrl.SwaggerCtrl,
 	_ *ctrl.TokenHandler,
 	_ *ctrl.WorkspaceHandler,
+	_ *ctrl.UsageHandler,
+	_ *ctrl.AuditLogHandler,
+	_ *ctrl.CalendarHandler,
 ) *RouteInit {
 	return &RouteInit{}
 }
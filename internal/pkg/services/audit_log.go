@@ -0,0 +1,104 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/models"
+)
+
+type IAuditLogService interface {
+	Record(userInfo *models.UserInfo, record *models.AuditRecord) error
+	QueryPageList(workspaceID string, pageParam dao.PageParam, queryParam dao.AuditLogQueryParam) (int64, []*dao.AuditLog, error)
+}
+
+type AuditLogService struct {
+	AuditLogDao dao.IAuditLogDao
+}
+
+func NewAuditLogService(auditLogDao dao.IAuditLogDao) IAuditLogService {
+	return &AuditLogService{AuditLogDao: auditLogDao}
+}
+
+// Record 记录一次 Token 管理操作
+func (s *AuditLogService) Record(userInfo *models.UserInfo, record *models.AuditRecord) error {
+	diff, err := buildAuditDiff(record.Before, record.After)
+	if err != nil {
+		return xerrors.Errorf("failed to build audit diff: %v", err)
+	}
+	log := &dao.AuditLog{
+		WorkspaceID: record.WorkspaceID,
+		TokenID:     record.TokenID,
+		Action:      record.Action,
+		ActorID:     userInfo.ID,
+		Actor:       userInfo.Username,
+		SourceIP:    record.SourceIP,
+		Diff:        diff,
+		CreateTime:  time.Now(),
+	}
+	if err := s.AuditLogDao.Create(log); err != nil {
+		return xerrors.Errorf("failed to create audit log: %v", err)
+	}
+	return nil
+}
+
+func (s *AuditLogService) QueryPageList(workspaceID string, pageParam dao.PageParam, queryParam dao.AuditLogQueryParam) (int64, []*dao.AuditLog, error) {
+	count, logs, err := s.AuditLogDao.QueryPageList(workspaceID, pageParam, queryParam)
+	if err != nil {
+		return 0, nil, xerrors.Errorf("failed to query audit log list: %v", err)
+	}
+	return count, logs, nil
+}
+
+// buildAuditDiff 按 JSON 字段比较操作前后的 Token，只保留有变化的字段，两者都为空时返回空
+func buildAuditDiff(before, after any) (dao.JSON, error) {
+	beforeFields, err := toFieldMap(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := toFieldMap(after)
+	if err != nil {
+		return nil, err
+	}
+	if beforeFields == nil && afterFields == nil {
+		return nil, nil
+	}
+	diff := make(map[string]*models.AuditDiffItem)
+	for field, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[field]) {
+			diff[field] = &models.AuditDiffItem{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok && value != nil {
+			diff[field] = &models.AuditDiffItem{After: value}
+		}
+	}
+	data, err := json.Marshal(diff)
+	if err != nil {
+		return nil, err
+	}
+	return dao.JSON(data), nil
+}
+
+func toFieldMap(v any) (map[string]any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
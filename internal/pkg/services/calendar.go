@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/samber/lo"
+	"golang.org/x/xerrors"
+	"gorm.io/gorm"
+
+	"github.com/auth-engine/config"
+	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/store"
+	"github.com/auth-engine/pkg/constants"
+)
+
+const calendarCacheKeyPrefix = "calendar:" // Token 关联的日历缓存 key 前缀，后接 token ID
+
+var (
+	// ErrCalendarNotFound 日历不存在或不属于指定的工作空间
+	ErrCalendarNotFound = xerrors.New("calendar not found")
+	// ErrCalendarNameExists 同一工作空间下已存在同名日历
+	ErrCalendarNameExists = xerrors.New("calendar name already exists")
+)
+
+type ICalendarService interface {
+	QueryPageList(workspaceID string, pageParam dao.PageParam, queryParam dao.CalendarQueryParam) (int64, []*models.CalendarResp, error)
+	Get(workspaceID, id string) (*models.CalendarResp, error)
+	Create(workspaceID string, req *models.CalendarReq, userInfo *models.UserInfo) (*models.CalendarResp, error)
+	Update(workspaceID, id string, req *models.CalendarReq, userInfo *models.UserInfo) (*models.CalendarResp, error)
+	Delete(workspaceID, id string) error
+	ListTokenCalendars(tokenID string) ([]*models.CalendarResp, error)
+	SetTokenCalendars(workspaceID, tokenID string, calendarIDs []string) ([]*models.CalendarResp, error)
+	GetTokenCalendars(ctx context.Context, tokenID string) ([]*models.CalendarResp, error)
+}
+
+type CalendarService struct {
+	CalendarDao dao.ICalendarDao
+	AppConfig   *config.AppConfig
+	Store       store.IStore // 多副本共享的 Token 关联日历缓存
+}
+
+func NewCalendarService(calendarDao dao.ICalendarDao, appConfig *config.AppConfig, store store.IStore) ICalendarService {
+	return &CalendarService{CalendarDao: calendarDao, AppConfig: appConfig, Store: store}
+}
+
+func (s *CalendarService) QueryPageList(
+	workspaceID string, pageParam dao.PageParam, queryParam dao.CalendarQueryParam,
+) (int64, []*models.CalendarResp, error) {
+	count, calendars, err := s.CalendarDao.QueryPageList(workspaceID, pageParam, queryParam)
+	if err != nil {
+		return 0, nil, xerrors.Errorf("failed to query calendar list: %v", err)
+	}
+	items, err := s.buildCalendarResps(calendars)
+	if err != nil {
+		return 0, nil, err
+	}
+	return count, items, nil
+}
+
+func (s *CalendarService) Get(workspaceID, id string) (*models.CalendarResp, error) {
+	calendar, err := s.findCalendar(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.buildCalendarResps([]*dao.Calendar{calendar})
+	if err != nil {
+		return nil, err
+	}
+	return items[0], nil
+}
+
+func (s *CalendarService) Create(workspaceID string, req *models.CalendarReq, userInfo *models.UserInfo) (*models.CalendarResp, error) {
+	exists, err := s.CalendarDao.CheckNameExists(workspaceID, req.Name, "")
+	if err != nil {
+		return nil, xerrors.Errorf("failed to check calendar exists: %v", err)
+	}
+	if exists {
+		return nil, ErrCalendarNameExists
+	}
+	calendar := &dao.Calendar{
+		ID:          dao.NewUUID(),
+		WorkspaceID: workspaceID,
+		Name:        req.Name,
+		Description: req.Description,
+		Timezone:    req.Timezone,
+		CreateBy:    userInfo.Username,
+		UpdateBy:    userInfo.Username,
+		CreateTime:  time.Now(),
+		UpdateTime:  time.Now(),
+	}
+	if err := s.CalendarDao.Create(calendar, generateCalendarDates(req.Dates)); err != nil {
+		return nil, xerrors.Errorf("failed to create calendar: %v", err)
+	}
+	return s.Get(workspaceID, calendar.ID)
+}
+
+// Update 更新日历，关联了该日历的 Token 立即按新的日期认证
+func (s *CalendarService) Update(workspaceID, id string, req *models.CalendarReq, userInfo *models.UserInfo) (*models.CalendarResp, error) {
+	calendar, err := s.findCalendar(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := s.CalendarDao.CheckNameExists(workspaceID, req.Name, id)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to check calendar exists: %v", err)
+	}
+	if exists {
+		return nil, ErrCalendarNameExists
+	}
+	calendar.Name = req.Name
+	calendar.Description = req.Description
+	calendar.Timezone = req.Timezone
+	calendar.UpdateBy = userInfo.Username
+	if err := s.CalendarDao.Update(calendar, generateCalendarDates(req.Dates)); err != nil {
+		return nil, xerrors.Errorf("failed to update calendar: %v", err)
+	}
+	s.invalidateCalendarTokens(id)
+	return s.Get(workspaceID, id)
+}
+
+// Delete 删除日历，同时取消 Token 的关联
+func (s *CalendarService) Delete(workspaceID, id string) error {
+	if _, err := s.findCalendar(workspaceID, id); err != nil {
+		return err
+	}
+	tokenIDs, err := s.CalendarDao.ListTokenIDs(id)
+	if err != nil {
+		return xerrors.Errorf("failed to list calendar tokens: %v", err)
+	}
+	if err := s.CalendarDao.Delete(id); err != nil {
+		return xerrors.Errorf("failed to delete calendar: %v", err)
+	}
+	s.invalidateCache(tokenIDs...)
+	return nil
+}
+
+func (s *CalendarService) ListTokenCalendars(tokenID string) ([]*models.CalendarResp, error) {
+	calendars, err := s.CalendarDao.ListByTokenID(tokenID)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to list token calendars: %v", err)
+	}
+	return s.buildCalendarResps(calendars)
+}
+
+// SetTokenCalendars 替换 Token 关联的日历，日历必须属于同一工作空间
+func (s *CalendarService) SetTokenCalendars(workspaceID, tokenID string, calendarIDs []string) ([]*models.CalendarResp, error) {
+	calendarIDs = lo.Uniq(calendarIDs)
+	calendars, err := s.CalendarDao.ListByIDs(workspaceID, calendarIDs)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to list calendars: %v", err)
+	}
+	if len(calendars) != len(calendarIDs) {
+		return nil, ErrCalendarNotFound
+	}
+	if err := s.CalendarDao.ReplaceTokenCalendars(tokenID, calendarIDs); err != nil {
+		return nil, xerrors.Errorf("failed to replace token calendars: %v", err)
+	}
+	s.invalidateCache(tokenID)
+	return s.buildCalendarResps(calendars)
+}
+
+// GetTokenCalendars 获取 Token 关联的日历，用于认证，开启缓存时优先读取缓存
+func (s *CalendarService) GetTokenCalendars(ctx context.Context, tokenID string) ([]*models.CalendarResp, error) {
+	if !s.AppConfig.MySQL.CacheFlag {
+		return s.ListTokenCalendars(tokenID)
+	}
+	key := calendarCacheKeyPrefix + tokenID
+	value, ok, err := s.Store.Get(ctx, key)
+	if err != nil {
+		hlog.CtxWarnf(ctx, "get token calendars from cache failed: %v", err)
+	}
+	if ok {
+		var calendars []*models.CalendarResp
+		if err := json.Unmarshal(value, &calendars); err == nil {
+			return calendars, nil
+		}
+	}
+	calendars, err := s.ListTokenCalendars(tokenID)
+	if err != nil {
+		return nil, err
+	}
+	value, err = json.Marshal(calendars)
+	if err == nil {
+		err = s.Store.Set(ctx, key, value, s.AppConfig.MySQL.CacheExpiration)
+	}
+	if err != nil {
+		hlog.CtxWarnf(ctx, "set token calendars cache failed: %v", err)
+	}
+	return calendars, nil
+}
+
+func (s *CalendarService) findCalendar(workspaceID, id string) (*dao.Calendar, error) {
+	calendar, err := s.CalendarDao.Get(workspaceID, id)
+	if xerrors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCalendarNotFound
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get calendar: %v", err)
+	}
+	return calendar, nil
+}
+
+func (s *CalendarService) buildCalendarResps(calendars []*dao.Calendar) ([]*models.CalendarResp, error) {
+	calendarIDs := lo.Map(calendars, func(calendar *dao.Calendar, _ int) string {
+		return calendar.ID
+	})
+	dates, err := s.CalendarDao.ListDates(calendarIDs)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to list calendar dates: %v", err)
+	}
+	datesByCalendar := lo.GroupBy(dates, func(date *dao.CalendarDate) string {
+		return date.CalendarID
+	})
+	return lo.Map(calendars, func(calendar *dao.Calendar, _ int) *models.CalendarResp {
+		return &models.CalendarResp{
+			Calendar: calendar,
+			Dates: lo.Map(datesByCalendar[calendar.ID], func(date *dao.CalendarDate, _ int) *models.CalendarDateRange {
+				return &models.CalendarDateRange{
+					StartDate: date.StartDate.Format(constants.DateRangeTimeFormat),
+					EndDate:   date.EndDate.Format(constants.DateRangeTimeFormat),
+					Note:      date.Note,
+				}
+			}),
+		}
+	}), nil
+}
+
+// invalidateCalendarTokens 日历变更后删除关联了该日历的 Token 的缓存
+func (s *CalendarService) invalidateCalendarTokens(calendarID string) {
+	if !s.AppConfig.MySQL.CacheFlag {
+		return
+	}
+	tokenIDs, err := s.CalendarDao.ListTokenIDs(calendarID)
+	if err != nil {
+		hlog.Warnf("list calendar tokens failed: %v", err)
+		return
+	}
+	s.invalidateCache(tokenIDs...)
+}
+
+// invalidateCache 删除 Token 关联的日历缓存，使用 Redis 时所有副本同时失效
+func (s *CalendarService) invalidateCache(tokenIDs ...string) {
+	if !s.AppConfig.MySQL.CacheFlag || len(tokenIDs) == 0 {
+		return
+	}
+	keys := lo.Map(tokenIDs, func(tokenID string, _ int) string {
+		return calendarCacheKeyPrefix + tokenID
+	})
+	if err := s.Store.Delete(context.Background(), keys...); err != nil {
+		hlog.Warnf("delete token calendars cache failed: %v", err)
+	}
+}
+
+// generateCalendarDates 生成日期范围记录，结束日期为空时与开始日期相同，日期格式已在接口中校验
+func generateCalendarDates(dateRanges []*models.CalendarDateRange) []*dao.CalendarDate {
+	return lo.Map(dateRanges, func(dateRange *models.CalendarDateRange, _ int) *dao.CalendarDate {
+		startDate, _ := time.ParseInLocation(constants.DateRangeTimeFormat, dateRange.StartDate, time.Local)
+		endDate := startDate
+		if dateRange.EndDate != "" {
+			endDate, _ = time.ParseInLocation(constants.DateRangeTimeFormat, dateRange.EndDate, time.Local)
+		}
+		return &dao.CalendarDate{StartDate: startDate, EndDate: endDate, Note: dateRange.Note}
+	})
+}
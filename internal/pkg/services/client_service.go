@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"strconv"
 
 	ghippov1alpha1 "ghippo.io/api/wssdk/v1alpha1/types"
 	wsypes "ghippo.io/api/wssdk/v1alpha1/types"
@@ -14,6 +15,7 @@ import (
 type IClientService interface {
 	ListCR(ctx context.Context, cluster, namespace, name, group, resource, version, workspace string, page *types.Pagination) (*apiv1alpha1.ListCustomResourcesResponse, error)
 	ListVisibleWorkspaces(ctx context.Context) ([]ghippov1alpha1.Workspace, error)
+	GetWorkspaceRoles(ctx context.Context, workspaceID string) ([]string, error)
 }
 type ClientService struct {
 	DceClient *clients.DCEClient
@@ -34,3 +36,12 @@ func (s *ClientService) ListCR(ctx context.Context, cluster, namespace, name, gr
 func (s *ClientService) ListVisibleWorkspaces(ctx context.Context) ([]ghippov1alpha1.Workspace, error) {
 	return s.GhippoSdk.ListVisibleWorkspaces(ctx)
 }
+
+// GetWorkspaceRoles 查询当前用户在工作空间中的 Ghippo 角色，如 workspace-admin
+func (s *ClientService) GetWorkspaceRoles(ctx context.Context, workspaceID string) ([]string, error) {
+	id, err := strconv.Atoi(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return s.GhippoSdk.GetWorkspaceRoles(ctx, id)
+}
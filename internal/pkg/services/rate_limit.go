@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/models"
+)
+
+const rateLimitKeyPrefix = "rate:" // Token 请求计数 key 前缀，后接 token ID、窗口名称和窗口序号
+
+type rateLimitWindow struct {
+	name   string
+	size   time.Duration
+	limit  int
+	window *models.RateLimitWindow
+}
+
+// CheckRateLimit 检查 Token 的每分钟和每天请求数，允许时计入本次请求
+// 使用滑动窗口计数：当前窗口的请求数加上上一个窗口的请求数按剩余比例折算，未配置上限的窗口不检查，now 为本次请求的时间
+func (s *TokenService) CheckRateLimit(ctx context.Context, info *dao.TokenAuthInfo, now time.Time) (*models.RateLimitResult, error) {
+	result := &models.RateLimitResult{Allowed: true}
+	var counted []string
+	for _, w := range rateLimitWindows(info) {
+		if w.limit <= 0 {
+			continue
+		}
+		key, retryAfter, err := s.slideWindow(ctx, info.TokenID, w, now)
+		if err != nil {
+			return nil, err
+		}
+		result.Windows = append(result.Windows, w.window)
+		if retryAfter > 0 {
+			// 已计入的其他窗口回退本次请求
+			for _, key := range counted {
+				if _, err := s.Store.IncrBy(ctx, key, -1, w.size); err != nil {
+					return nil, xerrors.Errorf("failed to rollback rate limit counter: %v", err)
+				}
+			}
+			result.Allowed = false
+			result.RetryAfter = retryAfter
+			return result, nil
+		}
+		counted = append(counted, key)
+	}
+	return result, nil
+}
+
+// RefundRateLimit 退回 CheckRateLimit 允许时计入的本次请求，用于之后因并发数超限等原因被拒绝的请求，
+// now 与 CheckRateLimit 相同，result 中的剩余额度同时加回
+func (s *TokenService) RefundRateLimit(ctx context.Context, info *dao.TokenAuthInfo, result *models.RateLimitResult, now time.Time) error {
+	for _, w := range rateLimitWindows(info) {
+		if w.limit <= 0 {
+			continue
+		}
+		key := rateLimitKey(info.TokenID, w.name, now.UnixNano()/int64(w.size))
+		if _, err := s.Store.IncrBy(ctx, key, -1, 2*w.size); err != nil {
+			return xerrors.Errorf("failed to refund rate limit counter: %v", err)
+		}
+	}
+	for _, window := range result.Windows {
+		window.Remaining = min(window.Remaining+1, window.Limit)
+	}
+	return nil
+}
+
+// rateLimitWindows Token 的每分钟和每天请求数限制窗口
+func rateLimitWindows(info *dao.TokenAuthInfo) []*rateLimitWindow {
+	return []*rateLimitWindow{
+		{name: "Minute", size: time.Minute, limit: info.RequestsPerMinute},
+		{name: "Day", size: 24 * time.Hour, limit: info.RequestsPerDay},
+	}
+}
+
+// rateLimitKey 第 index 个窗口的请求计数 key
+func rateLimitKey(tokenID, name string, index int64) string {
+	return fmt.Sprintf("%s%s:%s:%d", rateLimitKeyPrefix, tokenID, name, index)
+}
+
+// slideWindow 计入一次请求，超出上限时回退并返回需要等待的时间
+func (s *TokenService) slideWindow(ctx context.Context, tokenID string, w *rateLimitWindow, now time.Time) (string, time.Duration, error) {
+	index := now.UnixNano() / int64(w.size)
+	elapsed := now.Sub(time.Unix(0, index*int64(w.size)))
+	key := rateLimitKey(tokenID, w.name, index)
+	prevKey := rateLimitKey(tokenID, w.name, index-1)
+	// 计数器保留两个窗口，供下一个窗口折算
+	current, err := s.Store.IncrBy(ctx, key, 1, 2*w.size)
+	if err != nil {
+		return "", 0, xerrors.Errorf("failed to incr rate limit counter: %v", err)
+	}
+	previous, err := s.Store.IncrBy(ctx, prevKey, 0, w.size)
+	if err != nil {
+		return "", 0, xerrors.Errorf("failed to get rate limit counter: %v", err)
+	}
+	weight := 1 - float64(elapsed)/float64(w.size)
+	estimate := float64(previous)*weight + float64(current)
+	w.window = &models.RateLimitWindow{Name: w.name, Limit: w.limit}
+	if estimate <= float64(w.limit) {
+		w.window.Remaining = w.limit - int(math.Ceil(estimate))
+		return key, 0, nil
+	}
+	if _, err := s.Store.IncrBy(ctx, key, -1, 2*w.size); err != nil {
+		return "", 0, xerrors.Errorf("failed to rollback rate limit counter: %v", err)
+	}
+	return key, retryAfter(previous, current-1, w.limit, elapsed, w.size), nil
+}
+
+// retryAfter 计算再次请求时折算后的请求数不超过上限需要等待的时间，至少 1 秒
+func retryAfter(previous, current int64, limit int, elapsed, size time.Duration) time.Duration {
+	allowed := float64(limit - 1)
+	var wait float64
+	if float64(current) > allowed {
+		// 当前窗口已满，等到下一个窗口，当前窗口的请求数按比例折算
+		wait = float64(size - elapsed)
+		if current > 0 {
+			wait += math.Max(0, float64(size)*(1-allowed/float64(current)))
+		}
+	} else {
+		wait = float64(size)*(1-(allowed-float64(current))/float64(previous)) - float64(elapsed)
+	}
+	seconds := math.Ceil(time.Duration(wait).Seconds())
+	return time.Duration(math.Max(seconds, 1)) * time.Second
+}
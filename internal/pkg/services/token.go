@@ -1,44 +1,86 @@
 package services
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"github.com/samber/lo"
 	"golang.org/x/xerrors"
+	"gorm.io/gorm"
 
 	"github.com/auth-engine/config"
 	"github.com/auth-engine/internal/pkg/dao"
 	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/store"
+	"github.com/auth-engine/internal/pkg/utils"
 	"github.com/auth-engine/pkg/constants"
 )
 
+const (
+	tokenPrefixLen = 7 // token 展示前缀长度，如 sk-AbCd
+	tokenLast4Len  = 4 // token 展示后缀长度
+
+	authCacheKeyPrefix = "auth:"  // Token 认证信息缓存 key 前缀，后接 token 摘要
+	leaseKeyPrefix     = "lease:" // Token 并发租约 key 前缀，后接 token ID
+)
+
+var (
+	// ErrConcurrencyLimitExceeded Token 并发数已达到 MaxConcurrency
+	ErrConcurrencyLimitExceeded = xerrors.New("token concurrency limit exceeded")
+	// ErrTokenNotFound Token 不存在或不属于指定的工作空间
+	ErrTokenNotFound = xerrors.New("token not found")
+)
+
 type ITokenService interface {
-	GetTokenAuthInfo(token string) ([]*dao.TokenAuthInfo, error)
+	GetTokenAuthInfo(ctx context.Context, token string) ([]*dao.TokenAuthInfo, error)
+	GetTokenAuthInfoByID(workspaceID, id string) ([]*dao.TokenAuthInfo, error)
 	CheckTokenExists(appScenarioName, modelName, envName, id string) (bool, error)
 	GenerateToken() (string, error)
+	NewTokenDigest(token string) *dao.TokenDigest
+	PepperFingerprint() string
 	QueryPageList(
 		workspaceID string, pageParam dao.PageParam, orderParam dao.OrderParam, queryParam dao.TokenQueryParam,
 	) (int64, []*models.TokenBaseEntity, error)
-	Create(req *models.CreateTokenReq, userInfo *models.UserInfo, tokenStr string) (*dao.TokenEntity, error)
-	Get(id string) (*models.TokenResp, error)
+	Create(req *models.CreateTokenReq, userInfo *models.UserInfo, digest *dao.TokenDigest) (*models.CreateTokenResp, error)
+	Get(workspaceID, id string) (*models.TokenResp, error)
+	GetExportEntity(workspaceID, id string) (*models.TokenExportEntity, error)
+	ListExportEntities(workspaceID string, queryParam dao.TokenQueryParam) ([]*models.TokenExportEntity, error)
+	FindByTokenHash(tokenHash string) (*dao.TokenEntity, error)
+	FindByName(appScenarioName, modelName, envName string) (*dao.TokenEntity, error)
+	Overwrite(tokenEntity *dao.TokenEntity, req *models.CreateTokenReq, digest *dao.TokenDigest, userInfo *models.UserInfo) (*dao.TokenEntity, error)
 	Update(id string, req *models.UpdateTokenReq, tokenEntity *dao.TokenEntity, userInfo *models.UserInfo) (*dao.TokenEntity, error)
-	Delete(id string) error
-	FindTokenEntity(id string) (*dao.TokenEntity, error)
+	Delete(workspaceID, id string) error
+	FindTokenEntity(workspaceID, id string) (*dao.TokenEntity, error)
+	HashPlaintextTokens() (int, error)
+	AcquireLease(ctx context.Context, tokenID string, maxConcurrency int) (*models.TokenAuthResp, error)
+	ReleaseLease(ctx context.Context, tokenID, leaseID string) (bool, error)
+	CheckRateLimit(ctx context.Context, info *dao.TokenAuthInfo, now time.Time) (*models.RateLimitResult, error)
+	RefundRateLimit(ctx context.Context, info *dao.TokenAuthInfo, result *models.RateLimitResult, now time.Time) error
+	Rotate(tokenEntity *dao.TokenEntity, gracePeriod time.Duration, userInfo *models.UserInfo) (*models.CreateTokenResp, error)
+	Suspend(tokenEntity *dao.TokenEntity, reason string, resumeTime *time.Time, userInfo *models.UserInfo) (*dao.TokenEntity, error)
+	Resume(tokenEntity *dao.TokenEntity, userInfo *models.UserInfo) (*dao.TokenEntity, error)
+	InvalidateAuthCache(tokenEntity *dao.TokenEntity)
 }
 
 type TokenService struct {
 	TokenDao  dao.ITokenDao
 	PolicyDao dao.ITokenValidityPolicyDao
+	AppConfig *config.AppConfig
+	Store     store.IStore // 多副本共享的认证信息缓存
 }
 
-func NewTokenService(tokenDao dao.ITokenDao, policyDao dao.ITokenValidityPolicyDao) ITokenService {
+func NewTokenService(tokenDao dao.ITokenDao, policyDao dao.ITokenValidityPolicyDao, appConfig *config.AppConfig, store store.IStore) ITokenService {
 	return &TokenService{
 		TokenDao:  tokenDao,
 		PolicyDao: policyDao,
+		AppConfig: appConfig,
+		Store:     store,
 	}
 }
 
@@ -63,6 +105,27 @@ func (s *TokenService) GenerateToken() (string, error) {
 	return apiKey, nil
 }
 
+// PepperFingerprint 当前 pepper 的指纹，导出文件中的摘要只能导入到 pepper 相同的环境
+func (s *TokenService) PepperFingerprint() string {
+	return utils.PepperFingerprint(s.AppConfig.Auth.TokenPepper)
+}
+
+// NewTokenDigest 计算 Token 的摘要、展示前缀和后四位
+func (s *TokenService) NewTokenDigest(token string) *dao.TokenDigest {
+	digest := &dao.TokenDigest{
+		TokenHash:   utils.HashToken(s.AppConfig.Auth.TokenPepper, token),
+		TokenPrefix: token,
+		TokenLast4:  token,
+	}
+	if len(token) > tokenPrefixLen {
+		digest.TokenPrefix = token[:tokenPrefixLen]
+	}
+	if len(token) > tokenLast4Len {
+		digest.TokenLast4 = token[len(token)-tokenLast4Len:]
+	}
+	return digest
+}
+
 func (s *TokenService) CheckTokenExists(appScenarioName, modelName, envName, id string) (bool, error) {
 	return s.TokenDao.CheckTokenExists(appScenarioName, modelName, envName, id)
 }
@@ -90,25 +153,38 @@ func (s *TokenService) QueryPageList(
 			ExpiredTime:          &expiredTime,
 			AppScenarioName:      token.AppScenarioName,
 			ModelName:            token.ModelName,
+			ModelPatterns:        token.ModelPatterns,
 			EnvName:              token.EnvName,
 			EnvAlias:             envMap[token.EnvName], // 环境别名
 			MaxConcurrency:       token.MaxConcurrency,
+			RequestsPerMinute:    token.RequestsPerMinute,
+			RequestsPerDay:       token.RequestsPerDay,
 			EnableValidityPolicy: token.EnableValidityPolicy,
 			PolicyType:           token.PolicyType,
-			Token:                token.Token,
+			Timezone:             token.Timezone,
+			TokenPrefix:          token.TokenPrefix,
+			TokenLast4:           token.TokenLast4,
+			Status:               dao.EffectiveStatus(token.Status, token.ResumeTime, time.Now()),
+		}
+		if item.Status == constants.TokenStatusSuspended {
+			item.SuspendReason = token.SuspendReason
+			item.ResumeTime = formatTime(token.ResumeTime)
 		}
 		return item
 	})
 	return count, items, nil
 }
 
+// GeneratePolicyEntitys 生成策略记录，策略未指定 policyType 时使用 Token 的 policyType
 func (s *TokenService) GeneratePolicyEntitys(tokenID, policyType string, validityPolicy []*models.ValidityPolicy) []*dao.TokenValidityPolicy {
 	policyEntitys := lo.Map(validityPolicy, func(policy *models.ValidityPolicy, _ int) *dao.TokenValidityPolicy {
 		policyEntity := &dao.TokenValidityPolicy{
 			TokenID:    tokenID,
-			PolicyType: policyType,
+			PolicyType: lo.CoalesceOrEmpty(policy.PolicyType, policyType),
+			Timezone:   policy.Timezone,
 			StartDay:   policy.StartDay,
 			EndDay:     policy.EndDay,
+			Continuous: policy.Continuous,
 			CreateTime: time.Now(),
 			UpdateTime: time.Now(),
 		}
@@ -130,22 +206,41 @@ func (s *TokenService) GeneratePolicyEntitys(tokenID, policyType string, validit
 			endDate, _ := time.ParseInLocation(constants.DateRangeTimeFormat, *policy.EndDate, time.Local)
 			policyEntity.EndDate = &endDate
 		}
+		if policy.CronExpr != nil && policy.CronDuration != nil {
+			cronDuration, _ := time.ParseDuration(*policy.CronDuration)
+			policyEntity.CronExpr = *policy.CronExpr
+			policyEntity.CronDuration = int(cronDuration / time.Second)
+		}
 		return policyEntity
 	})
 	return policyEntitys
 }
 
-func (s *TokenService) Create(req *models.CreateTokenReq, userInfo *models.UserInfo, tokenStr string) (*dao.TokenEntity, error) {
+// tokenPolicyType Token 的 policyType 为空时使用第一个策略的类型，未启用有效期策略时为空
+func tokenPolicyType(enableValidityPolicy bool, policyType string, validityPolicy []*models.ValidityPolicy) string {
+	if !enableValidityPolicy || policyType != "" || len(validityPolicy) == 0 {
+		return policyType
+	}
+	return validityPolicy[0].PolicyType
+}
+
+// Create 创建 Token，digest 为空时生成新的 Token，并在返回结果中携带一次 Token 明文
+func (s *TokenService) Create(req *models.CreateTokenReq, userInfo *models.UserInfo, digest *dao.TokenDigest) (*models.CreateTokenResp, error) {
 	tokenID := dao.NewUUID()
 	tokenEntity := &dao.TokenEntity{
 		ID:                   tokenID,
 		WorkspaceID:          req.WorkspaceID,
 		AppScenarioName:      req.AppScenarioName,
 		ModelName:            req.ModelName,
+		ModelPatterns:        req.ModelPatterns,
 		EnvName:              req.EnvName,
 		EnableValidityPolicy: req.EnableValidityPolicy,
 		MaxConcurrency:       req.MaxConcurrency,
-		PolicyType:           req.PolicyType,
+		RequestsPerMinute:    req.RequestsPerMinute,
+		RequestsPerDay:       req.RequestsPerDay,
+		PolicyType:           tokenPolicyType(req.EnableValidityPolicy, req.PolicyType, req.ValidityPolicy),
+		Timezone:             req.Timezone,
+		Status:               constants.TokenStatusActive,
 		CommonModel: dao.CommonModel{
 			CreateBy:   userInfo.Username,
 			CreateTime: time.Now(),
@@ -157,15 +252,16 @@ func (s *TokenService) Create(req *models.CreateTokenReq, userInfo *models.UserI
 		expiredTime, _ := time.ParseInLocation(constants.TimeFormat, *req.ExpiredTime, time.Local)
 		tokenEntity.ExpiredTime = &expiredTime
 	}
-	if tokenStr == "" {
+	resp := &models.CreateTokenResp{TokenEntity: tokenEntity}
+	if digest == nil {
 		token, err := s.GenerateToken()
 		if err != nil {
 			return nil, xerrors.Errorf("failed to generate token: %v", err)
 		}
-		tokenEntity.Token = token
-	} else {
-		tokenEntity.Token = tokenStr
+		digest = s.NewTokenDigest(token)
+		resp.Token = token
 	}
+	tokenEntity.TokenDigest = *digest
 	// 创建有效期策略
 	if req.EnableValidityPolicy {
 		policyEntitys := s.GeneratePolicyEntitys(tokenID, req.PolicyType, req.ValidityPolicy)
@@ -176,14 +272,20 @@ func (s *TokenService) Create(req *models.CreateTokenReq, userInfo *models.UserI
 	if err := s.TokenDao.Create(tokenEntity); err != nil {
 		return nil, xerrors.Errorf("failed to create token: %v", err)
 	}
-	return tokenEntity, nil
+	s.invalidateAuthCache(tokenEntity.TokenHash)
+	return resp, nil
 }
 
-func (s *TokenService) Get(id string) (*models.TokenResp, error) {
-	token, err := s.TokenDao.Get(id)
+func (s *TokenService) Get(workspaceID, id string) (*models.TokenResp, error) {
+	token, err := s.FindTokenEntity(workspaceID, id)
 	if err != nil {
-		return nil, xerrors.Errorf("failed to get token: %v", err)
+		return nil, err
 	}
+	return s.buildTokenResp(token)
+}
+
+// buildTokenResp 组装 Token 详情，包含有效期策略
+func (s *TokenService) buildTokenResp(token *dao.TokenEntity) (*models.TokenResp, error) {
 	envMap := config.GetEnvMap()
 	var expiredTime string
 	if token.ExpiredTime != nil {
@@ -201,15 +303,31 @@ func (s *TokenService) Get(id string) (*models.TokenResp, error) {
 		EnvName:              token.EnvName,
 		EnvAlias:             envMap[token.EnvName], // 环境别名
 		ModelName:            token.ModelName,
+		ModelPatterns:        token.ModelPatterns,
 		MaxConcurrency:       token.MaxConcurrency,
+		RequestsPerMinute:    token.RequestsPerMinute,
+		RequestsPerDay:       token.RequestsPerDay,
 		EnableValidityPolicy: token.EnableValidityPolicy,
 		PolicyType:           token.PolicyType,
-		Token:                token.Token,
+		Timezone:             token.Timezone,
+		TokenPrefix:          token.TokenPrefix,
+		TokenLast4:           token.TokenLast4,
+	}
+	resp.Status = dao.EffectiveStatus(token.Status, token.ResumeTime, time.Now())
+	if resp.Status == constants.TokenStatusSuspended {
+		resp.SuspendReason = token.SuspendReason
+		resp.SuspendTime = formatTime(token.SuspendTime)
+		resp.ResumeTime = formatTime(token.ResumeTime)
+	}
+	if token.PreviousTokenExpiredTime != nil && token.PreviousTokenExpiredTime.After(time.Now()) {
+		previousExpiredTime := token.PreviousTokenExpiredTime.Format(constants.TimeFormat)
+		resp.PreviousExpiredTime = &previousExpiredTime
 	}
 	if !token.EnableValidityPolicy {
 		return resp, nil
 	}
-	policys, err := s.PolicyDao.ListByTokenID(id, token.PolicyType)
+	// 同一个 Token 可以包含不同类型的策略，不按 Token 的 policyType 过滤
+	policys, err := s.PolicyDao.ListByTokenID(token.ID, "")
 	if err != nil {
 		return nil, xerrors.Errorf("failed to get token policy: %v", err)
 	}
@@ -227,7 +345,7 @@ func (s *TokenService) Get(id string) (*models.TokenResp, error) {
 		if policy.EndDate != nil {
 			endDate = policy.EndDate.Format(constants.DateRangeTimeFormat)
 		}
-		return &models.ValidityPolicy{
+		item := &models.ValidityPolicy{
 			ID:         int32(policy.ID),
 			TokenID:    policy.TokenID,
 			PolicyType: policy.PolicyType,
@@ -237,18 +355,143 @@ func (s *TokenService) Get(id string) (*models.TokenResp, error) {
 			EndDay:     policy.EndDay,
 			StartDate:  &sartDate,
 			EndDate:    &endDate,
+			Timezone:   policy.Timezone,
+			Continuous: policy.Continuous,
 		}
+		if policy.CronExpr != "" {
+			cronDuration := (time.Duration(policy.CronDuration) * time.Second).String()
+			item.CronExpr, item.CronDuration = &policy.CronExpr, &cronDuration
+		}
+		return item
 	})
 	resp.ValidityPolicy = policyData
 	return resp, nil
 }
 
+// GetExportEntity 获取导出文件内容，导出文件中只包含 Token 摘要
+func (s *TokenService) GetExportEntity(workspaceID, id string) (*models.TokenExportEntity, error) {
+	token, err := s.FindTokenEntity(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.buildTokenResp(token)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TokenExportEntity{TokenResp: *resp, TokenHash: token.TokenHash, PepperFingerprint: s.PepperFingerprint()}, nil
+}
+
+// ListExportEntities 获取工作空间下符合过滤条件的全部 Token 的导出内容，按创建时间排序
+func (s *TokenService) ListExportEntities(workspaceID string, queryParam dao.TokenQueryParam) ([]*models.TokenExportEntity, error) {
+	_, tokens, err := s.TokenDao.QueryPageList(
+		workspaceID, dao.PageParam{PageSize: -1}, dao.OrderParam{Column: "createTime", Order: "asc"}, queryParam,
+	)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to query token list: %v", err)
+	}
+	entities := make([]*models.TokenExportEntity, 0, len(tokens))
+	for _, token := range tokens {
+		resp, err := s.buildTokenResp(token)
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, &models.TokenExportEntity{TokenResp: *resp, TokenHash: token.TokenHash, PepperFingerprint: s.PepperFingerprint()})
+	}
+	return entities, nil
+}
+
+// FindByTokenHash 按摘要查询 Token，不存在时返回 ErrTokenNotFound
+func (s *TokenService) FindByTokenHash(tokenHash string) (*dao.TokenEntity, error) {
+	token, err := s.TokenDao.GetByTokenHash(tokenHash)
+	if xerrors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get token by hash: %v", err)
+	}
+	return token, nil
+}
+
+// FindByName 按应用场景、模型和环境查询 Token，不存在时返回 ErrTokenNotFound
+func (s *TokenService) FindByName(appScenarioName, modelName, envName string) (*dao.TokenEntity, error) {
+	token, err := s.TokenDao.GetByName(appScenarioName, modelName, envName)
+	if xerrors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get token by name: %v", err)
+	}
+	return token, nil
+}
+
+// Overwrite 使用导入的内容覆盖已有 Token 的过期时间、限制、有效期策略和密钥，Token ID 不变
+func (s *TokenService) Overwrite(
+	tokenEntity *dao.TokenEntity, req *models.CreateTokenReq, digest *dao.TokenDigest, userInfo *models.UserInfo,
+) (*dao.TokenEntity, error) {
+	oldTokenHash, oldPreviousTokenHash := tokenEntity.TokenHash, tokenEntity.PreviousTokenHash
+	policies := s.applyTokenUpdate(tokenEntity, &models.UpdateTokenReq{
+		WorkspaceID:          tokenEntity.WorkspaceID,
+		ExpiredTime:          req.ExpiredTime,
+		AppScenarioName:      req.AppScenarioName,
+		ModelName:            req.ModelName,
+		ModelPatterns:        req.ModelPatterns,
+		MaxConcurrency:       req.MaxConcurrency,
+		RequestsPerMinute:    req.RequestsPerMinute,
+		RequestsPerDay:       req.RequestsPerDay,
+		EnableValidityPolicy: req.EnableValidityPolicy,
+		PolicyType:           req.PolicyType,
+		Timezone:             req.Timezone,
+		ValidityPolicy:       req.ValidityPolicy,
+	}, userInfo)
+	// 密钥未变化时只更新属性
+	if digest.TokenHash == oldTokenHash {
+		digest = nil
+	}
+	if err := s.TokenDao.Overwrite(tokenEntity.ID, tokenEntity, policies, digest); err != nil {
+		return nil, xerrors.Errorf("failed to overwrite token: %v", err)
+	}
+	if digest == nil {
+		s.invalidateAuthCache(tokenEntity.TokenHash, tokenEntity.PreviousTokenHash)
+		return tokenEntity, nil
+	}
+	tokenEntity.TokenDigest = *digest
+	tokenEntity.PreviousTokenHash = ""
+	tokenEntity.PreviousTokenExpiredTime = nil
+	s.invalidateAuthCache(oldTokenHash, oldPreviousTokenHash, digest.TokenHash)
+	return tokenEntity, nil
+}
+
 func (s *TokenService) Update(id string, req *models.UpdateTokenReq, tokenEntity *dao.TokenEntity, userInfo *models.UserInfo) (*dao.TokenEntity, error) {
+	policies := s.applyTokenUpdate(tokenEntity, req, userInfo)
+	// 先删除原有的策略，在创建
+	if err := s.PolicyDao.DeleteByTokenID(id); err != nil {
+		return nil, xerrors.Errorf("failed to delete old token validity policy: %v", err)
+	}
+	if len(policies) > 0 {
+		if err := s.PolicyDao.BatchCreate(policies, 100); err != nil {
+			return nil, xerrors.Errorf("failed to create token validity policy: %v", err)
+		}
+	}
+	if err := s.TokenDao.Update(id, tokenEntity); err != nil {
+		return nil, xerrors.Errorf("failed to update token: %v", err)
+	}
+	s.invalidateAuthCache(tokenEntity.TokenHash, tokenEntity.PreviousTokenHash)
+	return tokenEntity, nil
+}
+
+// applyTokenUpdate 将更新参数写入 tokenEntity，返回需要重新创建的有效期策略
+func (s *TokenService) applyTokenUpdate(
+	tokenEntity *dao.TokenEntity, req *models.UpdateTokenReq, userInfo *models.UserInfo,
+) []*dao.TokenValidityPolicy {
 	tokenEntity.AppScenarioName = req.AppScenarioName
 	tokenEntity.ModelName = req.ModelName
+	tokenEntity.ModelPatterns = req.ModelPatterns
 	tokenEntity.EnableValidityPolicy = req.EnableValidityPolicy
 	tokenEntity.MaxConcurrency = req.MaxConcurrency
-	tokenEntity.PolicyType = req.PolicyType
+	tokenEntity.RequestsPerMinute = req.RequestsPerMinute
+	tokenEntity.RequestsPerDay = req.RequestsPerDay
+	tokenEntity.PolicyType = tokenPolicyType(req.EnableValidityPolicy, req.PolicyType, req.ValidityPolicy)
+	tokenEntity.Timezone = req.Timezone
 	tokenEntity.UpdateBy = userInfo.Username
 	tokenEntity.UpdateTime = time.Now()
 	tokenEntity.ExpiredTime = nil
@@ -256,36 +499,224 @@ func (s *TokenService) Update(id string, req *models.UpdateTokenReq, tokenEntity
 		expiredTime, _ := time.ParseInLocation(constants.TimeFormat, *req.ExpiredTime, time.Local)
 		tokenEntity.ExpiredTime = &expiredTime
 	}
-	// 先删除原有的策略，在创建
-	if err := s.PolicyDao.DeleteByTokenID(id); err != nil {
-		return nil, xerrors.Errorf("failed to delete old token validity policy: %v", err)
-	}
-	if req.EnableValidityPolicy {
-		policyEntitys := s.GeneratePolicyEntitys(id, req.PolicyType, req.ValidityPolicy)
-		if err := s.PolicyDao.BatchCreate(policyEntitys, 100); err != nil {
-			return nil, xerrors.Errorf("failed to create token validity policy: %v", err)
-		}
-	}
-	if err := s.TokenDao.Update(id, tokenEntity); err != nil {
-		return nil, xerrors.Errorf("failed to update token: %v", err)
+	if !req.EnableValidityPolicy {
+		return nil
 	}
-	return tokenEntity, nil
+	return s.GeneratePolicyEntitys(tokenEntity.ID, req.PolicyType, req.ValidityPolicy)
 }
 
-func (s *TokenService) FindTokenEntity(id string) (*dao.TokenEntity, error) {
-	return s.TokenDao.Get(id)
+// FindTokenEntity 查询工作空间下的 Token，不存在或不属于该工作空间时返回 ErrTokenNotFound
+func (s *TokenService) FindTokenEntity(workspaceID, id string) (*dao.TokenEntity, error) {
+	token, err := s.TokenDao.Get(workspaceID, id)
+	if xerrors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get token: %v", err)
+	}
+	return token, nil
 }
 
-func (s *TokenService) Delete(id string) error {
+func (s *TokenService) Delete(workspaceID, id string) error {
+	token, err := s.FindTokenEntity(workspaceID, id)
+	if err != nil {
+		return err
+	}
 	if err := s.PolicyDao.DeleteByTokenID(id); err != nil {
 		return xerrors.Errorf("failed to delete old token validity policy: %v", err)
 	}
 	if err := s.TokenDao.Delete(id); err != nil {
 		return xerrors.Errorf("failed to delete token: %v", err)
 	}
+	s.invalidateAuthCache(token.TokenHash, token.PreviousTokenHash)
 	return nil
 }
 
-func (s *TokenService) GetTokenAuthInfo(token string) ([]*dao.TokenAuthInfo, error) {
-	return s.TokenDao.GetTokenAuthInfo(token)
+// GetTokenAuthInfo 获取 Token 认证信息，开启缓存时优先读取缓存，不存在的 Token 也会缓存较短的时间
+// 缓存读写失败时直接查询数据库，轮换前的密钥超过宽限期后视为不存在
+func (s *TokenService) GetTokenAuthInfo(ctx context.Context, token string) ([]*dao.TokenAuthInfo, error) {
+	infos, err := s.getTokenAuthInfo(ctx, utils.HashToken(s.AppConfig.Auth.TokenPepper, token))
+	if err != nil || len(infos) == 0 {
+		return infos, err
+	}
+	if infos[0].MatchedSecret == constants.MatchedSecretPrevious {
+		expiredTime := infos[0].PreviousTokenExpiredTime
+		if expiredTime == nil || !time.Now().Before(*expiredTime) {
+			return nil, nil
+		}
+	}
+	return infos, nil
+}
+
+// GetTokenAuthInfoByID 按 Token ID 查询认证信息，不使用缓存，Token 不存在时返回 ErrTokenNotFound
+func (s *TokenService) GetTokenAuthInfoByID(workspaceID, id string) ([]*dao.TokenAuthInfo, error) {
+	infos, err := s.TokenDao.GetTokenAuthInfoByID(workspaceID, id)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get token auth info: %v", err)
+	}
+	if len(infos) == 0 {
+		return nil, ErrTokenNotFound
+	}
+	return infos, nil
+}
+
+func (s *TokenService) getTokenAuthInfo(ctx context.Context, tokenHash string) ([]*dao.TokenAuthInfo, error) {
+	if !s.AppConfig.MySQL.CacheFlag {
+		return s.TokenDao.GetTokenAuthInfo(tokenHash)
+	}
+	key := authCacheKeyPrefix + tokenHash
+	value, ok, err := s.Store.Get(ctx, key)
+	if err != nil {
+		hlog.CtxWarnf(ctx, "get token auth info from cache failed: %v", err)
+	}
+	if ok {
+		var infos []*dao.TokenAuthInfo
+		if err := json.Unmarshal(value, &infos); err == nil {
+			return infos, nil
+		}
+	}
+	infos, err := s.TokenDao.GetTokenAuthInfo(tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	ttl := s.AppConfig.MySQL.CacheExpiration
+	if len(infos) == 0 {
+		ttl = s.AppConfig.MySQL.CacheNegativeExpiration
+	}
+	value, err = json.Marshal(infos)
+	if err == nil {
+		err = s.Store.Set(ctx, key, value, ttl)
+	}
+	if err != nil {
+		hlog.CtxWarnf(ctx, "set token auth info cache failed: %v", err)
+	}
+	return infos, nil
+}
+
+// InvalidateAuthCache 删除 Token 当前密钥和轮换前密钥的认证信息缓存，用于 Token 以外的关联数据（如日历）变更后
+func (s *TokenService) InvalidateAuthCache(tokenEntity *dao.TokenEntity) {
+	s.invalidateAuthCache(tokenEntity.TokenHash, tokenEntity.PreviousTokenHash)
+}
+
+// invalidateAuthCache Token 变更后删除认证信息缓存，使用 Redis 时所有副本同时失效
+func (s *TokenService) invalidateAuthCache(tokenHashes ...string) {
+	if !s.AppConfig.MySQL.CacheFlag {
+		return
+	}
+	// 没有轮换过的 Token 轮换前的摘要为空
+	keys := lo.Map(lo.Compact(tokenHashes), func(tokenHash string, _ int) string {
+		return authCacheKeyPrefix + tokenHash
+	})
+	if err := s.Store.Delete(context.Background(), keys...); err != nil {
+		hlog.Warnf("delete token auth info cache failed: %v", err)
+	}
+}
+
+// Rotate 生成新的 Token 密钥，轮换前的密钥在 gracePeriod 内仍然有效，Token ID、策略和限制不变
+// 宽限期内再次轮换时，更早的密钥立即失效
+func (s *TokenService) Rotate(tokenEntity *dao.TokenEntity, gracePeriod time.Duration, userInfo *models.UserInfo) (*models.CreateTokenResp, error) {
+	token, err := s.GenerateToken()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to generate token: %v", err)
+	}
+	expiredHashes := []string{tokenEntity.TokenHash, tokenEntity.PreviousTokenHash}
+	previousTokenExpiredTime := time.Now().Add(gracePeriod)
+	digest := s.NewTokenDigest(token)
+	rotated := *tokenEntity
+	rotated.PreviousTokenHash = tokenEntity.TokenHash
+	rotated.PreviousTokenExpiredTime = &previousTokenExpiredTime
+	rotated.TokenDigest = *digest
+	rotated.UpdateBy = userInfo.Username
+	rotated.UpdateTime = time.Now()
+	if err := s.TokenDao.Rotate(tokenEntity.ID, &rotated); err != nil {
+		return nil, xerrors.Errorf("failed to rotate token: %v", err)
+	}
+	*tokenEntity = rotated
+	s.invalidateAuthCache(append(expiredHashes, digest.TokenHash)...)
+	return &models.CreateTokenResp{TokenEntity: tokenEntity, Token: token}, nil
+}
+
+// Suspend 停用 Token，resumeTime 不为空时到达该时间后自动恢复
+func (s *TokenService) Suspend(
+	tokenEntity *dao.TokenEntity, reason string, resumeTime *time.Time, userInfo *models.UserInfo,
+) (*dao.TokenEntity, error) {
+	now := time.Now()
+	tokenEntity.Status = constants.TokenStatusSuspended
+	tokenEntity.SuspendReason = reason
+	tokenEntity.SuspendTime = &now
+	tokenEntity.ResumeTime = resumeTime
+	return s.updateStatus(tokenEntity, userInfo)
+}
+
+// Resume 恢复停用的 Token
+func (s *TokenService) Resume(tokenEntity *dao.TokenEntity, userInfo *models.UserInfo) (*dao.TokenEntity, error) {
+	tokenEntity.Status = constants.TokenStatusActive
+	tokenEntity.SuspendReason = ""
+	tokenEntity.SuspendTime = nil
+	tokenEntity.ResumeTime = nil
+	return s.updateStatus(tokenEntity, userInfo)
+}
+
+func (s *TokenService) updateStatus(tokenEntity *dao.TokenEntity, userInfo *models.UserInfo) (*dao.TokenEntity, error) {
+	tokenEntity.UpdateBy = userInfo.Username
+	tokenEntity.UpdateTime = time.Now()
+	if err := s.TokenDao.UpdateStatus(tokenEntity.ID, tokenEntity); err != nil {
+		return nil, xerrors.Errorf("failed to update token status: %v", err)
+	}
+	s.invalidateAuthCache(tokenEntity.TokenHash, tokenEntity.PreviousTokenHash)
+	return tokenEntity, nil
+}
+
+// AcquireLease 占用一个并发名额，maxConcurrency 为 0 时不限制并发，不生成租约
+// 并发数已达到上限时返回 ErrConcurrencyLimitExceeded
+func (s *TokenService) AcquireLease(ctx context.Context, tokenID string, maxConcurrency int) (*models.TokenAuthResp, error) {
+	resp := &models.TokenAuthResp{TokenID: tokenID}
+	if maxConcurrency <= 0 {
+		return resp, nil
+	}
+	leaseID := dao.NewUUID()
+	leaseTTL := s.AppConfig.Auth.LeaseTTL
+	expireTime := time.Now().Add(leaseTTL)
+	acquired, err := s.Store.AcquireLease(ctx, leaseKeyPrefix+tokenID, leaseID, maxConcurrency, leaseTTL)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to acquire lease: %v", err)
+	}
+	if !acquired {
+		return nil, ErrConcurrencyLimitExceeded
+	}
+	resp.LeaseID = leaseID
+	resp.LeaseExpireTime = &expireTime
+	return resp, nil
+}
+
+// ReleaseLease 释放并发名额，租约不存在或已过期时返回 false
+func (s *TokenService) ReleaseLease(ctx context.Context, tokenID, leaseID string) (bool, error) {
+	released, err := s.Store.ReleaseLease(ctx, leaseKeyPrefix+tokenID, leaseID)
+	if err != nil {
+		return false, xerrors.Errorf("failed to release lease: %v", err)
+	}
+	return released, nil
+}
+
+// HashPlaintextTokens 将历史明文 Token 转换为摘要，返回转换的数量
+func (s *TokenService) HashPlaintextTokens() (int, error) {
+	tokens, err := s.TokenDao.ListPlaintextTokens()
+	if err != nil {
+		return 0, xerrors.Errorf("failed to list plaintext tokens: %v", err)
+	}
+	for i, token := range tokens {
+		if err := s.TokenDao.UpdateDigest(token.ID, s.NewTokenDigest(token.Token)); err != nil {
+			return i, xerrors.Errorf("failed to update token digest, id: %s, err: %v", token.ID, err)
+		}
+	}
+	return len(tokens), nil
+}
+
+// formatTime 格式化时间，时间为空时返回 nil
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.Format(constants.TimeFormat)
+	return &formatted
 }
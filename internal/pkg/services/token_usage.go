@@ -0,0 +1,191 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/samber/lo"
+	"golang.org/x/xerrors"
+
+	"github.com/auth-engine/config"
+	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/pkg/constants"
+)
+
+const usageFlushBatchSize = 1000 // 内存中累计的统计记录达到该数量时提前写入数据库
+
+type ITokenUsageService interface {
+	Record(tokenID, workspaceID, outcome string)
+	Flush() error
+	QueryTokenUsage(workspaceID, tokenID string, startDate, endDate time.Time) (*models.TokenUsageSummary, error)
+	QueryWorkspaceUsage(workspaceID string, startDate, endDate time.Time) (*models.TokenUsageSummary, error)
+	QueryUnknownUsage(startDate, endDate time.Time) (*models.TokenUsageSummary, error)
+}
+
+type usageKey struct {
+	tokenID   string
+	usageDate string
+}
+
+type TokenUsageService struct {
+	UsageDao  dao.ITokenUsageDao
+	AppConfig *config.AppConfig
+
+	mu      sync.Mutex
+	pending map[usageKey]*dao.TokenUsage // 尚未写入数据库的统计
+	flushCh chan struct{}
+}
+
+// NewTokenUsageService 创建认证次数统计服务，UsageFlushInterval 大于 0 时定时写入数据库，
+// 服务配置中 UsageFlushInterval 必须大于 0，测试时为 0 由调用方手动写入
+func NewTokenUsageService(usageDao dao.ITokenUsageDao, appConfig *config.AppConfig) ITokenUsageService {
+	s := &TokenUsageService{
+		UsageDao:  usageDao,
+		AppConfig: appConfig,
+		pending:   make(map[usageKey]*dao.TokenUsage),
+		flushCh:   make(chan struct{}, 1),
+	}
+	if interval := appConfig.Auth.UsageFlushInterval; interval > 0 {
+		go s.flushLoop(interval)
+	}
+	return s
+}
+
+func (s *TokenUsageService) flushLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.flushCh:
+		}
+		if err := s.Flush(); err != nil {
+			hlog.Errorf("flush token usage failed: %v", err)
+		}
+	}
+}
+
+// Record 在内存中累加一次认证结果，outcome 为 constants.UsageAllowed 等
+func (s *TokenUsageService) Record(tokenID, workspaceID, outcome string) {
+	now := time.Now()
+	key := usageKey{tokenID: tokenID, usageDate: now.Format(constants.DateRangeTimeFormat)}
+	s.mu.Lock()
+	usage, ok := s.pending[key]
+	if !ok {
+		year, month, day := now.Date()
+		usage = &dao.TokenUsage{
+			TokenID:     tokenID,
+			WorkspaceID: workspaceID,
+			UsageDate:   time.Date(year, month, day, 0, 0, 0, 0, time.Local),
+		}
+		s.pending[key] = usage
+	}
+	switch outcome {
+	case constants.UsageAllowed:
+		usage.AllowedCount++
+	case constants.UsageExpired:
+		usage.ExpiredCount++
+	case constants.UsagePolicyDenied:
+		usage.PolicyDeniedCount++
+	case constants.UsageLimited:
+		usage.LimitedCount++
+	case constants.UsageSuspended:
+		usage.SuspendedCount++
+	case constants.UsageUnknown:
+		usage.UnknownCount++
+	}
+	full := len(s.pending) >= usageFlushBatchSize
+	s.mu.Unlock()
+	if full {
+		select {
+		case s.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Flush 将内存中的统计写入数据库，写入失败时保留统计等待下次写入
+func (s *TokenUsageService) Flush() error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[usageKey]*dao.TokenUsage)
+	s.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+	if err := s.UsageDao.BatchIncrease(lo.Values(pending)); err != nil {
+		s.mu.Lock()
+		for key, usage := range pending {
+			if current, ok := s.pending[key]; ok {
+				usage.AllowedCount += current.AllowedCount
+				usage.ExpiredCount += current.ExpiredCount
+				usage.PolicyDeniedCount += current.PolicyDeniedCount
+				usage.LimitedCount += current.LimitedCount
+				usage.SuspendedCount += current.SuspendedCount
+				usage.UnknownCount += current.UnknownCount
+			}
+			s.pending[key] = usage
+		}
+		s.mu.Unlock()
+		return xerrors.Errorf("failed to save token usage: %v", err)
+	}
+	return nil
+}
+
+func (s *TokenUsageService) QueryTokenUsage(workspaceID, tokenID string, startDate, endDate time.Time) (*models.TokenUsageSummary, error) {
+	usages, err := s.UsageDao.ListByTokenID(workspaceID, tokenID, startDate, endDate)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to query token usage: %v", err)
+	}
+	return buildUsageSummary(usages, startDate, endDate), nil
+}
+
+func (s *TokenUsageService) QueryWorkspaceUsage(workspaceID string, startDate, endDate time.Time) (*models.TokenUsageSummary, error) {
+	usages, err := s.UsageDao.SumByWorkspaceID(workspaceID, startDate, endDate)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to query workspace usage: %v", err)
+	}
+	return buildUsageSummary(usages, startDate, endDate), nil
+}
+
+// QueryUnknownUsage 不存在的 Token 的认证次数，这类请求无法归属到工作空间，统一记录在 token_id 为空的行中
+func (s *TokenUsageService) QueryUnknownUsage(startDate, endDate time.Time) (*models.TokenUsageSummary, error) {
+	usages, err := s.UsageDao.ListByTokenID("", "", startDate, endDate)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to query unknown token usage: %v", err)
+	}
+	return buildUsageSummary(usages, startDate, endDate), nil
+}
+
+// buildUsageSummary 组装每天的统计和区间合计
+func buildUsageSummary(usages []*dao.TokenUsage, startDate, endDate time.Time) *models.TokenUsageSummary {
+	summary := &models.TokenUsageSummary{
+		StartDate: startDate.Format(constants.DateRangeTimeFormat),
+		EndDate:   endDate.Format(constants.DateRangeTimeFormat),
+		Total:     &models.TokenUsageResp{},
+		Daily:     make([]*models.TokenUsageResp, 0, len(usages)),
+	}
+	for _, usage := range usages {
+		daily := &models.TokenUsageResp{
+			UsageDate:         usage.UsageDate.Format(constants.DateRangeTimeFormat),
+			AllowedCount:      usage.AllowedCount,
+			ExpiredCount:      usage.ExpiredCount,
+			PolicyDeniedCount: usage.PolicyDeniedCount,
+			LimitedCount:      usage.LimitedCount,
+			SuspendedCount:    usage.SuspendedCount,
+			UnknownCount:      usage.UnknownCount,
+		}
+		daily.TotalCount = daily.AllowedCount + daily.ExpiredCount + daily.PolicyDeniedCount + daily.LimitedCount + daily.SuspendedCount + daily.UnknownCount
+		summary.Daily = append(summary.Daily, daily)
+		summary.Total.AllowedCount += daily.AllowedCount
+		summary.Total.ExpiredCount += daily.ExpiredCount
+		summary.Total.PolicyDeniedCount += daily.PolicyDeniedCount
+		summary.Total.LimitedCount += daily.LimitedCount
+		summary.Total.SuspendedCount += daily.SuspendedCount
+		summary.Total.UnknownCount += daily.UnknownCount
+		summary.Total.TotalCount += daily.TotalCount
+	}
+	return summary
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/auth-engine/internal/pkg/utils/cache"
+)
+
+// MemoryStore 进程内存储，只在当前副本生效
+type MemoryStore struct {
+	values *cache.Cache[[]byte]
+
+	mu       sync.Mutex
+	counters map[string]*counter
+	leases   map[string]map[string]time.Time // key -> leaseID -> 过期时间
+
+	stopCleanup func()
+}
+
+type counter struct {
+	value    int64
+	expireAt time.Time
+}
+
+// NewMemoryStore 创建进程内存储，maxEntries 为 key-value 的最大条目数，cleanupInterval 为过期数据的清理间隔
+func NewMemoryStore(maxEntries int, cleanupInterval time.Duration) *MemoryStore {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	if cleanupInterval <= 0 {
+		cleanupInterval = time.Minute
+	}
+	s := &MemoryStore{
+		values:   cache.New[[]byte](maxEntries),
+		counters: make(map[string]*counter),
+		leases:   make(map[string]map[string]time.Time),
+	}
+	stopValues := s.values.StartCleanup(cleanupInterval)
+	ticker := time.NewTicker(cleanupInterval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				s.deleteExpired()
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	s.stopCleanup = func() {
+		once.Do(func() {
+			stopValues()
+			close(done)
+		})
+	}
+	return s
+}
+
+// Close 停止过期数据的清理，可以重复调用
+func (s *MemoryStore) Close() {
+	s.stopCleanup()
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	value, ok := s.values.Get(key)
+	return value, ok, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.values.Set(key, value, ttl)
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, keys ...string) error {
+	s.values.Delete(keys...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		delete(s.counters, key)
+		delete(s.leases, key)
+	}
+	return nil
+}
+
+func (s *MemoryStore) IncrBy(_ context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	c, ok := s.counters[key]
+	if !ok || now.After(c.expireAt) {
+		c = &counter{expireAt: now.Add(ttl)}
+		s.counters[key] = c
+	}
+	c.value += delta
+	return c.value, nil
+}
+
+func (s *MemoryStore) AcquireLease(_ context.Context, key, leaseID string, limit int, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	leases, ok := s.leases[key]
+	if !ok {
+		leases = make(map[string]time.Time)
+		s.leases[key] = leases
+	}
+	deleteExpiredLeases(leases, now)
+	if len(leases) >= limit {
+		return false, nil
+	}
+	leases[leaseID] = now.Add(ttl)
+	return true, nil
+}
+
+func (s *MemoryStore) ReleaseLease(_ context.Context, key, leaseID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	leases := s.leases[key]
+	expireAt, ok := leases[leaseID]
+	if !ok {
+		return false, nil
+	}
+	delete(leases, leaseID)
+	if len(leases) == 0 {
+		delete(s.leases, key)
+	}
+	return time.Now().Before(expireAt), nil
+}
+
+func (s *MemoryStore) deleteExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for key, c := range s.counters {
+		if now.After(c.expireAt) {
+			delete(s.counters, key)
+		}
+	}
+	for key, leases := range s.leases {
+		deleteExpiredLeases(leases, now)
+		if len(leases) == 0 {
+			delete(s.leases, key)
+		}
+	}
+}
+
+func deleteExpiredLeases(leases map[string]time.Time, now time.Time) {
+	for leaseID, expireAt := range leases {
+		if !now.Before(expireAt) {
+			delete(leases, leaseID)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// incrByScript 计数器加上 delta，计数器没有过期时间时（新创建）设置过期时间
+var incrByScript = redis.NewScript(`
+local v = redis.call('INCRBY', KEYS[1], ARGV[1])
+if redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return v
+`)
+
+// acquireLeaseScript 租约保存在有序集合中，score 为过期时间（毫秒）
+// 先删除已过期的租约，未过期的租约数量小于 limit 时添加新租约
+var acquireLeaseScript = redis.NewScript(`
+local now = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[4])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[3]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], now + ttl, ARGV[1])
+if redis.call('PTTL', KEYS[1]) < ttl then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return 1
+`)
+
+// releaseLeaseScript 删除租约，租约不存在或已过期时返回 0
+var releaseLeaseScript = redis.NewScript(`
+local expireAt = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not expireAt then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+if tonumber(expireAt) <= tonumber(ARGV[2]) then
+	return 0
+end
+return 1
+`)
+
+// RedisStore 基于 Redis 的存储，多个副本共享
+type RedisStore struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+// NewRedisStore 创建 Redis 存储，所有 key 都会加上 keyPrefix
+func NewRedisStore(client redis.UniversalClient, keyPrefix string) *RedisStore {
+	return &RedisStore{client: client, keyPrefix: keyPrefix}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := s.client.Get(ctx, s.keyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, s.keyPrefix+key, value, ttl).Err()
+}
+
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, s.keyPrefix+key)
+	}
+	return s.client.Del(ctx, prefixed...).Err()
+}
+
+func (s *RedisStore) IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return incrByScript.Run(ctx, s.client, []string{s.keyPrefix + key}, delta, ttl.Milliseconds()).Int64()
+}
+
+func (s *RedisStore) AcquireLease(ctx context.Context, key, leaseID string, limit int, ttl time.Duration) (bool, error) {
+	keys := []string{s.keyPrefix + key}
+	acquired, err := acquireLeaseScript.Run(ctx, s.client, keys, leaseID, time.Now().UnixMilli(), limit, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return acquired == 1, nil
+}
+
+func (s *RedisStore) ReleaseLease(ctx context.Context, key, leaseID string) (bool, error) {
+	keys := []string{s.keyPrefix + key}
+	released, err := releaseLeaseScript.Run(ctx, s.client, keys, leaseID, time.Now().UnixMilli()).Int()
+	if err != nil {
+		return false, err
+	}
+	return released == 1, nil
+}
@@ -0,0 +1,25 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// IStore 多副本共享的状态存储，用于认证缓存和计数器
+// 配置了 Redis 时使用 RedisStore，否则使用只在当前副本生效的 MemoryStore
+type IStore interface {
+	// Get 获取 key 的值，key 不存在时返回 false
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set 写入 key，ttl 为存活时间
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete 删除 key
+	Delete(ctx context.Context, keys ...string) error
+	// IncrBy 原子地给计数器加上 delta 并返回新值，计数器不存在时创建，并设置存活时间为 ttl
+	// delta 为 0 时可用于读取计数器
+	IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+	// AcquireLease 在 key 对应的租约集合中添加租约 leaseID，未过期的租约数量达到 limit 时返回 false
+	// 租约在 ttl 后自动过期，避免调用方异常退出后一直占用
+	AcquireLease(ctx context.Context, key, leaseID string, limit int, ttl time.Duration) (bool, error)
+	// ReleaseLease 释放租约，租约不存在或已过期时返回 false
+	ReleaseLease(ctx context.Context, key, leaseID string) (bool, error)
+}
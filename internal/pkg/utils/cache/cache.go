@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache 并发安全的内存缓存，每个条目有独立的过期时间，超出容量时淘汰最久未使用的条目
+type Cache[V any] struct {
+	mu         sync.Mutex
+	maxEntries int
+	items      map[string]*list.Element
+	lru        *list.List
+}
+
+type entry[V any] struct {
+	key      string
+	value    V
+	expireAt time.Time
+}
+
+// New 创建缓存，maxEntries <= 0 表示不限制容量
+func New[V any](maxEntries int) *Cache[V] {
+	return &Cache[V]{
+		maxEntries: maxEntries,
+		items:      make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Get 获取缓存，条目不存在或已过期时返回 false
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var zero V
+	elem, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	e := elem.Value.(*entry[V])
+	if time.Now().After(e.expireAt) {
+		c.removeElement(elem)
+		return zero, false
+	}
+	c.lru.MoveToFront(elem)
+	return e.value, true
+}
+
+// Set 写入缓存，ttl 为条目的存活时间
+func (c *Cache[V]) Set(key string, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expireAt := time.Now().Add(ttl)
+	if elem, ok := c.items[key]; ok {
+		e := elem.Value.(*entry[V])
+		e.value = value
+		e.expireAt = expireAt
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.lru.PushFront(&entry[V]{key: key, value: value, expireAt: expireAt})
+	if c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.removeElement(c.lru.Back())
+	}
+}
+
+// Delete 删除缓存
+func (c *Cache[V]) Delete(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if elem, ok := c.items[key]; ok {
+			c.removeElement(elem)
+		}
+	}
+}
+
+// DeleteExpired 清理所有已过期的条目
+func (c *Cache[V]) DeleteExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for _, elem := range c.items {
+		if now.After(elem.Value.(*entry[V]).expireAt) {
+			c.removeElement(elem)
+		}
+	}
+}
+
+// Len 返回缓存条目数量，包含尚未清理的过期条目
+func (c *Cache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// StartCleanup 按 interval 周期清理过期条目，返回停止函数
+func (c *Cache[V]) StartCleanup(interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				c.DeleteExpired()
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+func (c *Cache[V]) removeElement(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.items, elem.Value.(*entry[V]).key)
+}
@@ -1,16 +1,19 @@
 package utils
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
-	"fmt"
 	"io"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/auth-engine/internal/pkg/utils/cache"
 	"github.com/auth-engine/pkg/constants"
 	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/robfig/cron/v3"
 	"golang.org/x/xerrors"
 )
 
@@ -22,6 +25,21 @@ func GenerateRandomString(n int) (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
+// HashToken 使用服务端 pepper 计算 Token 的 HMAC-SHA256 摘要，返回十六进制字符串
+func HashToken(pepper, token string) string {
+	mac := hmac.New(sha256.New, []byte(pepper))
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// pepperFingerprintLabel 计算 pepper 指纹时使用的固定内容
+const pepperFingerprintLabel = "auth-engine/token-pepper-fingerprint"
+
+// PepperFingerprint 计算 pepper 的指纹，写入导出文件，导入时与当前 pepper 比较，不泄露 pepper 本身
+func PepperFingerprint(pepper string) string {
+	return HashToken(pepper, pepperFingerprintLabel)[:16]
+}
+
 // CheckTimeFormat checks if the given time string matches the "08:00:00" format.
 func CheckTimeFormat(timeStr string) (bool, time.Time) {
 	const format = constants.DaliyTimeFormat
@@ -42,6 +60,22 @@ func CheckDateFormat(dateStr string) (bool, time.Time) {
 	return err == nil, d
 }
 
+// locations 已加载的时区，key 为 IANA 时区名称
+var locations sync.Map
+
+// LoadLocation 根据 IANA 时区名称加载时区并缓存，避免每次认证都读取时区数据
+func LoadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, xerrors.Errorf("%w", err)
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
 // CheckDateTimeFormat checks if the given datetime string matches the "2006-01-02 15:04:05" format.
 func CheckDateTimeFormat(datetimeStr string) (bool, time.Time) {
 	const format = constants.TimeFormat
@@ -52,44 +86,32 @@ func CheckDateTimeFormat(datetimeStr string) (bool, time.Time) {
 	return err == nil, d
 }
 
-// encryptData 使用AES加密数据
-func EncryptData(data []byte) ([]byte, error) {
-	// Create a new AES block cipher
-	block, err := aes.NewCipher([]byte(constants.AesCryptionKey))
-	if err != nil {
-		return nil, err
-	}
-	// Create a new GCM cipher mode instance
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
-	}
-	// Generate a new nonce
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return nil, err
-	}
-	// Encrypt the data
-	encryptedData := gcm.Seal(nonce, nonce, data, nil)
-	return encryptedData, nil
-}
+// 已解析的 CRON 表达式缓存的容量和有效期，认证时按表达式读取
+const (
+	cronScheduleCacheSize = 1024
+	cronScheduleCacheTTL  = time.Hour
+)
 
-// decryptData 使用AES解密数据
-func DecryptData(encryptedData []byte) ([]byte, error) {
-	// Create a new AES block cipher
-	block, err := aes.NewCipher([]byte(constants.AesCryptionKey))
-	if err != nil {
-		return nil, err
+// cronSchedules 已解析的 CRON 表达式，key 为表达式
+var cronSchedules = cache.New[cron.Schedule](cronScheduleCacheSize)
+
+// ParseCron 解析标准 5 位 CRON 表达式并缓存，支持 @daily 等描述符，
+// 不支持 @every，其下次触发时间从调用时开始计算，不是固定的时间点；
+// 不支持 CRON_TZ 前缀，按调用 Next 时传入时间的时区计算
+func ParseCron(expr string) (cron.Schedule, error) {
+	if schedule, ok := cronSchedules.Get(expr); ok {
+		return schedule, nil
+	}
+	if strings.HasPrefix(expr, "TZ=") || strings.HasPrefix(expr, "CRON_TZ=") {
+		return nil, xerrors.New("timezone prefix is not supported, use timezone instead")
 	}
-	// Create a new GCM cipher mode instance
-	gcm, err := cipher.NewGCM(block)
+	schedule, err := cron.ParseStandard(expr)
 	if err != nil {
-		return nil, err
+		return nil, xerrors.Errorf("%w", err)
 	}
-	nonceSize := gcm.NonceSize()
-	if len(encryptedData) < nonceSize {
-		return nil, fmt.Errorf("ciphertext too short")
+	if _, ok := schedule.(*cron.SpecSchedule); !ok {
+		return nil, xerrors.New("only time-of-day based expressions are supported, @every is not supported")
 	}
-	nonce, ciphertext := encryptedData[:nonceSize], encryptedData[nonceSize:]
-	return gcm.Open(nil, nonce, ciphertext, nil)
+	cronSchedules.Set(expr, schedule, cronScheduleCacheTTL)
+	return schedule, nil
 }
@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"os"
+
+	"golang.org/x/xerrors"
+
+	"github.com/auth-engine/config"
+)
+
+// exportFileMagic 导出文件头，文件格式为 magic | 密钥 ID 长度(1 字节) | 密钥 ID | nonce | 密文，
+// 文件头作为 GCM 的附加数据参与认证，密钥 ID 被篡改时解密失败
+const exportFileMagic = "AEX1"
+
+var ErrUnknownExportKey = xerrors.New("unknown export key")
+
+// ExportCipher 使用 AES-GCM 加密和解密 Token 导出文件，支持同时配置多个密钥
+type ExportCipher struct {
+	keys         map[string]cipher.AEAD
+	primaryKeyID string
+	legacyKeyID  string
+}
+
+// NewExportCipher 根据配置创建 ExportCipher，至少需要配置一个密钥
+func NewExportCipher(cfg config.Export) (*ExportCipher, error) {
+	keys := append([]config.ExportKey(nil), cfg.Keys...)
+	if cfg.KeysFile != "" {
+		content, err := os.ReadFile(cfg.KeysFile)
+		if err != nil {
+			return nil, xerrors.Errorf("read export keys file: %w", err)
+		}
+		fileKeys, err := config.ParseExportKeys(string(content))
+		if err != nil {
+			return nil, xerrors.Errorf("parse export keys file: %w", err)
+		}
+		keys = append(keys, fileKeys...)
+	}
+	if len(keys) == 0 {
+		return nil, xerrors.New("EXPORT_KEYS or export.keysFile is required")
+	}
+	c := &ExportCipher{
+		keys:         make(map[string]cipher.AEAD, len(keys)),
+		primaryKeyID: cfg.PrimaryKeyID,
+		legacyKeyID:  cfg.LegacyKeyID,
+	}
+	for _, key := range keys {
+		if len(key.ID) > 255 {
+			return nil, xerrors.Errorf("export key id %s is too long", key.ID)
+		}
+		if _, ok := c.keys[key.ID]; ok {
+			return nil, xerrors.Errorf("duplicate export key id %s", key.ID)
+		}
+		block, err := aes.NewCipher([]byte(key.Key))
+		if err != nil {
+			return nil, xerrors.Errorf("export key %s: %w", key.ID, err)
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, xerrors.Errorf("export key %s: %w", key.ID, err)
+		}
+		c.keys[key.ID] = gcm
+	}
+	if c.primaryKeyID == "" {
+		c.primaryKeyID = keys[0].ID
+	}
+	if _, ok := c.keys[c.primaryKeyID]; !ok {
+		return nil, xerrors.Errorf("export primary key %s is not configured", c.primaryKeyID)
+	}
+	if _, ok := c.keys[c.legacyKeyID]; c.legacyKeyID != "" && !ok {
+		return nil, xerrors.Errorf("export legacy key %s is not configured", c.legacyKeyID)
+	}
+	return c, nil
+}
+
+// PrimaryKeyID 返回新导出文件使用的密钥 ID
+func (c *ExportCipher) PrimaryKeyID() string {
+	return c.primaryKeyID
+}
+
+// Encrypt 使用主密钥加密数据
+func (c *ExportCipher) Encrypt(data []byte) ([]byte, error) {
+	gcm := c.keys[c.primaryKeyID]
+	header := make([]byte, 0, len(exportFileMagic)+1+len(c.primaryKeyID))
+	header = append(header, exportFileMagic...)
+	header = append(header, byte(len(c.primaryKeyID)))
+	header = append(header, c.primaryKeyID...)
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, xerrors.Errorf("%w", err)
+	}
+	out := append(header, nonce...)
+	return gcm.Seal(out, nonce, data, header), nil
+}
+
+// Decrypt 解密数据，返回明文和加密使用的密钥 ID，不带密钥 ID 的旧版本文件使用 legacyKeyID 对应的密钥解密
+func (c *ExportCipher) Decrypt(encryptedData []byte) ([]byte, string, error) {
+	if IsPassphraseProtected(encryptedData) {
+		return nil, "", xerrors.Errorf("%w", ErrPassphraseRequired)
+	}
+	keyID, header, body, ok := parseExportHeader(encryptedData)
+	if !ok {
+		return c.decryptLegacy(encryptedData)
+	}
+	err := xerrors.Errorf("%w: %s", ErrUnknownExportKey, keyID)
+	if gcm, exists := c.keys[keyID]; exists {
+		var data []byte
+		if data, err = open(gcm, body, header); err == nil {
+			return data, keyID, nil
+		}
+	}
+	// 旧版本文件的随机 nonce 恰好以文件头开始时按旧版本文件处理
+	if legacyData, legacyKeyID, legacyErr := c.decryptLegacy(encryptedData); legacyErr == nil {
+		return legacyData, legacyKeyID, nil
+	}
+	return nil, keyID, err
+}
+
+// Reencrypt 使用主密钥重新加密数据，已使用主密钥加密时原样返回，changed 为 false
+func (c *ExportCipher) Reencrypt(encryptedData []byte) (result []byte, changed bool, err error) {
+	data, keyID, err := c.Decrypt(encryptedData)
+	if err != nil {
+		return nil, false, err
+	}
+	if keyID == c.primaryKeyID && bytes.HasPrefix(encryptedData, []byte(exportFileMagic)) {
+		return encryptedData, false, nil
+	}
+	result, err = c.Encrypt(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return result, true, nil
+}
+
+func (c *ExportCipher) decryptLegacy(encryptedData []byte) ([]byte, string, error) {
+	if c.legacyKeyID == "" {
+		return nil, "", xerrors.Errorf("%w: file has no key id and export.legacyKeyID is not configured", ErrUnknownExportKey)
+	}
+	data, err := open(c.keys[c.legacyKeyID], encryptedData, nil)
+	if err != nil {
+		return nil, c.legacyKeyID, err
+	}
+	return data, c.legacyKeyID, nil
+}
+
+// parseExportHeader 解析文件头，返回密钥 ID、文件头和剩余数据
+func parseExportHeader(encryptedData []byte) (keyID string, header, body []byte, ok bool) {
+	if !bytes.HasPrefix(encryptedData, []byte(exportFileMagic)) || len(encryptedData) <= len(exportFileMagic) {
+		return "", nil, nil, false
+	}
+	idLen := int(encryptedData[len(exportFileMagic)])
+	headerLen := len(exportFileMagic) + 1 + idLen
+	if idLen == 0 || len(encryptedData) < headerLen {
+		return "", nil, nil, false
+	}
+	return string(encryptedData[len(exportFileMagic)+1 : headerLen]), encryptedData[:headerLen], encryptedData[headerLen:], true
+}
+
+func open(gcm cipher.AEAD, encryptedData, additionalData []byte) ([]byte, error) {
+	nonceSize := gcm.NonceSize()
+	if len(encryptedData) < nonceSize {
+		return nil, xerrors.New("ciphertext too short")
+	}
+	nonce, ciphertext := encryptedData[:nonceSize], encryptedData[nonceSize:]
+	data, err := gcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, xerrors.Errorf("%w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/xerrors"
+)
+
+// passphraseFileMagic 口令加密的导出文件头，文件格式为
+// magic | time(4 字节) | memory(4 字节，KiB) | threads(1 字节) | salt(16 字节) | nonce | 密文，
+// 密钥由口令和 salt 通过 Argon2id 派生，文件头作为 GCM 的附加数据参与认证
+const passphraseFileMagic = "AEP1"
+
+const (
+	passphraseSaltSize = 16
+	passphraseKeySize  = 32
+	// Argon2id 参数，参考 OWASP 推荐值
+	passphraseTime    = 3
+	passphraseMemory  = 64 * 1024
+	passphraseThreads = 4
+	// 同时进行的密钥派生数量，每次派生占用 passphraseMemory 内存
+	passphraseMaxConcurrency = 2
+)
+
+// passphraseSem 限制同时进行的 Argon2id 密钥派生，避免并发的导入导出请求耗尽服务端内存
+var passphraseSem = make(chan struct{}, passphraseMaxConcurrency)
+
+var (
+	ErrPassphraseRequired  = xerrors.New("export file is protected by passphrase")
+	ErrIncorrectPassphrase = xerrors.New("incorrect passphrase")
+)
+
+// IsPassphraseProtected 判断导出文件是否使用口令加密
+func IsPassphraseProtected(encryptedData []byte) bool {
+	return bytes.HasPrefix(encryptedData, []byte(passphraseFileMagic))
+}
+
+// EncryptWithPassphrase 使用口令派生的密钥加密数据，每个文件使用随机 salt
+func EncryptWithPassphrase(data []byte, passphrase string) ([]byte, error) {
+	header := make([]byte, len(passphraseFileMagic)+9+passphraseSaltSize)
+	copy(header, passphraseFileMagic)
+	binary.BigEndian.PutUint32(header[len(passphraseFileMagic):], passphraseTime)
+	binary.BigEndian.PutUint32(header[len(passphraseFileMagic)+4:], passphraseMemory)
+	header[len(passphraseFileMagic)+8] = passphraseThreads
+	salt := header[len(passphraseFileMagic)+9:]
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, xerrors.Errorf("%w", err)
+	}
+	gcm, err := passphraseGCM(passphrase, salt, passphraseTime, passphraseMemory, passphraseThreads)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, xerrors.Errorf("%w", err)
+	}
+	out := append(header[:len(header):len(header)], nonce...)
+	return gcm.Seal(out, nonce, data, header), nil
+}
+
+// DecryptWithPassphrase 使用口令解密 EncryptWithPassphrase 加密的数据
+func DecryptWithPassphrase(encryptedData []byte, passphrase string) ([]byte, error) {
+	headerLen := len(passphraseFileMagic) + 9 + passphraseSaltSize
+	if !IsPassphraseProtected(encryptedData) || len(encryptedData) < headerLen {
+		return nil, xerrors.New("export file is not protected by passphrase")
+	}
+	header := encryptedData[:headerLen]
+	time := binary.BigEndian.Uint32(header[len(passphraseFileMagic):])
+	memory := binary.BigEndian.Uint32(header[len(passphraseFileMagic)+4:])
+	threads := header[len(passphraseFileMagic)+8]
+	// 文件头中的参数不能超过服务端写入的值，避免构造的文件耗尽服务端资源
+	if time == 0 || time > passphraseTime || memory == 0 || memory > passphraseMemory || threads == 0 || threads > passphraseThreads {
+		return nil, xerrors.Errorf("unsupported passphrase parameters: time=%d, memory=%d, threads=%d", time, memory, threads)
+	}
+	gcm, err := passphraseGCM(passphrase, header[len(passphraseFileMagic)+9:], time, memory, threads)
+	if err != nil {
+		return nil, err
+	}
+	data, err := open(gcm, encryptedData[headerLen:], header)
+	if err != nil {
+		// 口令错误和文件被篡改无法区分，统一按口令错误处理
+		return nil, xerrors.Errorf("%w", ErrIncorrectPassphrase)
+	}
+	return data, nil
+}
+
+func passphraseGCM(passphrase string, salt []byte, time, memory uint32, threads uint8) (cipher.AEAD, error) {
+	passphraseSem <- struct{}{}
+	key := argon2.IDKey([]byte(passphrase), salt, time, memory, threads, passphraseKeySize)
+	<-passphraseSem
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, xerrors.Errorf("%w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, xerrors.Errorf("%w", err)
+	}
+	return gcm, nil
+}
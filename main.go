@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	// 内置时区数据，镜像中没有 tzdata 时也能加载有效期策略的时区
+	_ "time/tzdata"
 
 	_ "github.com/auth-engine/docs"
 )
@@ -0,0 +1,2 @@
+-- Modify "tokens" table
+ALTER TABLE `tokens` DROP INDEX `idx_token`, MODIFY COLUMN `token` varchar(255) NULL COMMENT "token 明文，已废弃，执行 go run ./cmd/migrator hash-tokens 后清空", ADD COLUMN `token_hash` varchar(64) NULL COMMENT "token HMAC-SHA256 摘要", ADD COLUMN `token_prefix` varchar(16) NOT NULL DEFAULT "" COMMENT "token 前缀", ADD COLUMN `token_last4` varchar(4) NOT NULL DEFAULT "" COMMENT "token 后四位", ADD UNIQUE INDEX `idx_token_hash` (`token_hash`);
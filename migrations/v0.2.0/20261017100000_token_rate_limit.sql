@@ -0,0 +1,2 @@
+-- Modify "tokens" table
+ALTER TABLE `tokens` ADD COLUMN `requests_per_minute` int NOT NULL DEFAULT 0 COMMENT "每分钟请求数上限，0 表示不限制", ADD COLUMN `requests_per_day` int NOT NULL DEFAULT 0 COMMENT "每天请求数上限，0 表示不限制";
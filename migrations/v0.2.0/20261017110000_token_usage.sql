@@ -0,0 +1,16 @@
+-- Create "token_usages" table
+CREATE TABLE `token_usages` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT "ID",
+  `token_id` varchar(64) NOT NULL COMMENT "token ID",
+  `workspace_id` varchar(64) NOT NULL COMMENT "工作空间ID",
+  `usage_date` date NOT NULL COMMENT "统计日期",
+  `allowed_count` bigint NOT NULL DEFAULT 0 COMMENT "认证通过次数",
+  `expired_count` bigint NOT NULL DEFAULT 0 COMMENT "Token 过期拒绝次数",
+  `policy_denied_count` bigint NOT NULL DEFAULT 0 COMMENT "有效期策略拒绝次数",
+  `limited_count` bigint NOT NULL DEFAULT 0 COMMENT "请求频率或并发限制拒绝次数",
+  `unknown_count` bigint NOT NULL DEFAULT 0 COMMENT "不存在的 Token 认证次数",
+  `update_time` datetime NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT "更新时间",
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_token_date` (`token_id`, `usage_date`),
+  INDEX `idx_workspace_date` (`workspace_id`, `usage_date`)
+) CHARSET utf8mb4 COLLATE utf8mb4_general_ci;
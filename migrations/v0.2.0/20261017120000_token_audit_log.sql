@@ -0,0 +1,15 @@
+-- Create "token_audit_logs" table
+CREATE TABLE `token_audit_logs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT "ID",
+  `workspace_id` varchar(64) NOT NULL COMMENT "工作空间ID",
+  `token_id` varchar(64) NOT NULL COMMENT "token ID",
+  `action` varchar(32) NOT NULL COMMENT "操作类型",
+  `actor_id` varchar(64) NOT NULL COMMENT "操作人ID",
+  `actor` varchar(64) NOT NULL COMMENT "操作人",
+  `source_ip` varchar(64) NOT NULL COMMENT "来源IP",
+  `diff` json NULL COMMENT "变更内容",
+  `create_time` datetime NOT NULL COMMENT "操作时间",
+  PRIMARY KEY (`id`),
+  INDEX `idx_token_id` (`token_id`),
+  INDEX `idx_workspace_time` (`workspace_id`, `create_time`)
+) CHARSET utf8mb4 COLLATE utf8mb4_general_ci;
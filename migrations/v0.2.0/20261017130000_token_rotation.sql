@@ -0,0 +1,2 @@
+-- Modify "tokens" table
+ALTER TABLE `tokens` ADD COLUMN `previous_token_hash` varchar(64) NULL COMMENT "轮换前的 token 摘要", ADD COLUMN `previous_token_expired_time` datetime NULL COMMENT "轮换前的 token 失效时间", ADD INDEX `idx_previous_token_hash` (`previous_token_hash`);
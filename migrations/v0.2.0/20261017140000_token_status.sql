@@ -0,0 +1,4 @@
+-- Modify "tokens" table
+ALTER TABLE `tokens` ADD COLUMN `status` enum('ACTIVE','SUSPENDED') NOT NULL DEFAULT "ACTIVE" COMMENT "Token 状态", ADD COLUMN `suspend_reason` varchar(255) NOT NULL DEFAULT "" COMMENT "停用原因", ADD COLUMN `suspend_time` datetime NULL COMMENT "停用时间", ADD COLUMN `resume_time` datetime NULL COMMENT "自动恢复时间", ADD INDEX `idx_status` (`status`);
+-- Modify "token_usages" table
+ALTER TABLE `token_usages` ADD COLUMN `suspended_count` bigint NOT NULL DEFAULT 0 COMMENT "Token 停用拒绝次数" AFTER `limited_count`;
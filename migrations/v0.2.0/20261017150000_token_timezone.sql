@@ -0,0 +1,4 @@
+-- Modify "tokens" table
+ALTER TABLE `tokens` ADD COLUMN `timezone` varchar(64) NOT NULL DEFAULT "" COMMENT "有效期策略时区" AFTER `policy_type`;
+-- Modify "token_validity_policies" table
+ALTER TABLE `token_validity_policies` ADD COLUMN `timezone` varchar(64) NOT NULL DEFAULT "" COMMENT "策略时区" AFTER `end_date`;
@@ -0,0 +1,4 @@
+-- Modify "tokens" table
+ALTER TABLE `tokens` MODIFY COLUMN `policy_type` enum('DAILY','WEEKLY','DATERANGE','CRON','') NULL COMMENT "策略类型";
+-- Modify "token_validity_policies" table
+ALTER TABLE `token_validity_policies` MODIFY COLUMN `policy_type` enum('DAILY','WEEKLY','DATERANGE','CRON') NOT NULL COMMENT "策略类型", ADD COLUMN `cron_expr` varchar(255) NOT NULL DEFAULT "" COMMENT "CRON 表达式" AFTER `timezone`, ADD COLUMN `cron_duration` int NOT NULL DEFAULT 0 COMMENT "CRON 策略每次触发后的有效时长，单位秒" AFTER `cron_expr`;
@@ -0,0 +1,31 @@
+-- Create "token_calendars" table
+CREATE TABLE `token_calendars` (
+  `id` varchar(64) NOT NULL COMMENT "ID",
+  `workspace_id` varchar(64) NOT NULL COMMENT "工作空间ID",
+  `name` varchar(64) NOT NULL COMMENT "日历名称",
+  `description` varchar(255) NOT NULL DEFAULT "" COMMENT "描述",
+  `timezone` varchar(64) NOT NULL DEFAULT "" COMMENT "日历时区",
+  `create_by` varchar(36) NULL COMMENT "创建人",
+  `update_by` varchar(36) NULL COMMENT "更新人",
+  `create_time` datetime NULL DEFAULT CURRENT_TIMESTAMP COMMENT "创建时间",
+  `update_time` datetime NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT "更新时间",
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_workspace_name` (`workspace_id`, `name`)
+) CHARSET utf8mb4 COLLATE utf8mb4_general_ci;
+-- Create "token_calendar_dates" table
+CREATE TABLE `token_calendar_dates` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT "ID",
+  `calendar_id` varchar(64) NOT NULL COMMENT "日历ID",
+  `start_date` date NOT NULL COMMENT "开始日期",
+  `end_date` date NOT NULL COMMENT "结束日期",
+  `note` varchar(255) NOT NULL DEFAULT "" COMMENT "备注",
+  PRIMARY KEY (`id`),
+  INDEX `idx_calendar_id` (`calendar_id`)
+) CHARSET utf8mb4 COLLATE utf8mb4_general_ci;
+-- Create "token_calendar_bindings" table
+CREATE TABLE `token_calendar_bindings` (
+  `token_id` varchar(64) NOT NULL COMMENT "token ID",
+  `calendar_id` varchar(64) NOT NULL COMMENT "日历ID",
+  PRIMARY KEY (`token_id`, `calendar_id`),
+  INDEX `idx_calendar_id` (`calendar_id`)
+) CHARSET utf8mb4 COLLATE utf8mb4_general_ci;
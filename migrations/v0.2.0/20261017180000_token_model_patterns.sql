@@ -0,0 +1,2 @@
+-- Modify "tokens" table
+ALTER TABLE `tokens` ADD COLUMN `model_patterns` varchar(1024) NOT NULL DEFAULT "" COMMENT "允许认证的模型" AFTER `model_name`;
@@ -0,0 +1,4 @@
+-- Modify "token_validity_policies" table
+ALTER TABLE `token_validity_policies` ADD COLUMN `continuous` bool NOT NULL DEFAULT 0 COMMENT "WEEKLY 策略是否连续有效" AFTER `end_day`;
+-- 跨过零点的 WEEKLY 策略原来按连续时间段处理，保持已有策略的行为不变
+UPDATE `token_validity_policies` SET `continuous` = 1 WHERE `policy_type` = 'WEEKLY' AND `start_time` > `end_time`;
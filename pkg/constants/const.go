@@ -1,23 +1,106 @@
 package constants
 
+import "time"
+
+const (
+	ProductName             = "auth-engine"     // ProductName 表示产品名称
+	DefaultPageSize         = 10                // DefaultPageSize 表示默认的每页数量
+	ImportTokenFileMaxSize  = 1024 * 1024 * 512 // 512MB
+	ExportPassphraseMinLen  = 12                // 导出口令最小长度
+	ImportRenameMaxAttempts = 100               // 按 rename 策略导入时追加的最大序号
+	DaliyPolicyType         = "DAILY"
+	WeeklyPolicyType        = "WEEKLY"
+	DateRangePolicyType     = "DATERANGE"
+	CronPolicyType          = "CRON"
+	CronPolicyMaxDuration   = 366 * 24 * time.Hour // CRON 策略每次触发后有效时长的上限
+	CalendarMaxDates        = 1000                 // 单个日历的日期范围数量上限
+	ModelPatternsMaxLength  = 1024                 // Token 模型规则的长度上限
+	RequestedModelHeader    = "X-Requested-Model"  // 转发认证和 Envoy 外部认证中请求的模型
+	DaliyTimeFormat         = "15:04:05"           // time.Now().Format("08:00:00")
+	DateRangeTimeFormat     = "2006-01-02"         // time.Now().Format("2006-01-02")
+	TimeFormat              = "2006-01-02 15:04:05"
+	MONDAY                  = "MONDAY" // time.Now().Weekday()
+	TUESDAY                 = "TUESDAY"
+	WEDNESDAY               = "WEDNESDAY"
+	THURSDAY                = "THURSDAY"
+	FRIDAY                  = "FRIDAY"
+	SATURDAY                = "SATURDAY"
+	SUNDAY                  = "SUNDAY"
+)
+
+// 解释 Token 认证结果
+const (
+	ExplainDefaultTransitions = 10                 // 默认返回的生效和失效时间数量
+	ExplainMaxTransitions     = 100                // 返回的生效和失效时间数量上限
+	ExplainScheduleWindow     = 7 * 24 * time.Hour // 计算生效和失效时间的范围
+)
+
+// Token 认证结果，用于认证次数统计
+const (
+	UsageAllowed      = "ALLOWED"       // 认证通过
+	UsageExpired      = "EXPIRED"       // Token 已过期
+	UsagePolicyDenied = "POLICY_DENIED" // 不在有效期策略内
+	UsageLimited      = "LIMITED"       // 超过请求频率或并发限制
+	UsageSuspended    = "SUSPENDED"     // Token 已停用
+	UsageUnknown      = "UNKNOWN"       // Token 不存在
+)
+
+// Token 管理操作类型，用于审计日志
+const (
+	AuditActionCreate  = "CREATE"
+	AuditActionUpdate  = "UPDATE"
+	AuditActionDelete  = "DELETE"
+	AuditActionExport  = "EXPORT"
+	AuditActionImport  = "IMPORT"
+	AuditActionRotate  = "ROTATE"
+	AuditActionSuspend = "SUSPEND"
+	AuditActionResume  = "RESUME"
+	// AuditActionSetCalendars 设置 Token 关联的日历
+	AuditActionSetCalendars = "SET_CALENDARS"
+)
+
+// Token 状态
+const (
+	TokenStatusActive    = "ACTIVE"    // 启用
+	TokenStatusSuspended = "SUSPENDED" // 停用，到达自动恢复时间后视为启用
+)
+
+// Ghippo 工作空间角色
+const (
+	WorkspaceRoleAdmin  = "workspace-admin"
+	WorkspaceRoleEditor = "workspace-editor"
+	WorkspaceRoleViewer = "workspace-viewer"
+)
+
+// Token 管理权限
+const (
+	PermissionTokenView   = "token:view"   // 查看 Token 列表、详情和统计
+	PermissionTokenEdit   = "token:edit"   // 创建、更新、导入、停用和恢复 Token
+	PermissionTokenManage = "token:manage" // 删除、导出和轮换 Token，轮换会返回新的密钥
+)
+
+// 批量导入时每个 Token 的处理结果
+const (
+	ImportResultCreated  = "CREATED"  // 导入成功，按 rename 策略重命名后创建的 Token 也属于此类
+	ImportResultUpdated  = "UPDATED"  // 按 overwrite 策略覆盖了已有 Token
+	ImportResultSkipped  = "SKIPPED"  // 已导入过、不属于当前环境或按 skip 策略跳过
+	ImportResultConflict = "CONFLICT" // 与已有 Token 冲突
+	ImportResultError    = "ERROR"    // 内容校验失败或创建失败
+)
+
+// 导入模式和冲突处理策略
+const (
+	ImportModeDryRun          = "dryRun"    // 只返回导入结果，不写入数据
+	ConflictStrategyFail      = "fail"      // 默认，名称冲突时报告冲突
+	ConflictStrategySkip      = "skip"      // 跳过冲突的 Token
+	ConflictStrategyOverwrite = "overwrite" // 覆盖同一工作空间下同名 Token 的策略、限制和密钥
+	ConflictStrategyRename    = "rename"    // 在应用场景名称后追加序号后创建
+)
+
+// Token 认证时匹配的密钥
 const (
-	ProductName            = "auth-engine" // ProductName 表示产品名称
-	DefaultPageSize        = 10            // DefaultPageSize 表示默认的每页数量
-	AesCryptionKey         = "53436832ecc4eeda3769c19400395208"
-	ImportTokenFileMaxSize = 1024 * 1024 * 512 // 512MB
-	DaliyPolicyType        = "DAILY"
-	WeeklyPolicyType       = "WEEKLY"
-	DateRangePolicyType    = "DATERANGE"
-	DaliyTimeFormat        = "15:04:05"   // time.Now().Format("08:00:00")
-	DateRangeTimeFormat    = "2006-01-02" // time.Now().Format("2006-01-02")
-	TimeFormat             = "2006-01-02 15:04:05"
-	MONDAY                 = "MONDAY" // time.Now().Weekday()
-	TUESDAY                = "TUESDAY"
-	WEDNESDAY              = "WEDNESDAY"
-	THURSDAY               = "THURSDAY"
-	FRIDAY                 = "FRIDAY"
-	SATURDAY               = "SATURDAY"
-	SUNDAY                 = "SUNDAY"
+	MatchedSecretCurrent  = "CURRENT"  // 当前密钥
+	MatchedSecretPrevious = "PREVIOUS" // 轮换前的密钥，宽限期内有效
 )
 
 var (
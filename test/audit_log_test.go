@@ -0,0 +1,133 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	hertzconfig "github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+
+	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/routers/ctrl/common"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/pkg/constants"
+)
+
+// fakeAuditLogDao 内存中的 AuditLogDao
+type fakeAuditLogDao struct {
+	logs []*dao.AuditLog
+}
+
+func (d *fakeAuditLogDao) Create(log *dao.AuditLog) error {
+	d.logs = append(d.logs, log)
+	return nil
+}
+
+func (d *fakeAuditLogDao) QueryPageList(string, dao.PageParam, dao.AuditLogQueryParam) (int64, []*dao.AuditLog, error) {
+	return int64(len(d.logs)), d.logs, nil
+}
+
+func TestAuditLogDiff(t *testing.T) {
+	auditDao := &fakeAuditLogDao{}
+	as := services.NewAuditLogService(auditDao)
+	userInfo := &models.UserInfo{ID: "u-1", Username: "admin"}
+
+	before := &dao.TokenEntity{ID: "token-1", WorkspaceID: "ws-1", ModelName: "model", MaxConcurrency: 1}
+	after := *before
+	after.ModelName = "model-2"
+	err := as.Record(userInfo, &models.AuditRecord{
+		WorkspaceID: "ws-1",
+		TokenID:     "token-1",
+		Action:      constants.AuditActionUpdate,
+		SourceIP:    "10.0.0.1",
+		Before:      before,
+		After:       &after,
+	})
+	if err != nil {
+		t.Fatalf("record audit log failed: %v", err)
+	}
+	log := auditDao.logs[0]
+	if log.Actor != "admin" || log.ActorID != "u-1" || log.SourceIP != "10.0.0.1" || log.CreateTime.IsZero() {
+		t.Fatalf("unexpected audit log: %+v", log)
+	}
+	var diff map[string]models.AuditDiffItem
+	if err := json.Unmarshal(log.Diff, &diff); err != nil {
+		t.Fatalf("unmarshal diff failed: %v", err)
+	}
+	if len(diff) != 1 || diff["modelName"].Before != "model" || diff["modelName"].After != "model-2" {
+		t.Fatalf("expected only modelName in diff, got %s", log.Diff)
+	}
+
+	// 导出操作没有变更内容
+	if err := as.Record(userInfo, &models.AuditRecord{TokenID: "token-1", Action: constants.AuditActionExport}); err != nil {
+		t.Fatal(err)
+	}
+	if auditDao.logs[1].Diff != nil {
+		t.Fatalf("expected empty diff for export, got %s", auditDao.logs[1].Diff)
+	}
+
+	// 删除操作记录删除前的内容
+	if err := as.Record(userInfo, &models.AuditRecord{TokenID: "token-1", Action: constants.AuditActionDelete, Before: before}); err != nil {
+		t.Fatal(err)
+	}
+	diff = nil
+	if err := json.Unmarshal(auditDao.logs[2].Diff, &diff); err != nil {
+		t.Fatal(err)
+	}
+	if diff["id"].Before != "token-1" || diff["id"].After != nil {
+		t.Fatalf("expected deleted token in diff, got %s", auditDao.logs[2].Diff)
+	}
+}
+
+// TestUpdateTokenAudit 更新 Token 的审计日志记录有效期策略的变更，不使用客户端传入的 X-Forwarded-For
+func TestUpdateTokenAudit(t *testing.T) {
+	handler := newTestTokenHandler(t, &models.CreateTokenReq{WorkspaceID: "1", AppScenarioName: "a", ModelName: "m", EnvName: "test"})
+	engine := route.NewEngine(hertzconfig.NewOptions(nil))
+	engine.PUT("/workspaces/:workspaceId/tokens/:tokenId", func(ctx context.Context, c *app.RequestContext) {
+		c.Set(common.AuthResultKey, models.AuthResult{PreferredUsername: "admin"})
+		c.Next(ctx)
+	}, common.Handle(handler.UpdateToken))
+
+	// update 更新 Token 并返回审计日志中的变更内容
+	update := func(req *models.UpdateTokenReq) map[string]models.AuditDiffItem {
+		body, _ := json.Marshal(req)
+		resp := ut.PerformRequest(engine, http.MethodPut, "/workspaces/1/tokens/"+handler.created.ID,
+			&ut.Body{Body: bytes.NewReader(body), Len: len(body)},
+			ut.Header{Key: "Content-Type", Value: "application/json"}, ut.Header{Key: "X-Forwarded-For", Value: "203.0.113.7"}).Result()
+		if resp.StatusCode() != http.StatusOK {
+			t.Fatalf("update token: %d, %s", resp.StatusCode(), resp.Body())
+		}
+		log := handler.auditDao.logs[len(handler.auditDao.logs)-1]
+		if log.SourceIP == "203.0.113.7" {
+			t.Fatalf("expected source IP from remote address, got forwarded %s", log.SourceIP)
+		}
+		var diff map[string]models.AuditDiffItem
+		if err := json.Unmarshal(log.Diff, &diff); err != nil {
+			t.Fatal(err)
+		}
+		return diff
+	}
+
+	withPolicy := &models.UpdateTokenReq{
+		AppScenarioName: "a", ModelName: "m", EnableValidityPolicy: true,
+		ValidityPolicy: []*models.ValidityPolicy{dailyPolicy("09:00:00", "18:00:00")},
+	}
+	if diff := update(withPolicy); diff["validityPolicy"].After == nil || diff["enableValidityPolicy"].After != true {
+		t.Fatalf("expected validity policy in diff, got %+v", diff)
+	}
+	// 策略未变化时重新创建的策略不计入变更
+	withPolicy.ModelName = "m2"
+	if diff := update(withPolicy); len(diff) == 0 || diff["validityPolicy"].Before != nil || diff["validityPolicy"].After != nil {
+		t.Fatalf("expected unchanged validity policy to be omitted from diff, got %+v", diff)
+	}
+	withPolicy.ValidityPolicy = []*models.ValidityPolicy{dailyPolicy("10:00:00", "18:00:00")}
+	if diff := update(withPolicy); diff["validityPolicy"].Before == nil || diff["validityPolicy"].After == nil {
+		t.Fatalf("expected changed validity policy in diff, got %+v", diff)
+	}
+}
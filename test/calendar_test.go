@@ -0,0 +1,333 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	hertzconfig "github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"golang.org/x/xerrors"
+	"gorm.io/gorm"
+
+	"github.com/auth-engine/config"
+	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/routers/ctrl"
+	"github.com/auth-engine/internal/pkg/routers/ctrl/common"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/internal/pkg/store"
+)
+
+// fakeCalendarDao 内存中的 CalendarDao
+type fakeCalendarDao struct {
+	calendars map[string]*dao.Calendar       // key 为日历ID
+	dates     map[string][]*dao.CalendarDate // key 为日历ID
+	bindings  map[string][]string            // key 为 token ID
+	tokenHits int                            // ListByTokenID 调用次数
+}
+
+func newFakeCalendarDao() *fakeCalendarDao {
+	return &fakeCalendarDao{
+		calendars: map[string]*dao.Calendar{},
+		dates:     map[string][]*dao.CalendarDate{},
+		bindings:  map[string][]string{},
+	}
+}
+
+func (d *fakeCalendarDao) Create(calendar *dao.Calendar, dates []*dao.CalendarDate) error {
+	d.calendars[calendar.ID] = calendar
+	d.setDates(calendar.ID, dates)
+	return nil
+}
+
+func (d *fakeCalendarDao) Get(workspaceID, id string) (*dao.Calendar, error) {
+	calendar, ok := d.calendars[id]
+	if !ok || calendar.WorkspaceID != workspaceID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return calendar, nil
+}
+
+func (d *fakeCalendarDao) CheckNameExists(workspaceID, name, id string) (bool, error) {
+	for _, calendar := range d.calendars {
+		if calendar.WorkspaceID == workspaceID && calendar.Name == name && calendar.ID != id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (d *fakeCalendarDao) QueryPageList(workspaceID string, _ dao.PageParam, _ dao.CalendarQueryParam) (int64, []*dao.Calendar, error) {
+	var calendars []*dao.Calendar
+	for _, calendar := range d.calendars {
+		if calendar.WorkspaceID == workspaceID {
+			calendars = append(calendars, calendar)
+		}
+	}
+	return int64(len(calendars)), calendars, nil
+}
+
+func (d *fakeCalendarDao) Update(calendar *dao.Calendar, dates []*dao.CalendarDate) error {
+	d.calendars[calendar.ID] = calendar
+	d.setDates(calendar.ID, dates)
+	return nil
+}
+
+func (d *fakeCalendarDao) Delete(id string) error {
+	delete(d.calendars, id)
+	delete(d.dates, id)
+	for tokenID, calendarIDs := range d.bindings {
+		d.bindings[tokenID] = removeString(calendarIDs, id)
+	}
+	return nil
+}
+
+func (d *fakeCalendarDao) ListDates(calendarIDs []string) ([]*dao.CalendarDate, error) {
+	var dates []*dao.CalendarDate
+	for _, id := range calendarIDs {
+		dates = append(dates, d.dates[id]...)
+	}
+	return dates, nil
+}
+
+func (d *fakeCalendarDao) ListByIDs(workspaceID string, ids []string) ([]*dao.Calendar, error) {
+	var calendars []*dao.Calendar
+	for _, id := range ids {
+		if calendar, ok := d.calendars[id]; ok && calendar.WorkspaceID == workspaceID {
+			calendars = append(calendars, calendar)
+		}
+	}
+	return calendars, nil
+}
+
+func (d *fakeCalendarDao) ListByTokenID(tokenID string) ([]*dao.Calendar, error) {
+	d.tokenHits++
+	var calendars []*dao.Calendar
+	for _, id := range d.bindings[tokenID] {
+		calendars = append(calendars, d.calendars[id])
+	}
+	return calendars, nil
+}
+
+func (d *fakeCalendarDao) ListTokenIDs(calendarID string) ([]string, error) {
+	var tokenIDs []string
+	for tokenID, calendarIDs := range d.bindings {
+		for _, id := range calendarIDs {
+			if id == calendarID {
+				tokenIDs = append(tokenIDs, tokenID)
+			}
+		}
+	}
+	sort.Strings(tokenIDs)
+	return tokenIDs, nil
+}
+
+func (d *fakeCalendarDao) ReplaceTokenCalendars(tokenID string, calendarIDs []string) error {
+	d.bindings[tokenID] = calendarIDs
+	return nil
+}
+
+func (d *fakeCalendarDao) setDates(calendarID string, dates []*dao.CalendarDate) {
+	for _, date := range dates {
+		date.CalendarID = calendarID
+	}
+	d.dates[calendarID] = dates
+}
+
+func removeString(values []string, value string) []string {
+	var result []string
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func TestCheckCalendar(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *models.CalendarReq
+		wantErr bool
+	}{
+		{name: "valid", req: &models.CalendarReq{Name: "holidays", Dates: []*models.CalendarDateRange{
+			{StartDate: "2026-10-01", EndDate: "2026-10-07", Note: "National Day"}, {StartDate: "2026-12-25"},
+		}}},
+		{name: "valid timezone", req: &models.CalendarReq{Name: "holidays", Timezone: "America/New_York"}},
+		{name: "empty name", req: &models.CalendarReq{}, wantErr: true},
+		{name: "name too long", req: &models.CalendarReq{Name: strings.Repeat("a", 65)}, wantErr: true},
+		{name: "invalid timezone", req: &models.CalendarReq{Name: "holidays", Timezone: "Mars/Olympus"}, wantErr: true},
+		{name: "invalid start date", req: &models.CalendarReq{Name: "holidays", Dates: []*models.CalendarDateRange{
+			{StartDate: "2026-13-01"},
+		}}, wantErr: true},
+		{name: "end before start", req: &models.CalendarReq{Name: "holidays", Dates: []*models.CalendarDateRange{
+			{StartDate: "2026-10-07", EndDate: "2026-10-01"},
+		}}, wantErr: true},
+		{name: "nil date", req: &models.CalendarReq{Name: "holidays", Dates: []*models.CalendarDateRange{nil}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ctrl.CheckCalendar(tt.req); (err != nil) != tt.wantErr {
+				t.Fatalf("CheckCalendar() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCalendarService(t *testing.T) {
+	cacheConfig := &config.AppConfig{MySQL: config.DBConfig{CacheFlag: true, CacheExpiration: time.Minute}}
+	cs := services.NewCalendarService(newFakeCalendarDao(), cacheConfig, store.NewMemoryStore(0, 0))
+	userInfo := &models.UserInfo{Username: "admin"}
+	holidays, err := cs.Create("1", &models.CalendarReq{Name: "holidays", Dates: []*models.CalendarDateRange{
+		{StartDate: "2026-10-01", EndDate: "2026-10-07"}, {StartDate: "2026-12-25"},
+	}}, userInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 结束日期为空时与开始日期相同
+	if len(holidays.Dates) != 2 || holidays.Dates[1].EndDate != "2026-12-25" {
+		t.Fatalf("unexpected calendar dates: %+v", holidays.Dates)
+	}
+	if _, err := cs.Create("1", &models.CalendarReq{Name: "holidays"}, userInfo); !xerrors.Is(err, services.ErrCalendarNameExists) {
+		t.Fatalf("expected ErrCalendarNameExists, got %v", err)
+	}
+	// 其他工作空间可以使用相同的名称，但不能关联到当前工作空间的 Token
+	other, err := cs.Create("2", &models.CalendarReq{Name: "holidays"}, userInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.SetTokenCalendars("1", "token-1", []string{holidays.ID, other.ID}); !xerrors.Is(err, services.ErrCalendarNotFound) {
+		t.Fatalf("expected ErrCalendarNotFound, got %v", err)
+	}
+	if _, err := cs.SetTokenCalendars("1", "token-1", []string{holidays.ID}); err != nil {
+		t.Fatal(err)
+	}
+	calendars, err := cs.GetTokenCalendars(context.Background(), "token-1")
+	if err != nil || len(calendars) != 1 || len(calendars[0].Dates) != 2 {
+		t.Fatalf("unexpected token calendars: %+v, %v", calendars, err)
+	}
+
+	// 更新日历后缓存失效，Token 立即按新的日期认证
+	if _, err := cs.Update("1", holidays.ID, &models.CalendarReq{Name: "holidays", Dates: []*models.CalendarDateRange{
+		{StartDate: "2027-01-01"},
+	}}, userInfo); err != nil {
+		t.Fatal(err)
+	}
+	calendars, err = cs.GetTokenCalendars(context.Background(), "token-1")
+	if err != nil || len(calendars) != 1 || len(calendars[0].Dates) != 1 || calendars[0].Dates[0].StartDate != "2027-01-01" {
+		t.Fatalf("expected updated calendar dates, got %+v, %v", calendars, err)
+	}
+
+	if err := cs.Delete("2", holidays.ID); !xerrors.Is(err, services.ErrCalendarNotFound) {
+		t.Fatalf("expected ErrCalendarNotFound, got %v", err)
+	}
+	if err := cs.Delete("1", holidays.ID); err != nil {
+		t.Fatal(err)
+	}
+	calendars, err = cs.GetTokenCalendars(context.Background(), "token-1")
+	if err != nil || len(calendars) != 0 {
+		t.Fatalf("expected no token calendars after delete, got %+v, %v", calendars, err)
+	}
+}
+
+func TestTokenAuthWithCalendar(t *testing.T) {
+	handler := newTestTokenHandler(t, &models.CreateTokenReq{WorkspaceID: "1", AppScenarioName: "a", ModelName: "m", EnvName: "test"})
+	created, cs := handler.created, handler.CalendarService
+	userInfo := &models.UserInfo{Username: "admin"}
+	holidays, err := cs.Create("1", &models.CalendarReq{Name: "national-day", Dates: []*models.CalendarDateRange{
+		{StartDate: "2026-10-01", EndDate: "2026-10-07"},
+	}}, userInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	freeze, err := cs.Create("1", &models.CalendarReq{Name: "us-freeze", Timezone: "America/New_York", Dates: []*models.CalendarDateRange{
+		{StartDate: "2026-10-09"},
+	}}, userInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.SetTokenCalendars("1", created.TokenEntity.ID, []string{holidays.ID, freeze.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	var now time.Time
+	handler.Now = func() time.Time { return now }
+	handler.Location = mustLoadLocation(t, "Asia/Shanghai")
+	engine := route.NewEngine(hertzconfig.NewOptions(nil))
+	engine.POST("/token/auth", common.Handle(handler.TokenAuth))
+	body, _ := json.Marshal(models.TokenAuthBody{Token: created.Token})
+
+	tests := []struct {
+		now      string
+		want     int
+		calendar string
+	}{
+		{now: "2026-10-01T00:00:00+08:00", want: http.StatusForbidden, calendar: "national-day"},
+		{now: "2026-10-07T23:59:59+08:00", want: http.StatusForbidden, calendar: "national-day"}, // 包含结束日期当天
+		{now: "2026-10-08T10:00:00+08:00", want: http.StatusOK},
+		{now: "2026-10-09T10:00:00+08:00", want: http.StatusOK},                               // 纽约仍是 10-08
+		{now: "2026-10-09T13:00:00+08:00", want: http.StatusForbidden, calendar: "us-freeze"}, // 纽约 10-09 01:00
+		{now: "2026-10-10T12:30:00+08:00", want: http.StatusOK},                               // 纽约 10-10 00:30
+	}
+	for _, tt := range tests {
+		now, _ = time.Parse(time.RFC3339, tt.now)
+		resp := ut.PerformRequest(engine, http.MethodPost, "/token/auth",
+			&ut.Body{Body: bytes.NewReader(body), Len: len(body)}, ut.Header{Key: "Content-Type", Value: "application/json"}).Result()
+		if resp.StatusCode() != tt.want {
+			t.Fatalf("%s: expected %d, got %d, %s", tt.now, tt.want, resp.StatusCode(), resp.Body())
+		}
+		if tt.calendar != "" && !strings.Contains(string(resp.Body()), tt.calendar) {
+			t.Fatalf("%s: expected denial to name calendar %s, got %s", tt.now, tt.calendar, resp.Body())
+		}
+	}
+}
+
+// 没有关联日历的 Token 认证时不查询日历，关联日历后认证信息缓存失效
+func TestTokenAuthSkipsCalendarLookup(t *testing.T) {
+	tokenHandler := newTestTokenHandler(t, &models.CreateTokenReq{WorkspaceID: "1", AppScenarioName: "a", ModelName: "m", EnvName: "test"})
+	created, calendarDao := tokenHandler.created, tokenHandler.calendarDao
+	holidays, err := tokenHandler.CalendarService.Create("1", &models.CalendarReq{Name: "national-day", Dates: []*models.CalendarDateRange{
+		{StartDate: "2026-10-01", EndDate: "2026-10-07"},
+	}}, &models.UserInfo{Username: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now, _ := time.Parse(time.RFC3339, "2026-10-01T12:00:00+08:00")
+	tokenHandler.Now = func() time.Time { return now }
+	calendarHandler := &ctrl.CalendarHandler{
+		CalendarService: tokenHandler.CalendarService, TokenService: tokenHandler.TokenService, AuditService: tokenHandler.AuditService,
+	}
+	engine := route.NewEngine(hertzconfig.NewOptions(nil))
+	engine.POST("/token/auth", common.Handle(tokenHandler.TokenAuth))
+	engine.PUT("/workspaces/:workspaceId/tokens/:tokenId/calendars", func(ctx context.Context, c *app.RequestContext) {
+		c.Set(common.AuthResultKey, models.AuthResult{PreferredUsername: "admin"})
+		c.Next(ctx)
+	}, common.Handle(calendarHandler.SetTokenCalendars))
+	auth := func() int {
+		body, _ := json.Marshal(models.TokenAuthBody{Token: created.Token})
+		return ut.PerformRequest(engine, http.MethodPost, "/token/auth",
+			&ut.Body{Body: bytes.NewReader(body), Len: len(body)}, ut.Header{Key: "Content-Type", Value: "application/json"}).Result().StatusCode()
+	}
+
+	if status := auth(); status != http.StatusOK || calendarDao.tokenHits != 0 {
+		t.Fatalf("expected auth without calendar lookup, got %d with %d lookups", status, calendarDao.tokenHits)
+	}
+	body, _ := json.Marshal(models.TokenCalendarsReq{CalendarIDs: []string{holidays.ID}})
+	resp := ut.PerformRequest(engine, http.MethodPut, "/workspaces/1/tokens/"+created.TokenEntity.ID+"/calendars",
+		&ut.Body{Body: bytes.NewReader(body), Len: len(body)}, ut.Header{Key: "Content-Type", Value: "application/json"}).Result()
+	if resp.StatusCode() != http.StatusOK {
+		t.Fatalf("set token calendars: %d, %s", resp.StatusCode(), resp.Body())
+	}
+	if status := auth(); status != http.StatusForbidden {
+		t.Fatalf("expected cached auth info to be invalidated after binding calendar, got %d", status)
+	}
+}
@@ -0,0 +1,170 @@
+package test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	"github.com/auth-engine/config"
+	"github.com/auth-engine/internal/pkg/utils"
+)
+
+const (
+	exportKey1 = "0123456789abcdef0123456789abcdef"
+	exportKey2 = "fedcba9876543210fedcba9876543210"
+)
+
+func TestExportCipherKeyRotation(t *testing.T) {
+	oldCipher, err := utils.NewExportCipher(config.Export{Keys: []config.ExportKey{{ID: "k1", Key: exportKey1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldFile, err := oldCipher.Encrypt([]byte("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 新增 k2 并设置为主密钥，k1 导出的文件仍然可以导入
+	keysFile := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(keysFile, []byte("# rotated\nk2:"+exportKey2+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	newCipher, err := utils.NewExportCipher(config.Export{
+		Keys:         []config.ExportKey{{ID: "k1", Key: exportKey1}},
+		KeysFile:     keysFile,
+		PrimaryKeyID: "k2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, keyID, err := newCipher.Decrypt(oldFile)
+	if err != nil || string(data) != "token" || keyID != "k1" {
+		t.Fatalf("decrypt old file: %s, %s, %v", data, keyID, err)
+	}
+
+	newFile, changed, err := newCipher.Reencrypt(oldFile)
+	if err != nil || !changed {
+		t.Fatalf("reencrypt: changed=%v, err=%v", changed, err)
+	}
+	if _, keyID, _ := newCipher.Decrypt(newFile); keyID != "k2" {
+		t.Fatalf("expected file re-encrypted with k2, got %s", keyID)
+	}
+	if _, changed, _ := newCipher.Reencrypt(newFile); changed {
+		t.Fatal("file encrypted with primary key should not be re-encrypted")
+	}
+
+	// 删除 k1 后无法导入 k1 导出的文件
+	k2Only, err := utils.NewExportCipher(config.Export{Keys: []config.ExportKey{{ID: "k2", Key: exportKey2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := k2Only.Decrypt(oldFile); !xerrors.Is(err, utils.ErrUnknownExportKey) {
+		t.Fatalf("expected ErrUnknownExportKey, got %v", err)
+	}
+}
+
+func TestExportCipherLegacyFile(t *testing.T) {
+	// 旧版本导出文件格式为 nonce | 密文，不带密钥 ID
+	block, _ := aes.NewCipher([]byte(exportKey1))
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	_, _ = rand.Read(nonce)
+	legacyFile := gcm.Seal(nonce, nonce, []byte("token"), nil)
+
+	withoutLegacy, err := utils.NewExportCipher(config.Export{Keys: []config.ExportKey{{ID: "k1", Key: exportKey1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := withoutLegacy.Decrypt(legacyFile); !xerrors.Is(err, utils.ErrUnknownExportKey) {
+		t.Fatalf("expected ErrUnknownExportKey, got %v", err)
+	}
+
+	exportCipher, err := utils.NewExportCipher(config.Export{
+		Keys:        []config.ExportKey{{ID: "legacy", Key: exportKey1}, {ID: "k2", Key: exportKey2}},
+		LegacyKeyID: "legacy",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exportCipher.PrimaryKeyID() != "legacy" {
+		t.Fatalf("expected first key as primary key, got %s", exportCipher.PrimaryKeyID())
+	}
+	data, keyID, err := exportCipher.Decrypt(legacyFile)
+	if err != nil || string(data) != "token" || keyID != "legacy" {
+		t.Fatalf("decrypt legacy file: %s, %s, %v", data, keyID, err)
+	}
+	// 旧版本文件即使密钥与主密钥相同也需要重新加密，以写入密钥 ID
+	if _, changed, err := exportCipher.Reencrypt(legacyFile); err != nil || !changed {
+		t.Fatalf("reencrypt legacy file: changed=%v, err=%v", changed, err)
+	}
+}
+
+func TestNewExportCipherInvalidConfig(t *testing.T) {
+	cases := []config.Export{
+		{},
+		{Keys: []config.ExportKey{{ID: "k1", Key: "short"}}},
+		{Keys: []config.ExportKey{{ID: "k1", Key: exportKey1}, {ID: "k1", Key: exportKey2}}},
+		{Keys: []config.ExportKey{{ID: "k1", Key: exportKey1}}, PrimaryKeyID: "k2"},
+		{Keys: []config.ExportKey{{ID: "k1", Key: exportKey1}}, LegacyKeyID: "k2"},
+	}
+	for i, c := range cases {
+		if _, err := utils.NewExportCipher(c); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+	if _, err := config.ParseExportKeys("k1:" + exportKey1 + "," + exportKey2); err == nil {
+		t.Error("expected error for key without id")
+	}
+}
+
+func TestExportPassphrase(t *testing.T) {
+	file1, err := utils.EncryptWithPassphrase([]byte("token"), "correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file2, err := utils.EncryptWithPassphrase([]byte("token"), "correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utils.IsPassphraseProtected(file1) || string(file1[:24]) == string(file2[:24]) {
+		t.Fatal("expected passphrase protected file with per-file salt")
+	}
+	data, err := utils.DecryptWithPassphrase(file1, "correct horse battery")
+	if err != nil || string(data) != "token" {
+		t.Fatalf("decrypt with passphrase: %s, %v", data, err)
+	}
+	if _, err := utils.DecryptWithPassphrase(file1, "wrong passphrase"); !xerrors.Is(err, utils.ErrIncorrectPassphrase) {
+		t.Fatalf("expected ErrIncorrectPassphrase, got %v", err)
+	}
+
+	// 口令加密的文件不能使用服务端密钥解密
+	exportCipher, _ := utils.NewExportCipher(config.Export{Keys: []config.ExportKey{{ID: "k1", Key: exportKey1}}})
+	if _, _, err := exportCipher.Decrypt(file1); !xerrors.Is(err, utils.ErrPassphraseRequired) {
+		t.Fatalf("expected ErrPassphraseRequired, got %v", err)
+	}
+	keyFile, _ := exportCipher.Encrypt([]byte("token"))
+	if utils.IsPassphraseProtected(keyFile) {
+		t.Fatal("file encrypted with server key should not be passphrase protected")
+	}
+
+	// 拒绝文件头中超过服务端写入值的 Argon2 参数
+	for name, tamper := range map[string]func(header []byte){
+		"memory":      func(header []byte) { header[8] = 0xff },
+		"memory +1":   func(header []byte) { binary.BigEndian.PutUint32(header[8:], 64*1024+1) },
+		"time":        func(header []byte) { binary.BigEndian.PutUint32(header[4:], 4) },
+		"threads":     func(header []byte) { header[12] = 5 },
+		"zero memory": func(header []byte) { binary.BigEndian.PutUint32(header[8:], 0) },
+	} {
+		tampered := append([]byte(nil), file1...)
+		tamper(tampered)
+		if _, err := utils.DecryptWithPassphrase(tampered, "correct horse battery"); err == nil || xerrors.Is(err, utils.ErrIncorrectPassphrase) {
+			t.Fatalf("%s: expected unsupported parameters error, got %v", name, err)
+		}
+	}
+}
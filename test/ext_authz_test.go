@@ -0,0 +1,116 @@
+package test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/routers/ctrl"
+	"github.com/auth-engine/internal/pkg/routers/extauthz"
+)
+
+func newExtAuthzClient(t *testing.T, handler *ctrl.TokenHandler) authv3.AuthorizationClient {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	authv3.RegisterAuthorizationServer(server, extauthz.NewServer(handler))
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return authv3.NewAuthorizationClient(conn)
+}
+
+func checkRequest(headers map[string]string) *authv3.CheckRequest {
+	return &authv3.CheckRequest{Attributes: &authv3.AttributeContext{
+		Request: &authv3.AttributeContext_Request{Http: &authv3.AttributeContext_HttpRequest{Method: "POST", Path: "/v1/chat/completions", Headers: headers}},
+	}}
+}
+
+func headerValues(options []*corev3.HeaderValueOption) map[string]string {
+	headers := make(map[string]string, len(options))
+	for _, option := range options {
+		headers[option.GetHeader().GetKey()] = option.GetHeader().GetValue()
+	}
+	return headers
+}
+
+func TestExtAuthzCheck(t *testing.T) {
+	handler := newTestTokenHandler(t, &models.CreateTokenReq{
+		WorkspaceID: "1", AppScenarioName: "a", ModelName: "m", EnvName: "test", RequestsPerMinute: 2,
+	})
+	created := handler.created
+	client := newExtAuthzClient(t, handler.TokenHandler)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Bearer 和 x-api-key 两种方式都能认证，客户端伪造的 Token 信息请求头会被覆盖
+	for _, headers := range []map[string]string{
+		{"authorization": "Bearer " + created.Token, extauthz.HeaderTokenID: "spoofed"},
+		{"authorization": "Basic dXNlcjpwYXNz", "x-api-key": created.Token},
+	} {
+		resp, err := client.Check(ctx, checkRequest(headers))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if codes.Code(resp.GetStatus().GetCode()) != codes.OK || resp.GetOkResponse() == nil {
+			t.Fatalf("expected OK, got %+v", resp)
+		}
+		upstream := headerValues(resp.GetOkResponse().GetHeaders())
+		if upstream[extauthz.HeaderTokenID] != created.TokenEntity.ID || upstream[extauthz.HeaderWorkspaceID] != "1" ||
+			upstream[extauthz.HeaderMatchedSecret] != "CURRENT" || upstream[extauthz.HeaderModelName] != "m" {
+			t.Fatalf("unexpected upstream headers: %v", upstream)
+		}
+		for _, option := range resp.GetOkResponse().GetHeaders() {
+			if option.GetAppendAction() != corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD {
+				t.Fatalf("expected header %s to overwrite client header", option.GetHeader().GetKey())
+			}
+		}
+		if _, ok := headerValues(resp.GetOkResponse().GetResponseHeadersToAdd())["X-RateLimit-Remaining-Minute"]; !ok {
+			t.Fatalf("expected rate limit headers, got %v", resp.GetOkResponse().GetResponseHeadersToAdd())
+		}
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		code    codes.Code
+		status  int
+		message string
+	}{
+		{name: "missing key", headers: map[string]string{}, code: codes.Unauthenticated, status: 401, message: "Token is empty."},
+		{name: "invalid key", headers: map[string]string{"x-api-key": "invalid"}, code: codes.PermissionDenied, status: 403, message: "Forbidden: Token is invalid."},
+		{name: "other model", headers: map[string]string{"x-api-key": created.Token, "x-requested-model": "m2"}, code: codes.PermissionDenied, status: 403, message: "Token is not allowed for model m2."},
+		{name: "rate limited", headers: map[string]string{"x-api-key": created.Token}, code: codes.ResourceExhausted, status: 429, message: "Token rate limit exceeded."},
+	}
+	for _, tt := range tests {
+		resp, err := client.Check(ctx, checkRequest(tt.headers))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		denied := resp.GetDeniedResponse()
+		if codes.Code(resp.GetStatus().GetCode()) != tt.code || denied == nil || int(denied.GetStatus().GetCode()) != tt.status {
+			t.Fatalf("%s: expected %s/%d, got %+v", tt.name, tt.code, tt.status, resp)
+		}
+		if !strings.Contains(denied.GetBody(), tt.message) {
+			t.Fatalf("%s: expected body to contain %q, got %s", tt.name, tt.message, denied.GetBody())
+		}
+		if tt.code == codes.ResourceExhausted && headerValues(denied.GetHeaders())["Retry-After"] == "" {
+			t.Fatalf("%s: expected Retry-After header, got %v", tt.name, denied.GetHeaders())
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+
+	hertzconfig "github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+
+	"github.com/auth-engine/internal/pkg/models"
+)
+
+func TestForwardAuth(t *testing.T) {
+	handler := newTestTokenHandler(t, &models.CreateTokenReq{
+		WorkspaceID: "1", AppScenarioName: "a", ModelName: "m", EnvName: "test", RequestsPerMinute: 2,
+	})
+	created := handler.created
+	handler.AppConfig.Auth.ForwardAuthHeader = "X-Token"
+	engine := route.NewEngine(hertzconfig.NewOptions(nil))
+	engine.GET("/token/forward-auth", handler.ForwardAuth)
+
+	tests := []struct {
+		name    string
+		headers []ut.Header
+		want    int
+	}{
+		{name: "bearer", headers: []ut.Header{{Key: "Authorization", Value: "Bearer " + created.Token}}, want: http.StatusOK},
+		{name: "configured header", headers: []ut.Header{{Key: "Authorization", Value: "Basic dXNlcjpwYXNz"}, {Key: "X-Token", Value: created.Token}}, want: http.StatusOK},
+		{name: "default header not configured", headers: []ut.Header{{Key: "X-Api-Key", Value: created.Token}}, want: http.StatusUnauthorized},
+		{name: "invalid", headers: []ut.Header{{Key: "Authorization", Value: "Bearer invalid"}}, want: http.StatusForbidden},
+		{name: "other model", headers: []ut.Header{{Key: "Authorization", Value: "Bearer " + created.Token}, {Key: "X-Requested-Model", Value: "m2"}}, want: http.StatusForbidden},
+		{name: "rate limited", headers: []ut.Header{{Key: "Authorization", Value: "Bearer " + created.Token}}, want: http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		resp := ut.PerformRequest(engine, http.MethodGet, "/token/forward-auth", nil, tt.headers...).Result()
+		if resp.StatusCode() != tt.want {
+			t.Fatalf("%s: expected %d, got %d", tt.name, tt.want, resp.StatusCode())
+		}
+		if len(resp.Body()) != 0 {
+			t.Fatalf("%s: expected empty body, got %s", tt.name, resp.Body())
+		}
+		switch tt.want {
+		case http.StatusOK:
+			if string(resp.Header.Peek("X-Auth-Token-Id")) != created.TokenEntity.ID || string(resp.Header.Peek("X-Auth-App")) != "a" ||
+				string(resp.Header.Peek("X-Auth-Model")) != "m" || string(resp.Header.Peek("X-Auth-Env")) != "test" {
+				t.Fatalf("%s: unexpected token headers: %s", tt.name, resp.Header.Header())
+			}
+		case http.StatusTooManyRequests:
+			if len(resp.Header.Peek("Retry-After")) == 0 {
+				t.Fatalf("%s: expected Retry-After header, got %s", tt.name, resp.Header.Header())
+			}
+		default:
+			if len(resp.Header.Peek("X-Auth-Token-Id")) != 0 {
+				t.Fatalf("%s: expected no token headers on denial, got %s", tt.name, resp.Header.Header())
+			}
+		}
+	}
+}
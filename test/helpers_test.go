@@ -0,0 +1,50 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/auth-engine/config"
+	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/routers/ctrl"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/internal/pkg/store"
+)
+
+// testTokenHandler 使用内存 DAO 的 TokenHandler，以及创建的 Token 和测试中需要检查的 DAO
+type testTokenHandler struct {
+	*ctrl.TokenHandler
+	created     *models.CreateTokenResp
+	calendarDao *fakeCalendarDao
+	usageDao    *fakeUsageDao
+	auditDao    *fakeAuditLogDao
+}
+
+// newTestTokenHandler 按 req 创建 Token 并返回认证用的 TokenHandler，Token 认证信息开启缓存，当前时间默认为 time.Now
+func newTestTokenHandler(t *testing.T, req *models.CreateTokenReq) *testTokenHandler {
+	t.Helper()
+	tokenDao, policyDao, calendarDao := newFakeTokenDao(), newFakePolicyDao(), newFakeCalendarDao()
+	tokenDao.policies, tokenDao.calendars = policyDao, calendarDao
+	cachedConfig := newCachedAppConfig()
+	ts := services.NewTokenService(tokenDao, policyDao, cachedConfig, store.NewMemoryStore(0, 0))
+	created, err := ts.Create(req, &models.UserInfo{Username: "admin"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usageDao, auditDao := &fakeUsageDao{usages: map[string]*dao.TokenUsage{}}, &fakeAuditLogDao{}
+	return &testTokenHandler{
+		TokenHandler: &ctrl.TokenHandler{
+			TokenService:    ts,
+			UsageService:    services.NewTokenUsageService(usageDao, &config.AppConfig{}),
+			AuditService:    services.NewAuditLogService(auditDao),
+			CalendarService: services.NewCalendarService(calendarDao, appConfig, store.NewMemoryStore(0, 0)),
+			AppConfig:       cachedConfig,
+			Now:             time.Now,
+		},
+		created:     created,
+		calendarDao: calendarDao,
+		usageDao:    usageDao,
+		auditDao:    auditDao,
+	}
+}
@@ -0,0 +1,195 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	hertzconfig "github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/auth-engine/config"
+	"github.com/auth-engine/internal/pkg/authn"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/routers/ctrl/common"
+)
+
+const (
+	testIssuer   = "https://idp.test"
+	testAudience = "auth-engine"
+)
+
+// newJWKSServer 本地的 JWKS 服务，返回 kid 为 rsa-1 的 RSA 公钥
+func newJWKSServer(t *testing.T, key *rsa.PublicKey) *httptest.Server {
+	jwks := map[string]any{"keys": []map[string]string{{
+		"kid": "rsa-1",
+		"kty": "RSA",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":                "user-1",
+		"preferred_username": "alice",
+		"iss":                testIssuer,
+		"aud":                testAudience,
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func withClaim(name string, value any) jwt.MapClaims {
+	claims := validClaims()
+	if value == nil {
+		delete(claims, name)
+	} else {
+		claims[name] = value
+	}
+	return claims
+}
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecDER, _ := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	server := newJWKSServer(t, &rsaKey.PublicKey)
+
+	verifier, err := authn.NewVerifier(config.JWTConfig{
+		JWKSURL:    server.URL,
+		PublicKeys: []string{string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: ecDER}))},
+		Issuer:     testIssuer,
+		Audience:   testAudience,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	claims, err := verifier.Verify(ctx, signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", validClaims()))
+	if err != nil || claims.Subject != "user-1" || claims.PreferredUsername != "alice" {
+		t.Fatalf("expected jwks token to be valid, got %+v, %v", claims, err)
+	}
+	if _, err := verifier.Verify(ctx, signToken(t, jwt.SigningMethodES256, ecKey, "", validClaims())); err != nil {
+		t.Fatalf("expected static key token to be valid, got %v", err)
+	}
+
+	invalid := map[string]string{
+		"forged signature": signToken(t, jwt.SigningMethodRS256, otherKey, "rsa-1", validClaims()),
+		"unknown kid":      signToken(t, jwt.SigningMethodRS256, otherKey, "rsa-2", validClaims()),
+		"wrong issuer":     signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", withClaim("iss", "https://evil.test")),
+		"wrong audience":   signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", withClaim("aud", "other")),
+		"expired":          signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", withClaim("exp", time.Now().Add(-time.Hour).Unix())),
+		"no expiry":        signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", withClaim("exp", nil)),
+		"no subject":       signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", withClaim("sub", nil)),
+		"hmac":             signToken(t, jwt.SigningMethodHS256, []byte("secret"), "rsa-1", validClaims()),
+		"unsigned":         signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims()),
+	}
+	for name, token := range invalid {
+		if _, err := verifier.Verify(ctx, token); err == nil {
+			t.Errorf("expected %s token to be rejected", name)
+		}
+	}
+
+	if _, err := authn.NewVerifier(config.JWTConfig{}); err == nil {
+		t.Fatal("expected error when no key is configured")
+	}
+}
+
+func TestJWTVerifierPublicKeysFile(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	ecDER, _ := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	file := filepath.Join(t.TempDir(), "public-keys.pem")
+	data := append(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaDER}), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: ecDER})...)
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err := authn.NewVerifier(config.JWTConfig{PublicKeysFile: file, Issuer: testIssuer, Audience: testAudience})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := verifier.Verify(ctx, signToken(t, jwt.SigningMethodRS256, rsaKey, "", validClaims())); err != nil {
+		t.Fatalf("expected token signed by first key to be valid, got %v", err)
+	}
+	if _, err := verifier.Verify(ctx, signToken(t, jwt.SigningMethodES256, ecKey, "", validClaims())); err != nil {
+		t.Fatalf("expected token signed by second key to be valid, got %v", err)
+	}
+
+	if _, err := authn.NewVerifier(config.JWTConfig{PublicKeysFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Fatal("expected error when public keys file is missing")
+	}
+	if err := os.WriteFile(file, []byte("not a pem"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authn.NewVerifier(config.JWTConfig{PublicKeysFile: file}); err == nil {
+		t.Fatal("expected error when public keys file has no pem block")
+	}
+}
+
+func TestVerifyAuthorization(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	verifier, err := authn.NewVerifier(config.JWTConfig{JWKSURL: newJWKSServer(t, &rsaKey.PublicKey).URL, Issuer: testIssuer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := route.NewEngine(hertzconfig.NewOptions(nil))
+	engine.Use(common.VerifyAuthorization(verifier))
+	engine.GET("/me", func(_ context.Context, c *app.RequestContext) {
+		authResult, _ := c.Get(common.AuthResultKey)
+		c.JSON(http.StatusOK, authResult)
+	})
+
+	token := signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", validClaims())
+	resp := ut.PerformRequest(engine, http.MethodGet, "/me", nil, ut.Header{Key: "Authorization", Value: "Bearer " + token}).Result()
+	var authResult models.AuthResult
+	if err := json.Unmarshal(resp.Body(), &authResult); err != nil || resp.StatusCode() != http.StatusOK || authResult.Sub != "user-1" {
+		t.Fatalf("expected verified user, got %d %s", resp.StatusCode(), resp.Body())
+	}
+
+	forged := signToken(t, jwt.SigningMethodRS256, otherKey, "rsa-1", validClaims())
+	resp = ut.PerformRequest(engine, http.MethodGet, "/me", nil, ut.Header{Key: "Authorization", Value: "Bearer " + forged}).Result()
+	if resp.StatusCode() != http.StatusUnauthorized {
+		t.Fatalf("expected forged token to be rejected, got %d", resp.StatusCode())
+	}
+}
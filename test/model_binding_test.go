@@ -0,0 +1,124 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	hertzconfig "github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/routers/ctrl"
+	"github.com/auth-engine/internal/pkg/routers/ctrl/common"
+)
+
+func TestMatchModel(t *testing.T) {
+	tests := []struct {
+		model    string
+		patterns string
+		want     bool
+	}{
+		{model: "gpt-4o", want: true},
+		{model: "gpt-4o-mini", want: false},
+		{model: "gpt-4o-mini", patterns: "gpt-4o*", want: true},
+		{model: "gpt-4", patterns: "gpt-4o*", want: false},
+		{model: "qwen2-7b-instruct", patterns: "llama-*,qwen*-instruct", want: true},
+		{model: "qwen2-7b-chat", patterns: "llama-*,qwen*-instruct", want: false},
+		{model: "meta-llama/Llama-3-8B", patterns: "meta-llama/*", want: true},
+		{model: "gpt-4o-a-b", patterns: "gpt-*-*-b", want: true},
+		{model: "ab", patterns: "ab*b", want: false},
+		{model: "claude-3", patterns: "*", want: true},
+	}
+	for _, tt := range tests {
+		if got := ctrl.MatchModel(tt.model, "gpt-4o", tt.patterns); got != tt.want {
+			t.Errorf("MatchModel(%q, %q) = %v, want %v", tt.model, tt.patterns, got, tt.want)
+		}
+	}
+
+	if got := ctrl.NormalizeModelPatterns(" gpt-4o* , ,qwen-*,"); got != "gpt-4o*,qwen-*" {
+		t.Errorf("unexpected normalized patterns: %q", got)
+	}
+	if err := ctrl.CheckModelPatterns(ctrl.NormalizeModelPatterns("gpt 4o*")); err == nil || err.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for pattern with whitespace, got %v", err)
+	}
+	if err := ctrl.CheckModelPatterns(strings.Repeat("a", 1025)); err == nil {
+		t.Error("expected error for too long patterns")
+	}
+}
+
+func TestTokenAuthWithModel(t *testing.T) {
+	handler := newTestTokenHandler(t, &models.CreateTokenReq{
+		WorkspaceID: "1", AppScenarioName: "a", ModelName: "gpt-4o", ModelPatterns: "gpt-4o-*", EnvName: "test",
+	})
+	created, usageService, usageDao := handler.created, handler.UsageService, handler.usageDao
+	engine := route.NewEngine(hertzconfig.NewOptions(nil))
+	engine.POST("/token/auth", common.Handle(handler.TokenAuth))
+
+	tests := []struct {
+		name    string
+		model   string
+		request string
+		want    int
+	}{
+		{name: "model not provided", want: http.StatusOK},
+		{name: "token model", model: "gpt-4o", want: http.StatusOK},
+		{name: "pattern", model: "gpt-4o-mini", want: http.StatusOK},
+		{name: "other model", model: "gpt-3.5-turbo", want: http.StatusForbidden},
+		{name: "openai request body", request: `{"model":"gpt-4o-mini","messages":[{"role":"user","content":"hi"}]}`, want: http.StatusOK},
+		{name: "openai request body with other model", request: `{"model":"claude-3","messages":[]}`, want: http.StatusForbidden},
+		{name: "model overrides request body", model: "gpt-4o", request: `{"model":"claude-3"}`, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		body, _ := json.Marshal(models.TokenAuthBody{Token: created.Token, Model: tt.model, Request: json.RawMessage(tt.request)})
+		resp := ut.PerformRequest(engine, http.MethodPost, "/token/auth",
+			&ut.Body{Body: bytes.NewReader(body), Len: len(body)}, ut.Header{Key: "Content-Type", Value: "application/json"}).Result()
+		if resp.StatusCode() != tt.want {
+			t.Fatalf("%s: expected %d, got %d, %s", tt.name, tt.want, resp.StatusCode(), resp.Body())
+		}
+		if tt.want == http.StatusForbidden && !strings.Contains(string(resp.Body()), "Token is not allowed for model") {
+			t.Fatalf("%s: expected model denial reason, got %s", tt.name, resp.Body())
+		}
+	}
+
+	if err := usageService.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	usage := usageDao.usages[created.TokenEntity.ID]
+	if usage == nil || usage.AllowedCount != 5 || usage.PolicyDeniedCount != 2 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+
+	// 开启 auth.requireModel 后未携带模型的请求被拒绝
+	handler.AppConfig.Auth.RequireModel = true
+	for _, tt := range []struct {
+		name    string
+		model   string
+		request string
+		want    int
+	}{
+		{name: "model required", want: http.StatusForbidden},
+		{name: "request body without model", request: `{"messages":[]}`, want: http.StatusForbidden},
+		{name: "model provided", model: "gpt-4o", want: http.StatusOK},
+		{name: "openai request body", request: `{"model":"gpt-4o-mini"}`, want: http.StatusOK},
+	} {
+		body, _ := json.Marshal(models.TokenAuthBody{Token: created.Token, Model: tt.model, Request: json.RawMessage(tt.request)})
+		resp := ut.PerformRequest(engine, http.MethodPost, "/token/auth",
+			&ut.Body{Body: bytes.NewReader(body), Len: len(body)}, ut.Header{Key: "Content-Type", Value: "application/json"}).Result()
+		if resp.StatusCode() != tt.want {
+			t.Fatalf("%s: expected %d, got %d, %s", tt.name, tt.want, resp.StatusCode(), resp.Body())
+		}
+		if tt.want == http.StatusForbidden && !strings.Contains(string(resp.Body()), "Model is required") {
+			t.Fatalf("%s: expected model required reason, got %s", tt.name, resp.Body())
+		}
+	}
+	if err := usageService.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if usage := usageDao.usages[created.TokenEntity.ID]; usage.AllowedCount != 7 || usage.PolicyDeniedCount != 4 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+}
@@ -0,0 +1,97 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/internal/pkg/store"
+)
+
+func TestCheckRateLimit(t *testing.T) {
+	ts := services.NewTokenService(newFakeTokenDao(), newFakePolicyDao(), newCachedAppConfig(), store.NewMemoryStore(100, time.Minute))
+	ctx := context.Background()
+	now := time.Date(2026, 10, 17, 12, 0, 30, 0, time.UTC)
+
+	// 未配置上限时不限制
+	result, err := ts.CheckRateLimit(ctx, &dao.TokenAuthInfo{TokenID: "unlimited"}, now)
+	if err != nil || !result.Allowed || len(result.Windows) != 0 {
+		t.Fatalf("expected unlimited token to be allowed, got %+v, %v", result, err)
+	}
+
+	info := &dao.TokenAuthInfo{TokenID: "rpm", RequestsPerMinute: 2}
+	for i := 1; i <= 2; i++ {
+		result, err := ts.CheckRateLimit(ctx, info, now)
+		if err != nil || !result.Allowed {
+			t.Fatalf("expected request %d to be allowed, got %+v, %v", i, result, err)
+		}
+		if result.Windows[0].Remaining != 2-i {
+			t.Fatalf("expected remaining %d, got %d", 2-i, result.Windows[0].Remaining)
+		}
+	}
+	result, err = ts.CheckRateLimit(ctx, info, now)
+	if err != nil || result.Allowed {
+		t.Fatalf("expected request to be limited, got %+v, %v", result, err)
+	}
+	if result.RetryAfter < time.Second || result.RetryAfter > 2*time.Minute {
+		t.Fatalf("unexpected retry after: %v", result.RetryAfter)
+	}
+	if result.Windows[0].Remaining != 0 {
+		t.Fatalf("expected no remaining quota, got %d", result.Windows[0].Remaining)
+	}
+	// 下一个窗口中上一个窗口的请求按剩余比例折算，两个窗口之后额度完全恢复
+	if result, _ := ts.CheckRateLimit(ctx, info, now.Add(75*time.Second)); !result.Allowed || result.Windows[0].Remaining != 0 {
+		t.Fatalf("expected one request allowed in next window, got %+v", result)
+	}
+	if result, _ := ts.CheckRateLimit(ctx, info, now.Add(3*time.Minute)); !result.Allowed || result.Windows[0].Remaining != 1 {
+		t.Fatalf("expected quota to recover after two windows, got %+v", result)
+	}
+}
+
+// TestCheckRateLimitRollback 每天的额度用完时，本次请求不计入每分钟的额度
+func TestCheckRateLimitRollback(t *testing.T) {
+	ts := services.NewTokenService(newFakeTokenDao(), newFakePolicyDao(), newCachedAppConfig(), store.NewMemoryStore(100, time.Minute))
+	ctx := context.Background()
+	now := time.Date(2026, 10, 17, 12, 0, 30, 0, time.UTC)
+	info := &dao.TokenAuthInfo{TokenID: "rpd", RequestsPerMinute: 10, RequestsPerDay: 1}
+	if result, _ := ts.CheckRateLimit(ctx, info, now); !result.Allowed {
+		t.Fatal("expected first request to be allowed")
+	}
+	for i := 0; i < 3; i++ {
+		if result, _ := ts.CheckRateLimit(ctx, info, now); result.Allowed {
+			t.Fatal("expected daily limit to be enforced")
+		}
+	}
+	info.RequestsPerDay = 0
+	result, err := ts.CheckRateLimit(ctx, info, now)
+	if err != nil || !result.Allowed || result.Windows[0].Remaining != 8 {
+		t.Fatalf("expected limited requests not to be counted, got %+v, %v", result.Windows[0], err)
+	}
+}
+
+// TestRateLimitRefundOnLeaseDenied 因并发数超限被拒绝的请求不计入每分钟的额度
+func TestRateLimitRefundOnLeaseDenied(t *testing.T) {
+	handler := newTestTokenHandler(t, &models.CreateTokenReq{
+		WorkspaceID: "1", AppScenarioName: "a", ModelName: "m", EnvName: "test", MaxConcurrency: 1, RequestsPerMinute: 2,
+	})
+	ctx := context.Background()
+	token := handler.created.Token
+	if _, err := handler.Authenticate(ctx, &models.TokenAuthBody{Token: token, AcquireLease: true}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := handler.Authenticate(ctx, &models.TokenAuthBody{Token: token, AcquireLease: true})
+	if err == nil || !strings.Contains(err.Error(), "concurrency limit exceeded") {
+		t.Fatalf("expected concurrency limit error, got %v", err)
+	}
+	if result.RateLimit.Windows[0].Remaining != 1 {
+		t.Fatalf("expected denied request to be refunded, got remaining %d", result.RateLimit.Windows[0].Remaining)
+	}
+	result, err = handler.Authenticate(ctx, &models.TokenAuthBody{Token: token})
+	if err != nil || result.RateLimit.Windows[0].Remaining != 0 {
+		t.Fatalf("expected second counted request to be allowed, got %+v, %v", result, err)
+	}
+}
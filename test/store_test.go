@@ -0,0 +1,203 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/internal/pkg/store"
+)
+
+func newMiniRedisStore(t *testing.T) (*miniredis.Miniredis, store.IStore) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, store.NewRedisStore(client, "auth-engine:")
+}
+
+func TestStore(t *testing.T) {
+	_, redisStore := newMiniRedisStore(t)
+	memoryStore := store.NewMemoryStore(100, time.Minute)
+	t.Cleanup(memoryStore.Close)
+	stores := map[string]store.IStore{
+		"memory": memoryStore,
+		"redis":  redisStore,
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, ok, err := s.Get(ctx, "missing"); err != nil || ok {
+				t.Fatalf("expected missing key, got %v, %v", ok, err)
+			}
+			if err := s.Set(ctx, "k", []byte("v"), time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			if value, ok, err := s.Get(ctx, "k"); err != nil || !ok || string(value) != "v" {
+				t.Fatalf("expected value v, got %q, %v, %v", value, ok, err)
+			}
+			if err := s.Delete(ctx, "k"); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok, _ := s.Get(ctx, "k"); ok {
+				t.Fatal("expected key to be deleted")
+			}
+			for i := int64(1); i <= 3; i++ {
+				n, err := s.IncrBy(ctx, "counter", 1, time.Minute)
+				if err != nil || n != i {
+					t.Fatalf("expected counter %d, got %d, %v", i, n, err)
+				}
+			}
+			if n, _ := s.IncrBy(ctx, "counter", -2, time.Minute); n != 1 {
+				t.Fatalf("expected counter 1, got %d", n)
+			}
+			for _, leaseID := range []string{"l1", "l2"} {
+				if ok, err := s.AcquireLease(ctx, "lease", leaseID, 2, time.Minute); err != nil || !ok {
+					t.Fatalf("expected lease %s to be acquired, got %v, %v", leaseID, ok, err)
+				}
+			}
+			if ok, _ := s.AcquireLease(ctx, "lease", "l3", 2, time.Minute); ok {
+				t.Fatal("expected lease limit to be enforced")
+			}
+			if ok, err := s.ReleaseLease(ctx, "lease", "l1"); err != nil || !ok {
+				t.Fatalf("expected lease to be released, got %v, %v", ok, err)
+			}
+			if ok, _ := s.ReleaseLease(ctx, "lease", "l1"); ok {
+				t.Fatal("expected released lease to be gone")
+			}
+			if ok, _ := s.AcquireLease(ctx, "lease", "l3", 2, time.Minute); !ok {
+				t.Fatal("expected lease to be acquired after release")
+			}
+			// 过期的租约不占用名额
+			if ok, _ := s.AcquireLease(ctx, "short", "l1", 1, 20*time.Millisecond); !ok {
+				t.Fatal("expected lease to be acquired")
+			}
+			time.Sleep(30 * time.Millisecond)
+			if ok, _ := s.AcquireLease(ctx, "short", "l2", 1, time.Minute); !ok {
+				t.Fatal("expected expired lease to free its slot")
+			}
+		})
+	}
+}
+
+// TestMemoryStoreClose Close 后清理协程退出，重复调用不会 panic
+func TestMemoryStoreClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	s := store.NewMemoryStore(10, 10*time.Millisecond)
+	if runtime.NumGoroutine() <= before {
+		t.Fatal("expected cleanup goroutines to be started")
+	}
+	s.Close()
+	s.Close()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("expected cleanup goroutines to exit, got %d goroutines, want %d", n, before)
+	}
+}
+
+func TestRedisStoreCounterExpiration(t *testing.T) {
+	mr, s := newMiniRedisStore(t)
+	ctx := context.Background()
+	if _, err := s.IncrBy(ctx, "counter", 1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// 后续递增不会刷新过期时间
+	if _, err := s.IncrBy(ctx, "counter", 1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL("auth-engine:counter"); ttl != time.Second {
+		t.Fatalf("expected ttl 1s, got %v", ttl)
+	}
+	mr.FastForward(2 * time.Second)
+	if n, _ := s.IncrBy(ctx, "counter", 1, time.Second); n != 1 {
+		t.Fatalf("expected counter to restart after expiration, got %d", n)
+	}
+}
+
+// TestTokenAuthInfoSharedCache 两个副本共享 Redis，一个副本修改 Token 后另一个副本的缓存同时失效
+func TestTokenAuthInfoSharedCache(t *testing.T) {
+	_, s := newMiniRedisStore(t)
+	tokenDao := newFakeTokenDao()
+	policyDao := newFakePolicyDao()
+	replica1 := services.NewTokenService(tokenDao, policyDao, newCachedAppConfig(), s)
+	replica2 := services.NewTokenService(tokenDao, policyDao, newCachedAppConfig(), s)
+	userInfo := &models.UserInfo{Username: "admin"}
+	ctx := context.Background()
+
+	created, err := replica1.Create(&models.CreateTokenReq{AppScenarioName: "app", ModelName: "model"}, userInfo, nil)
+	if err != nil {
+		t.Fatalf("create token failed: %v", err)
+	}
+	if infos, err := replica2.GetTokenAuthInfo(ctx, created.Token); err != nil || len(infos) != 1 {
+		t.Fatalf("expected auth info, got %v, %v", infos, err)
+	}
+	// replica1 直接命中 replica2 写入的缓存
+	hits := tokenDao.authInfoHits
+	if _, err := replica1.GetTokenAuthInfo(ctx, created.Token); err != nil {
+		t.Fatal(err)
+	}
+	if tokenDao.authInfoHits != hits {
+		t.Fatal("expected auth info to be served from shared cache")
+	}
+
+	if err := replica1.Delete(created.WorkspaceID, created.ID); err != nil {
+		t.Fatalf("delete token failed: %v", err)
+	}
+	if infos, _ := replica2.GetTokenAuthInfo(ctx, created.Token); len(infos) != 0 {
+		t.Fatalf("expected no auth info on other replica after delete, got %+v", infos)
+	}
+}
+
+// TestTokenAuthInfoStoreUnavailable Redis 不可用时直接查询数据库
+func TestTokenAuthInfoStoreUnavailable(t *testing.T) {
+	mr, s := newMiniRedisStore(t)
+	tokenDao := newFakeTokenDao()
+	ts := services.NewTokenService(tokenDao, newFakePolicyDao(), newCachedAppConfig(), s)
+	created, err := ts.Create(&models.CreateTokenReq{AppScenarioName: "app", ModelName: "model"}, &models.UserInfo{Username: "admin"}, nil)
+	if err != nil {
+		t.Fatalf("create token failed: %v", err)
+	}
+	mr.Close()
+	if infos, err := ts.GetTokenAuthInfo(context.Background(), created.Token); err != nil || len(infos) != 1 {
+		t.Fatalf("expected auth info from dao, got %v, %v", infos, err)
+	}
+}
+
+func TestTokenLease(t *testing.T) {
+	_, s := newMiniRedisStore(t)
+	ts := services.NewTokenService(newFakeTokenDao(), newFakePolicyDao(), newCachedAppConfig(), s)
+	ctx := context.Background()
+
+	// 未限制并发时不生成租约
+	resp, err := ts.AcquireLease(ctx, "token-1", 0)
+	if err != nil || resp.LeaseID != "" {
+		t.Fatalf("expected no lease for unlimited token, got %+v, %v", resp, err)
+	}
+
+	lease, err := ts.AcquireLease(ctx, "token-1", 1)
+	if err != nil || lease.LeaseID == "" || lease.LeaseExpireTime == nil {
+		t.Fatalf("expected lease, got %+v, %v", lease, err)
+	}
+	if _, err := ts.AcquireLease(ctx, "token-1", 1); !errors.Is(err, services.ErrConcurrencyLimitExceeded) {
+		t.Fatalf("expected concurrency limit error, got %v", err)
+	}
+	// 其他 Token 不受影响
+	if _, err := ts.AcquireLease(ctx, "token-2", 1); err != nil {
+		t.Fatalf("expected lease for other token, got %v", err)
+	}
+	if released, err := ts.ReleaseLease(ctx, "token-1", lease.LeaseID); err != nil || !released {
+		t.Fatalf("expected lease to be released, got %v, %v", released, err)
+	}
+	if _, err := ts.AcquireLease(ctx, "token-1", 1); err != nil {
+		t.Fatalf("expected lease after release, got %v", err)
+	}
+}
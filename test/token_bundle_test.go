@@ -0,0 +1,244 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	hertzconfig "github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/cloudwego/hertz/pkg/route"
+
+	"github.com/auth-engine/config"
+	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/routers/ctrl"
+	"github.com/auth-engine/internal/pkg/routers/ctrl/common"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/internal/pkg/store"
+	"github.com/auth-engine/internal/pkg/utils"
+	"github.com/auth-engine/pkg/constants"
+)
+
+func newBundleTokenService(tokenDao *fakeTokenDao) services.ITokenService {
+	return services.NewTokenService(tokenDao, newFakePolicyDao(), appConfig, store.NewMemoryStore(0, 0))
+}
+
+// newBundleImportEngine 注册批量导入接口，导入到工作空间 2，当前用户在工作空间中的角色为 role
+func newBundleImportEngine(tokenService services.ITokenService, exportCipher *utils.ExportCipher, role string) *route.Engine {
+	handler := &ctrl.TokenHandler{
+		TokenService: tokenService,
+		AuditService: services.NewAuditLogService(&fakeAuditLogDao{}),
+		ExportCipher: exportCipher,
+	}
+	engine := route.NewEngine(hertzconfig.NewOptions(nil))
+	engine.POST("/workspaces/:workspaceId/tokens/bundle/import", func(ctx context.Context, c *app.RequestContext) {
+		c.Set(common.AuthResultKey, models.AuthResult{PreferredUsername: "admin"})
+		c.Set(common.WorkspaceRoleKey, role)
+		c.Next(ctx)
+	}, common.Handle(handler.ImportTokenBundle))
+	return engine
+}
+
+// postBundle 以 multipart 表单上传导出文件到批量导入接口，fields 为其他表单字段
+func postBundle(engine *route.Engine, data []byte, fields ...string) *protocol.Response {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", "tokens.txt")
+	_, _ = part.Write(data)
+	for i := 0; i+1 < len(fields); i += 2 {
+		_ = writer.WriteField(fields[i], fields[i+1])
+	}
+	_ = writer.Close()
+	return ut.PerformRequest(engine, http.MethodPost, "/workspaces/2/tokens/bundle/import",
+		&ut.Body{Body: &body, Len: body.Len()}, ut.Header{Key: "Content-Type", Value: writer.FormDataContentType()}).Result()
+}
+
+// importBundle 上传导出文件并返回导入结果，导入接口返回错误时测试失败
+func importBundle(t *testing.T, engine *route.Engine, data []byte, fields ...string) *models.ImportTokenBundleResp {
+	resp := postBundle(engine, data, fields...)
+	if resp.StatusCode() != http.StatusOK {
+		t.Fatalf("import bundle: %d, %s", resp.StatusCode(), resp.Body())
+	}
+	var result models.DataResult[*models.ImportTokenBundleResp]
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result.Result
+}
+
+func TestImportTokenBundle(t *testing.T) {
+	currentEnvName := config.CurrentEnvName
+	config.CurrentEnvName = "test"
+	defer func() { config.CurrentEnvName = currentEnvName }()
+	userInfo := &models.UserInfo{Username: "admin"}
+
+	// 源工作空间中有两个当前环境的 Token 和一个其他环境的 Token
+	sourceService := newBundleTokenService(newFakeTokenDao())
+	for _, req := range []*models.CreateTokenReq{
+		{WorkspaceID: "1", AppScenarioName: "a", ModelName: "m", EnvName: "test"},
+		{WorkspaceID: "1", AppScenarioName: "b", ModelName: "m", EnvName: "test"},
+		{WorkspaceID: "1", AppScenarioName: "c", ModelName: "m", EnvName: "prod"},
+	} {
+		if _, err := sourceService.Create(req, userInfo, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tokens, err := sourceService.ListExportEntities("1", dao.TokenQueryParam{EnvName: "test"})
+	if err != nil || len(tokens) != 2 {
+		t.Fatalf("expected 2 tokens filtered by env, got %d, %v", len(tokens), err)
+	}
+	tokens, _ = sourceService.ListExportEntities("1", dao.TokenQueryParam{})
+	// 缺少摘要的 Token 导入失败
+	tokens = append(tokens, &models.TokenExportEntity{TokenResp: models.TokenResp{AppScenarioName: "d", ModelName: "m", EnvName: "test"}})
+	bundle, _ := json.Marshal(&models.TokenExportBundle{WorkspaceID: "1", Tokens: tokens})
+
+	// 目标工作空间中已有同名 Token
+	targetService := newBundleTokenService(newFakeTokenDao())
+	if _, err := targetService.Create(&models.CreateTokenReq{WorkspaceID: "2", AppScenarioName: "b", ModelName: "m", EnvName: "test"}, userInfo, nil); err != nil {
+		t.Fatal(err)
+	}
+	exportCipher, _ := utils.NewExportCipher(config.Export{Keys: []config.ExportKey{{ID: "k1", Key: exportKey1}}})
+	engine := newBundleImportEngine(targetService, exportCipher, constants.WorkspaceRoleAdmin)
+
+	data, _ := exportCipher.Encrypt(bundle)
+	resp := importBundle(t, engine, data)
+	if resp.Total != 4 || resp.Created != 1 || resp.Conflict != 1 || resp.Skipped != 1 || resp.Error != 1 {
+		t.Fatalf("unexpected import summary: %+v", resp)
+	}
+	expected := map[string]string{
+		"a": constants.ImportResultCreated,
+		"b": constants.ImportResultConflict,
+		"c": constants.ImportResultSkipped,
+		"d": constants.ImportResultError,
+	}
+	for _, result := range resp.Results {
+		if result.Status != expected[result.AppScenarioName] {
+			t.Errorf("token %s: expected %s, got %s (%s)", result.AppScenarioName, expected[result.AppScenarioName], result.Status, result.Message)
+		}
+	}
+
+	// 重复导入时已导入的 Token 跳过
+	resp = importBundle(t, engine, data)
+	if resp.Created != 0 || resp.Skipped != 2 {
+		t.Fatalf("unexpected re-import summary: %+v", resp)
+	}
+
+	// pepper 不同的环境中摘要无法认证，拒绝导入
+	otherPepperConfig := &config.AppConfig{Auth: config.Auth{TokenPepper: "other-pepper"}}
+	otherService := services.NewTokenService(newFakeTokenDao(), newFakePolicyDao(), otherPepperConfig, store.NewMemoryStore(0, 0))
+	resp = importBundle(t, newBundleImportEngine(otherService, exportCipher, constants.WorkspaceRoleAdmin), data)
+	if resp.Created != 0 {
+		t.Fatalf("expected no token imported with other pepper, got %+v", resp)
+	}
+	for _, result := range resp.Results {
+		if result.AppScenarioName == "a" && (result.Status != constants.ImportResultError || !strings.Contains(result.Message, "pepper")) {
+			t.Fatalf("expected pepper mismatch error, got %+v", result)
+		}
+	}
+}
+
+func TestImportConflictStrategies(t *testing.T) {
+	currentEnvName := config.CurrentEnvName
+	config.CurrentEnvName = "test"
+	defer func() { config.CurrentEnvName = currentEnvName }()
+	userInfo := &models.UserInfo{Username: "admin"}
+
+	sourceService := newBundleTokenService(newFakeTokenDao())
+	if _, err := sourceService.Create(&models.CreateTokenReq{
+		WorkspaceID: "1", AppScenarioName: "a", ModelName: "m", EnvName: "test", RequestsPerMinute: 60,
+	}, userInfo, nil); err != nil {
+		t.Fatal(err)
+	}
+	tokens, _ := sourceService.ListExportEntities("1", dao.TokenQueryParam{})
+	bundle, _ := json.Marshal(&models.TokenExportBundle{WorkspaceID: "1", Tokens: tokens})
+	exportCipher, _ := utils.NewExportCipher(config.Export{Keys: []config.ExportKey{{ID: "k1", Key: exportKey1}}})
+	data, _ := exportCipher.Encrypt(bundle)
+
+	// 目标工作空间中已有同名但密钥不同的 Token
+	targetDao := newFakeTokenDao()
+	targetService := newBundleTokenService(targetDao)
+	existing, err := targetService.Create(&models.CreateTokenReq{WorkspaceID: "2", AppScenarioName: "a", ModelName: "m", EnvName: "test"}, userInfo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := newBundleImportEngine(targetService, exportCipher, constants.WorkspaceRoleAdmin)
+
+	cases := []struct {
+		strategy string
+		status   string
+	}{
+		{"", constants.ImportResultConflict},
+		{constants.ConflictStrategySkip, constants.ImportResultSkipped},
+		{constants.ConflictStrategyOverwrite, constants.ImportResultUpdated},
+		{constants.ConflictStrategyRename, constants.ImportResultCreated},
+	}
+	// 试运行不写入数据
+	for _, c := range cases {
+		resp := importBundle(t, engine, data, "mode", constants.ImportModeDryRun, "conflictStrategy", c.strategy)
+		if !resp.DryRun || resp.Results[0].Status != c.status {
+			t.Errorf("dry run with strategy %q: expected %s, got %+v", c.strategy, c.status, resp.Results[0])
+		}
+	}
+	if len(targetDao.tokens) != 1 || targetDao.tokens[existing.ID].TokenHash != existing.TokenHash {
+		t.Fatal("dry run should not write any data")
+	}
+
+	// overwrite 会替换密钥，editor 不能使用
+	editorEngine := newBundleImportEngine(targetService, exportCipher, constants.WorkspaceRoleEditor)
+	if resp := postBundle(editorEngine, data, "conflictStrategy", constants.ConflictStrategyOverwrite); resp.StatusCode() != http.StatusForbidden {
+		t.Fatalf("expected editor overwrite to be forbidden, got %d, %s", resp.StatusCode(), resp.Body())
+	}
+	if resp := importBundle(t, editorEngine, data, "conflictStrategy", constants.ConflictStrategySkip); resp.Skipped != 1 {
+		t.Fatalf("expected editor to import with skip strategy, got %+v", resp.Results[0])
+	}
+
+	// overwrite 更新限制和密钥，Token ID 不变
+	resp := importBundle(t, engine, data, "conflictStrategy", constants.ConflictStrategyOverwrite)
+	if resp.Updated != 1 || resp.Results[0].TokenID != existing.ID {
+		t.Fatalf("unexpected overwrite result: %+v", resp.Results[0])
+	}
+	overwritten := targetDao.tokens[existing.ID]
+	if overwritten.TokenHash != tokens[0].TokenHash || overwritten.RequestsPerMinute != 60 {
+		t.Fatalf("token not overwritten: %+v", overwritten)
+	}
+	// 再次导入相同内容时跳过，结果幂等
+	if resp := importBundle(t, engine, data); resp.Skipped != 1 {
+		t.Fatalf("expected re-import skipped, got %+v", resp.Results[0])
+	}
+
+	// rename 在应用场景名称后追加序号，新 Token 的密钥不能与已有 Token 相同，使用旧版本的明文导出
+	tokens[0].TokenHash = ""
+	tokens[0].Token = "renamed-token-secret"
+	bundle, _ = json.Marshal(&models.TokenExportBundle{WorkspaceID: "1", Tokens: tokens})
+	data, _ = exportCipher.Encrypt(bundle)
+	resp = importBundle(t, engine, data, "conflictStrategy", constants.ConflictStrategyRename)
+	if resp.Created != 1 || resp.Results[0].RenamedTo != "a-2" {
+		t.Fatalf("unexpected rename result: %+v", resp.Results[0])
+	}
+
+	// 试运行时同一批次中重名的 Token 不会被重命名为相同的名称
+	second := *tokens[0]
+	second.Token = "another-token-secret"
+	tokens[0].Token = "third-token-secret"
+	bundle, _ = json.Marshal(&models.TokenExportBundle{WorkspaceID: "1", Tokens: []*models.TokenExportEntity{tokens[0], &second}})
+	data, _ = exportCipher.Encrypt(bundle)
+	resp = importBundle(t, engine, data, "mode", constants.ImportModeDryRun, "conflictStrategy", constants.ConflictStrategyRename)
+	if resp.Created != 2 || resp.Results[0].RenamedTo != "a-3" || resp.Results[1].RenamedTo != "a-4" {
+		t.Fatalf("unexpected dry run rename results: %+v, %+v", resp.Results[0], resp.Results[1])
+	}
+	// 试运行时批次中前面的 Token 将要创建的名称同样视为冲突
+	second.AppScenarioName, tokens[0].AppScenarioName = "b", "b"
+	bundle, _ = json.Marshal(&models.TokenExportBundle{WorkspaceID: "1", Tokens: []*models.TokenExportEntity{tokens[0], &second}})
+	data, _ = exportCipher.Encrypt(bundle)
+	resp = importBundle(t, engine, data, "mode", constants.ImportModeDryRun)
+	if resp.Created != 1 || resp.Conflict != 1 || resp.Results[1].Status != constants.ImportResultConflict {
+		t.Fatalf("unexpected dry run conflict results: %+v", resp)
+	}
+}
@@ -0,0 +1,330 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/auth-engine/config"
+	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/internal/pkg/store"
+	"github.com/auth-engine/internal/pkg/utils/cache"
+	"github.com/auth-engine/pkg/constants"
+	"gorm.io/gorm"
+)
+
+// fakeTokenDao 内存中的 TokenDao，只实现测试用到的方法
+type fakeTokenDao struct {
+	dao.ITokenDao
+	tokens       map[string]*dao.TokenEntity // key 为 token ID
+	authInfoHits int                         // GetTokenAuthInfo 调用次数
+	policies     *fakePolicyDao              // 不为空时 GetTokenAuthInfo 按策略返回多条记录
+	calendars    *fakeCalendarDao            // 不为空时按关联的日历设置 CalendarCount
+}
+
+func newFakeTokenDao() *fakeTokenDao {
+	return &fakeTokenDao{tokens: map[string]*dao.TokenEntity{}}
+}
+
+func (d *fakeTokenDao) GetTokenAuthInfo(tokenHash string) ([]*dao.TokenAuthInfo, error) {
+	d.authInfoHits++
+	var infos []*dao.TokenAuthInfo
+	for _, token := range d.tokens {
+		matchedSecret := constants.MatchedSecretCurrent
+		if token.TokenHash != tokenHash {
+			if token.PreviousTokenHash == "" || token.PreviousTokenHash != tokenHash {
+				continue
+			}
+			matchedSecret = constants.MatchedSecretPrevious
+		}
+		infos = append(infos, d.authInfos(token, matchedSecret)...)
+	}
+	return infos, nil
+}
+
+func (d *fakeTokenDao) GetTokenAuthInfoByID(workspaceID, id string) ([]*dao.TokenAuthInfo, error) {
+	token, ok := d.tokens[id]
+	if !ok || token.WorkspaceID != workspaceID {
+		return nil, nil
+	}
+	return d.authInfos(token, constants.MatchedSecretCurrent), nil
+}
+
+// authInfos 与 GetTokenAuthInfo 的左连接一致，每个策略一条记录，没有策略时返回一条记录
+func (d *fakeTokenDao) authInfos(token *dao.TokenEntity, matchedSecret string) []*dao.TokenAuthInfo {
+	info := dao.TokenAuthInfo{
+		TokenID:                  token.ID,
+		WorkspaceID:              token.WorkspaceID,
+		MatchedSecret:            matchedSecret,
+		Status:                   token.Status,
+		ResumeTime:               token.ResumeTime,
+		PreviousTokenExpiredTime: token.PreviousTokenExpiredTime,
+		TokenPrefix:              token.TokenPrefix,
+		ExpiredTime:              token.ExpiredTime,
+		AppScenarioName:          token.AppScenarioName,
+		ModelName:                token.ModelName,
+		ModelPatterns:            token.ModelPatterns,
+		EnvName:                  token.EnvName,
+		MaxConcurrency:           token.MaxConcurrency,
+		RequestsPerMinute:        token.RequestsPerMinute,
+		RequestsPerDay:           token.RequestsPerDay,
+		EnableValidityPolicy:     token.EnableValidityPolicy,
+		PolicyType:               token.PolicyType,
+		Timezone:                 token.Timezone,
+	}
+	if d.calendars != nil {
+		info.CalendarCount = len(d.calendars.bindings[token.ID])
+	}
+	var policies []*dao.TokenValidityPolicy
+	if d.policies != nil {
+		policies = d.policies.policies[token.ID]
+	}
+	if len(policies) == 0 {
+		return []*dao.TokenAuthInfo{&info}
+	}
+	var infos []*dao.TokenAuthInfo
+	for _, policy := range policies {
+		policyInfo := info
+		policyInfo.PolicyType = policy.PolicyType
+		policyInfo.StartTime, policyInfo.EndTime = policy.StartTime, policy.EndTime
+		policyInfo.StartDay, policyInfo.EndDay = policy.StartDay, policy.EndDay
+		policyInfo.StartDate, policyInfo.EndDate = policy.StartDate, policy.EndDate
+		policyInfo.CronExpr, policyInfo.CronDuration = policy.CronExpr, policy.CronDuration
+		if policy.Timezone != "" {
+			policyInfo.Timezone = policy.Timezone
+		}
+		infos = append(infos, &policyInfo)
+	}
+	return infos
+}
+
+func (d *fakeTokenDao) Create(req *dao.TokenEntity) error {
+	d.tokens[req.ID] = req
+	return nil
+}
+
+func (d *fakeTokenDao) Get(workspaceID, id string) (*dao.TokenEntity, error) {
+	token, ok := d.tokens[id]
+	if !ok || token.WorkspaceID != workspaceID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return token, nil
+}
+
+func (d *fakeTokenDao) GetByTokenHash(tokenHash string) (*dao.TokenEntity, error) {
+	for _, token := range d.tokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (d *fakeTokenDao) GetByName(appScenarioName, modelName, envName string) (*dao.TokenEntity, error) {
+	for _, token := range d.tokens {
+		if token.AppScenarioName == appScenarioName && token.ModelName == modelName && token.EnvName == envName {
+			return token, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (d *fakeTokenDao) CheckTokenExists(appScenarioName, modelName, envName, id string) (bool, error) {
+	for _, token := range d.tokens {
+		if token.AppScenarioName == appScenarioName && token.ModelName == modelName && token.EnvName == envName && token.ID != id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// QueryPageList 只支持按工作空间和环境过滤，不分页
+func (d *fakeTokenDao) QueryPageList(
+	workspaceID string, _ dao.PageParam, _ dao.OrderParam, queryParam dao.TokenQueryParam,
+) (int64, []*dao.TokenEntity, error) {
+	var tokens []*dao.TokenEntity
+	for _, token := range d.tokens {
+		if token.WorkspaceID == workspaceID && (queryParam.EnvName == "" || token.EnvName == queryParam.EnvName) {
+			tokens = append(tokens, token)
+		}
+	}
+	return int64(len(tokens)), tokens, nil
+}
+
+func (d *fakeTokenDao) Update(id string, req *dao.TokenEntity) error {
+	d.tokens[id] = req
+	return nil
+}
+
+func (d *fakeTokenDao) Rotate(id string, req *dao.TokenEntity) error {
+	token, ok := d.tokens[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	rotated := *token
+	rotated.TokenDigest = req.TokenDigest
+	rotated.PreviousTokenHash = req.PreviousTokenHash
+	rotated.PreviousTokenExpiredTime = req.PreviousTokenExpiredTime
+	rotated.UpdateBy = req.UpdateBy
+	rotated.UpdateTime = req.UpdateTime
+	d.tokens[id] = &rotated
+	return nil
+}
+
+func (d *fakeTokenDao) Overwrite(id string, req *dao.TokenEntity, policies []*dao.TokenValidityPolicy, digest *dao.TokenDigest) error {
+	if _, ok := d.tokens[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if d.policies != nil {
+		delete(d.policies.policies, id)
+		_ = d.policies.BatchCreate(policies, 0)
+	}
+	overwritten := *req
+	if digest != nil {
+		overwritten.TokenDigest = *digest
+		overwritten.PreviousTokenHash = ""
+		overwritten.PreviousTokenExpiredTime = nil
+	}
+	d.tokens[id] = &overwritten
+	return nil
+}
+
+func (d *fakeTokenDao) UpdateStatus(id string, req *dao.TokenEntity) error {
+	d.tokens[id] = req
+	return nil
+}
+
+func (d *fakeTokenDao) Delete(id string) error {
+	delete(d.tokens, id)
+	return nil
+}
+
+// fakePolicyDao 内存中的 TokenValidityPolicyDao
+type fakePolicyDao struct {
+	policies map[string][]*dao.TokenValidityPolicy // key 为 token ID
+}
+
+func newFakePolicyDao() *fakePolicyDao {
+	return &fakePolicyDao{policies: map[string][]*dao.TokenValidityPolicy{}}
+}
+
+func (d *fakePolicyDao) ListByTokenID(tokenID, policyType string) ([]*dao.TokenValidityPolicy, error) {
+	return d.policies[tokenID], nil
+}
+
+func (d *fakePolicyDao) BatchCreate(reqList []*dao.TokenValidityPolicy, _ int) error {
+	for _, req := range reqList {
+		d.policies[req.TokenID] = append(d.policies[req.TokenID], req)
+	}
+	return nil
+}
+
+func (d *fakePolicyDao) DeleteByTokenID(tokenID string) error {
+	delete(d.policies, tokenID)
+	return nil
+}
+
+func newCachedAppConfig() *config.AppConfig {
+	return &config.AppConfig{
+		Auth: config.Auth{TokenPepper: "test-pepper", LeaseTTL: time.Minute},
+		MySQL: config.DBConfig{
+			CacheFlag:               true,
+			CacheExpiration:         time.Minute,
+			CacheNegativeExpiration: time.Minute,
+			CacheCleanupInterval:    time.Minute,
+			CacheMaxEntries:         100,
+		},
+	}
+}
+
+func TestCacheExpiration(t *testing.T) {
+	c := cache.New[string](10)
+	c.Set("a", "1", 20*time.Millisecond)
+	if v, ok := c.Get("a"); !ok || v != "1" {
+		t.Fatalf("expected cached value, got %q, %v", v, ok)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expected value to be expired")
+	}
+	c.Set("b", "2", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	c.DeleteExpired()
+	if c.Len() != 0 {
+		t.Fatalf("expected expired entries to be cleaned, got %d", c.Len())
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := cache.New[int](2)
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Minute)
+	// 访问 a 后 b 成为最久未使用的条目
+	c.Get("a")
+	c.Set("c", 3, time.Minute)
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a to be kept")
+	}
+	if c.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", c.Len())
+	}
+}
+
+func TestTokenAuthInfoCache(t *testing.T) {
+	tokenDao := newFakeTokenDao()
+	ts := services.NewTokenService(tokenDao, newFakePolicyDao(), newCachedAppConfig(), store.NewMemoryStore(100, time.Minute))
+	ctx := context.Background()
+	userInfo := &models.UserInfo{Username: "admin"}
+
+	// 不存在的 Token 也会被缓存
+	for i := 0; i < 3; i++ {
+		infos, err := ts.GetTokenAuthInfo(ctx, "sk-unknown")
+		if err != nil || len(infos) != 0 {
+			t.Fatalf("expected no auth info, got %v, %v", infos, err)
+		}
+	}
+	if tokenDao.authInfoHits != 1 {
+		t.Fatalf("expected negative result to be cached, dao hits: %d", tokenDao.authInfoHits)
+	}
+
+	created, err := ts.Create(&models.CreateTokenReq{AppScenarioName: "app", ModelName: "model"}, userInfo, nil)
+	if err != nil {
+		t.Fatalf("create token failed: %v", err)
+	}
+	// 创建后缓存失效，可以立即认证
+	infos, err := ts.GetTokenAuthInfo(ctx, created.Token)
+	if err != nil || len(infos) != 1 {
+		t.Fatalf("expected auth info after create, got %v, %v", infos, err)
+	}
+	hits := tokenDao.authInfoHits
+	if _, err := ts.GetTokenAuthInfo(ctx, created.Token); err != nil {
+		t.Fatal(err)
+	}
+	if tokenDao.authInfoHits != hits {
+		t.Fatal("expected auth info to be served from cache")
+	}
+
+	// 更新后缓存失效
+	_, err = ts.Update(created.ID, &models.UpdateTokenReq{AppScenarioName: "app", ModelName: "model-2"}, created.TokenEntity, userInfo)
+	if err != nil {
+		t.Fatalf("update token failed: %v", err)
+	}
+	infos, _ = ts.GetTokenAuthInfo(ctx, created.Token)
+	if len(infos) != 1 || infos[0].ModelName != "model-2" {
+		t.Fatalf("expected updated auth info, got %+v", infos)
+	}
+
+	// 删除后缓存失效
+	if err := ts.Delete(created.WorkspaceID, created.ID); err != nil {
+		t.Fatalf("delete token failed: %v", err)
+	}
+	infos, _ = ts.GetTokenAuthInfo(ctx, created.Token)
+	if len(infos) != 0 {
+		t.Fatalf("expected no auth info after delete, got %+v", infos)
+	}
+}
@@ -0,0 +1,136 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	hertzconfig "github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+
+	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/routers/ctrl"
+	"github.com/auth-engine/internal/pkg/routers/ctrl/common"
+)
+
+func TestExplainAuth(t *testing.T) {
+	shanghai := mustLoadLocation(t, "Asia/Shanghai")
+	infos := toAuthInfos(
+		withTimezone(weeklyPolicy("MONDAY", "FRIDAY", "09:00:00", "18:00:00"), "Asia/Shanghai"),
+		withTimezone(cronPolicy("0 20 * * *", "30m"), "Asia/Shanghai"),
+	)
+	calendars := []*models.CalendarResp{{
+		Calendar: &dao.Calendar{ID: "c-1", Name: "maintenance"},
+		Dates:    []*models.CalendarDateRange{{StartDate: "2026-10-14", EndDate: "2026-10-14"}},
+	}}
+
+	// 2026-10-12 是周一，20:10 不在工作时间，但在 20:00 触发的 CRON 有效期内
+	now := time.Date(2026, 10, 12, 20, 10, 0, 0, shanghai)
+	resp := ctrl.ExplainAuth(now, infos, calendars)
+	if !resp.Allowed || resp.Reason != "" || len(resp.Policies) != 2 || len(resp.Calendars) != 1 {
+		t.Fatalf("unexpected explain result: %+v", resp)
+	}
+	weekly, cron := resp.Policies[0], resp.Policies[1]
+	if weekly.Matched || weekly.Rule != "WEEKLY MONDAY-FRIDAY 09:00:00-18:00:00" || weekly.LocalTime != "2026-10-12 20:10:00 MONDAY" {
+		t.Fatalf("unexpected weekly trace: %+v", weekly)
+	}
+	if !cron.Matched || cron.Reason != "last fired at 2026-10-12 20:00:00, active until 2026-10-12 20:30:00." {
+		t.Fatalf("unexpected cron trace: %+v", cron)
+	}
+	if resp.Calendars[0].Blocked || resp.Calendars[0].LocalDate != "2026-10-12" {
+		t.Fatalf("unexpected calendar trace: %+v", resp.Calendars[0])
+	}
+
+	// 日历中的日期即使策略通过也认证失败
+	resp = ctrl.ExplainAuth(time.Date(2026, 10, 14, 10, 0, 0, 0, shanghai), infos, calendars)
+	if resp.Allowed || !resp.Policies[0].Matched || !resp.Calendars[0].Blocked ||
+		resp.Reason != "Forbidden: Token is blocked by calendar maintenance." {
+		t.Fatalf("expected blocked by calendar, got %+v", resp)
+	}
+
+	// 周三全天被日历拒绝，周四零点日历结束时策略不通过，认证结果不变
+	want := []struct {
+		time   string
+		active bool
+	}{
+		{"2026-10-12 20:30:00", false},
+		{"2026-10-13 09:00:00", true},
+		{"2026-10-13 18:00:00", false},
+		{"2026-10-13 20:00:00", true},
+		{"2026-10-13 20:30:00", false},
+		{"2026-10-15 09:00:00", true},
+		{"2026-10-15 18:00:00", false},
+		{"2026-10-15 20:00:00", true},
+	}
+	schedule := ctrl.AuthSchedule(now, infos, calendars, len(want))
+	if len(schedule) != len(want) {
+		t.Fatalf("expected %d transitions, got %d", len(want), len(schedule))
+	}
+	for i, transition := range schedule {
+		if got := transition.Time.In(shanghai).Format("2006-01-02 15:04:05"); got != want[i].time || transition.Active != want[i].active {
+			t.Fatalf("transition %d: expected %s %v, got %s %v", i, want[i].time, want[i].active, got, transition.Active)
+		}
+	}
+
+	// 过期后不再生效
+	expiredTime := time.Date(2026, 10, 13, 12, 0, 0, 0, shanghai)
+	for _, info := range infos {
+		info.ExpiredTime = &expiredTime
+	}
+	schedule = ctrl.AuthSchedule(now, infos, nil, 10)
+	last := schedule[len(schedule)-1]
+	if len(schedule) != 3 || !last.Time.Equal(expiredTime) || last.Active {
+		t.Fatalf("expected deactivation at expired time, got %d transitions, last %+v", len(schedule), last)
+	}
+}
+
+func TestExplainToken(t *testing.T) {
+	handler := newTestTokenHandler(t, &models.CreateTokenReq{
+		WorkspaceID: "1", AppScenarioName: "a", ModelName: "m", EnvName: "test", EnableValidityPolicy: true, Timezone: "Asia/Shanghai",
+		ValidityPolicy: []*models.ValidityPolicy{dailyPolicy("09:00:00", "18:00:00")},
+	})
+	created := handler.created
+	handler.Location = mustLoadLocation(t, "Asia/Shanghai")
+	engine := route.NewEngine(hertzconfig.NewOptions(nil))
+	engine.POST("/workspaces/:workspaceId/tokens/:tokenId/explain", common.Handle(handler.ExplainToken))
+	explain := func(workspaceID, tokenID string, req *models.ExplainTokenReq) (int, []byte) {
+		body, _ := json.Marshal(req)
+		resp := ut.PerformRequest(engine, http.MethodPost, "/workspaces/"+workspaceID+"/tokens/"+tokenID+"/explain",
+			&ut.Body{Body: bytes.NewReader(body), Len: len(body)}, ut.Header{Key: "Content-Type", Value: "application/json"}).Result()
+		return resp.StatusCode(), resp.Body()
+	}
+
+	status, body := explain("1", created.TokenEntity.ID, &models.ExplainTokenReq{Time: "2026-10-12 20:00:00", Transitions: 2})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d, %s", status, body)
+	}
+	var result models.DataResult[models.ExplainTokenResp]
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+	explained := result.Result
+	if explained.Allowed || explained.Reason != "Forbidden: Token is invalid for no policy pass" || explained.Timezone != "Asia/Shanghai" {
+		t.Fatalf("unexpected explain result: %s", body)
+	}
+	if len(explained.Policies) != 1 || !strings.Contains(explained.Policies[0].Reason, "is outside DAILY 09:00:00-18:00:00") {
+		t.Fatalf("unexpected policy trace: %s", body)
+	}
+	if len(explained.Schedule) != 2 || !explained.Schedule[0].Active || explained.Schedule[0].Time.Format(time.RFC3339) != "2026-10-13T09:00:00+08:00" {
+		t.Fatalf("unexpected schedule: %s", body)
+	}
+
+	if status, body := explain("2", created.TokenEntity.ID, &models.ExplainTokenReq{}); status != http.StatusNotFound {
+		t.Fatalf("expected 404 for token in other workspace, got %d, %s", status, body)
+	}
+	if status, body := explain("1", created.TokenEntity.ID, &models.ExplainTokenReq{Time: "2026-10-12T20:00:00Z"}); status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid time, got %d, %s", status, body)
+	}
+	if status, body := explain("1", created.TokenEntity.ID, &models.ExplainTokenReq{Transitions: 101}); status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for too many transitions, got %d, %s", status, body)
+	}
+}
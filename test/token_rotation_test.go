@@ -0,0 +1,95 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/internal/pkg/store"
+	"github.com/auth-engine/pkg/constants"
+)
+
+// TestTokenRotate 轮换后新旧密钥在宽限期内都有效，再次轮换时更早的密钥立即失效
+func TestTokenRotate(t *testing.T) {
+	tokenDao := newFakeTokenDao()
+	ts := services.NewTokenService(tokenDao, newFakePolicyDao(), newCachedAppConfig(), store.NewMemoryStore(100, time.Minute))
+	userInfo := &models.UserInfo{Username: "admin"}
+	ctx := context.Background()
+
+	created, err := ts.Create(&models.CreateTokenReq{AppScenarioName: "app", ModelName: "model", RequestsPerMinute: 10}, userInfo, nil)
+	if err != nil {
+		t.Fatalf("create token failed: %v", err)
+	}
+	// 预热缓存，轮换后旧的缓存需要失效
+	if infos, _ := ts.GetTokenAuthInfo(ctx, created.Token); len(infos) != 1 {
+		t.Fatalf("expected auth info, got %+v", infos)
+	}
+
+	tokenEntity, _ := ts.FindTokenEntity(created.WorkspaceID, created.ID)
+	rotated, err := ts.Rotate(tokenEntity, time.Hour, &models.UserInfo{Username: "operator"})
+	if err != nil {
+		t.Fatalf("rotate token failed: %v", err)
+	}
+	if stored, _ := ts.FindTokenEntity(created.WorkspaceID, created.ID); stored.UpdateBy != "operator" || stored.UpdateTime.IsZero() {
+		t.Fatalf("expected rotation to record operator, got %q at %v", stored.UpdateBy, stored.UpdateTime)
+	}
+	if rotated.Token == created.Token || rotated.ID != created.ID || rotated.RequestsPerMinute != 10 {
+		t.Fatalf("expected new secret with unchanged token, got %+v", rotated)
+	}
+	for token, matched := range map[string]string{
+		rotated.Token: constants.MatchedSecretCurrent,
+		created.Token: constants.MatchedSecretPrevious,
+	} {
+		infos, err := ts.GetTokenAuthInfo(ctx, token)
+		if err != nil || len(infos) != 1 || infos[0].TokenID != created.ID || infos[0].MatchedSecret != matched {
+			t.Fatalf("expected %s secret to match, got %+v, %v", matched, infos, err)
+		}
+	}
+
+	tokenEntity, _ = ts.FindTokenEntity(created.WorkspaceID, created.ID)
+	if _, err := ts.Rotate(tokenEntity, 0, userInfo); err != nil {
+		t.Fatalf("rotate token failed: %v", err)
+	}
+	for _, token := range []string{created.Token, rotated.Token} {
+		if infos, _ := ts.GetTokenAuthInfo(ctx, token); len(infos) != 0 {
+			t.Fatalf("expected previous secret to be invalid after grace period, got %+v", infos)
+		}
+	}
+}
+
+// TestRotatedTokenUpdateAndDelete 宽限期内更新和删除 Token 时轮换前密钥的认证缓存同时失效
+func TestRotatedTokenUpdateAndDelete(t *testing.T) {
+	ts := services.NewTokenService(newFakeTokenDao(), newFakePolicyDao(), newCachedAppConfig(), store.NewMemoryStore(100, time.Minute))
+	userInfo := &models.UserInfo{Username: "admin"}
+	ctx := context.Background()
+
+	created, err := ts.Create(&models.CreateTokenReq{AppScenarioName: "app", ModelName: "model"}, userInfo, nil)
+	if err != nil {
+		t.Fatalf("create token failed: %v", err)
+	}
+	tokenEntity, _ := ts.FindTokenEntity(created.WorkspaceID, created.ID)
+	if _, err := ts.Rotate(tokenEntity, time.Hour, userInfo); err != nil {
+		t.Fatalf("rotate token failed: %v", err)
+	}
+	// 预热轮换前密钥的缓存
+	if infos, _ := ts.GetTokenAuthInfo(ctx, created.Token); len(infos) != 1 {
+		t.Fatalf("expected previous secret to match, got %+v", infos)
+	}
+
+	tokenEntity, _ = ts.FindTokenEntity(created.WorkspaceID, created.ID)
+	if _, err := ts.Update(created.ID, &models.UpdateTokenReq{AppScenarioName: "app", ModelName: "model-2"}, tokenEntity, userInfo); err != nil {
+		t.Fatalf("update token failed: %v", err)
+	}
+	if infos, _ := ts.GetTokenAuthInfo(ctx, created.Token); len(infos) != 1 || infos[0].ModelName != "model-2" {
+		t.Fatalf("expected updated auth info for previous secret, got %+v", infos)
+	}
+
+	if err := ts.Delete(created.WorkspaceID, created.ID); err != nil {
+		t.Fatalf("delete token failed: %v", err)
+	}
+	if infos, _ := ts.GetTokenAuthInfo(ctx, created.Token); len(infos) != 0 {
+		t.Fatalf("expected previous secret to be invalid after delete, got %+v", infos)
+	}
+}
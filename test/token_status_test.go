@@ -0,0 +1,77 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/internal/pkg/store"
+	"github.com/auth-engine/pkg/constants"
+)
+
+func TestEffectiveStatus(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+	cases := []struct {
+		status     string
+		resumeTime *time.Time
+		expected   string
+	}{
+		{"", nil, constants.TokenStatusActive},
+		{constants.TokenStatusActive, nil, constants.TokenStatusActive},
+		{constants.TokenStatusSuspended, nil, constants.TokenStatusSuspended},
+		{constants.TokenStatusSuspended, &future, constants.TokenStatusSuspended},
+		{constants.TokenStatusSuspended, &past, constants.TokenStatusActive},
+	}
+	for _, c := range cases {
+		if status := dao.EffectiveStatus(c.status, c.resumeTime, now); status != c.expected {
+			t.Errorf("EffectiveStatus(%q, %v) = %s, expected %s", c.status, c.resumeTime, status, c.expected)
+		}
+	}
+}
+
+// TestTokenSuspend 停用和恢复后缓存的认证信息同步更新
+func TestTokenSuspend(t *testing.T) {
+	ts := services.NewTokenService(newFakeTokenDao(), newFakePolicyDao(), newCachedAppConfig(), store.NewMemoryStore(100, time.Minute))
+	userInfo := &models.UserInfo{Username: "admin"}
+	ctx := context.Background()
+
+	created, err := ts.Create(&models.CreateTokenReq{AppScenarioName: "app", ModelName: "model"}, userInfo, nil)
+	if err != nil {
+		t.Fatalf("create token failed: %v", err)
+	}
+	authStatus := func() string {
+		infos, err := ts.GetTokenAuthInfo(ctx, created.Token)
+		if err != nil || len(infos) != 1 {
+			t.Fatalf("expected auth info, got %+v, %v", infos, err)
+		}
+		return dao.EffectiveStatus(infos[0].Status, infos[0].ResumeTime, time.Now())
+	}
+	if status := authStatus(); status != constants.TokenStatusActive {
+		t.Fatalf("expected new token to be active, got %s", status)
+	}
+
+	tokenEntity, _ := ts.FindTokenEntity(created.WorkspaceID, created.ID)
+	resumeTime := time.Now().Add(time.Hour)
+	if _, err := ts.Suspend(tokenEntity, "leaked", &resumeTime, userInfo); err != nil {
+		t.Fatalf("suspend token failed: %v", err)
+	}
+	if status := authStatus(); status != constants.TokenStatusSuspended {
+		t.Fatalf("expected token to be suspended, got %s", status)
+	}
+	resp, _ := ts.Get(created.WorkspaceID, created.ID)
+	if resp.Status != constants.TokenStatusSuspended || resp.SuspendReason != "leaked" || resp.ResumeTime == nil {
+		t.Fatalf("expected suspend details, got %+v", resp)
+	}
+
+	tokenEntity, _ = ts.FindTokenEntity(created.WorkspaceID, created.ID)
+	if _, err := ts.Resume(tokenEntity, userInfo); err != nil {
+		t.Fatalf("resume token failed: %v", err)
+	}
+	if status := authStatus(); status != constants.TokenStatusActive {
+		t.Fatalf("expected token to be active after resume, got %s", status)
+	}
+}
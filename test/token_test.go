@@ -2,12 +2,15 @@ package test
 
 import (
 	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"testing"
 
+	"github.com/auth-engine/config"
 	"github.com/auth-engine/internal/pkg/dao"
 	"github.com/auth-engine/internal/pkg/models"
 	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/internal/pkg/store"
 	"github.com/auth-engine/internal/pkg/utils"
 )
 
@@ -15,7 +18,8 @@ var (
 	db                      = dao.GetDB()
 	iTokenDao               = dao.NewTokenDao(db)
 	iTokenValidityPolicyDao = dao.NewTokenValidityPolicyDao(db)
-	tokenService            = services.NewTokenService(iTokenDao, iTokenValidityPolicyDao)
+	appConfig               = &config.AppConfig{Auth: config.Auth{TokenPepper: "test-pepper"}}
+	tokenService            = services.NewTokenService(iTokenDao, iTokenValidityPolicyDao, appConfig, store.NewMemoryStore(0, 0))
 )
 
 func generateKey() ([]byte, error) {
@@ -39,6 +43,28 @@ func TestGenerateAPIKey(t *testing.T) {
 	t.Logf("Token: %s", token)
 }
 
+func TestNewTokenDigest(t *testing.T) {
+	token, err := tokenService.GenerateToken()
+	if err != nil {
+		t.Fatalf("Error getting token: %v", err)
+	}
+	digest := tokenService.NewTokenDigest(token)
+	if digest.TokenHash == "" || digest.TokenHash == token {
+		t.Fatalf("unexpected token hash: %s", digest.TokenHash)
+	}
+	// 相同的 Token 和 pepper 摘要一致
+	if digest.TokenHash != utils.HashToken(appConfig.Auth.TokenPepper, token) {
+		t.Fatal("token hash is not stable")
+	}
+	// 不同的 pepper 摘要不同
+	if digest.TokenHash == utils.HashToken("other-pepper", token) {
+		t.Fatal("token hash does not depend on pepper")
+	}
+	if digest.TokenPrefix != token[:7] || digest.TokenLast4 != token[len(token)-4:] {
+		t.Fatalf("unexpected token prefix or last4: %s, %s", digest.TokenPrefix, digest.TokenLast4)
+	}
+}
+
 func TestGenerateAPIKeyWithKey(t *testing.T) {
 	key, err := generateKey()
 	if err != nil {
@@ -49,33 +75,40 @@ func TestGenerateAPIKeyWithKey(t *testing.T) {
 	ID := dao.NewUUID()
 	t.Logf("uuid: %s", ID)
 
-	token := models.TokenResp{
-		Token:           "test-000001",
-		AppScenarioName: "test",
+	token := models.TokenExportEntity{
+		TokenResp: models.TokenResp{AppScenarioName: "test", RequestsPerMinute: 60, RequestsPerDay: 10000},
+		TokenHash: utils.HashToken(appConfig.Auth.TokenPepper, "test-000001"),
 	}
 	// 将token转换为JSON
 	jsonData, err := json.Marshal(token)
 	if err != nil {
 		t.Fatalf("Error marshalling token: %v", err)
 	}
+	exportCipher, err := utils.NewExportCipher(config.Export{Keys: []config.ExportKey{{ID: "test", Key: hex.EncodeToString(key)}}})
+	if err != nil {
+		t.Fatalf("Error creating export cipher: %v", err)
+	}
 	// 加密JSON数据
-	encryptedData, err := utils.EncryptData(jsonData)
+	encryptedData, err := exportCipher.Encrypt(jsonData)
 	if err != nil {
 		t.Fatalf("Error encrypting data: %v", err)
 	}
 	t.Logf("encryptedData: %x", encryptedData)
 
 	// 解密数据
-	decryptedData, err := utils.DecryptData(encryptedData)
+	decryptedData, _, err := exportCipher.Decrypt(encryptedData)
 	if err != nil {
 		t.Fatalf("Error decrypting data: %v", err)
 	}
 	t.Logf("decryptedData: %s", decryptedData)
-	// 将解密后的数据转换为TokenResp结构体
-	var decryptedToken models.TokenResp
+	// 将解密后的数据转换为TokenExportEntity结构体
+	var decryptedToken models.TokenExportEntity
 	err = json.Unmarshal(decryptedData, &decryptedToken)
 	if err != nil {
 		t.Fatalf("Error unmarshalling data: %v", err)
 	}
 	t.Logf("decryptedToken: %+v", decryptedToken)
+	if decryptedToken.RequestsPerMinute != 60 || decryptedToken.RequestsPerDay != 10000 {
+		t.Fatalf("rate limits are lost after export, got %d, %d", decryptedToken.RequestsPerMinute, decryptedToken.RequestsPerDay)
+	}
 }
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/auth-engine/config"
+	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/pkg/constants"
+)
+
+// fakeUsageDao 内存中的 TokenUsageDao
+type fakeUsageDao struct {
+	usages map[string]*dao.TokenUsage // key 为 token ID
+	err    error                      // 不为空时写入失败
+}
+
+func (d *fakeUsageDao) BatchIncrease(usages []*dao.TokenUsage) error {
+	if d.err != nil {
+		return d.err
+	}
+	for _, usage := range usages {
+		saved, ok := d.usages[usage.TokenID]
+		if !ok {
+			d.usages[usage.TokenID] = usage
+			continue
+		}
+		saved.AllowedCount += usage.AllowedCount
+		saved.ExpiredCount += usage.ExpiredCount
+		saved.PolicyDeniedCount += usage.PolicyDeniedCount
+		saved.LimitedCount += usage.LimitedCount
+		saved.UnknownCount += usage.UnknownCount
+	}
+	return nil
+}
+
+func (d *fakeUsageDao) ListByTokenID(workspaceID, tokenID string, _, _ time.Time) ([]*dao.TokenUsage, error) {
+	if usage, ok := d.usages[tokenID]; ok && usage.WorkspaceID == workspaceID {
+		return []*dao.TokenUsage{usage}, nil
+	}
+	return nil, nil
+}
+
+func (d *fakeUsageDao) SumByWorkspaceID(string, time.Time, time.Time) ([]*dao.TokenUsage, error) {
+	return nil, nil
+}
+
+func TestTokenUsageFlush(t *testing.T) {
+	usageDao := &fakeUsageDao{usages: map[string]*dao.TokenUsage{}}
+	us := services.NewTokenUsageService(usageDao, &config.AppConfig{})
+	us.Record("token-1", "ws-1", constants.UsageAllowed)
+	us.Record("token-1", "ws-1", constants.UsageAllowed)
+	us.Record("token-1", "ws-1", constants.UsageExpired)
+	us.Record("", "", constants.UsageUnknown)
+
+	// 写入失败时保留统计，下次写入时合并
+	usageDao.err = errors.New("db unavailable")
+	if err := us.Flush(); err == nil {
+		t.Fatal("expected flush error")
+	}
+	us.Record("token-1", "ws-1", constants.UsageLimited)
+	usageDao.err = nil
+	if err := us.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	usage := usageDao.usages["token-1"]
+	if usage == nil || usage.AllowedCount != 2 || usage.ExpiredCount != 1 || usage.LimitedCount != 1 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+	if usageDao.usages[""].UnknownCount != 1 {
+		t.Fatalf("expected unknown token to be counted, got %+v", usageDao.usages[""])
+	}
+
+	summary, err := us.QueryTokenUsage("ws-1", "token-1", time.Now().AddDate(0, 0, -6), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Total.TotalCount != 4 || len(summary.Daily) != 1 {
+		t.Fatalf("unexpected summary: %+v", summary.Total)
+	}
+
+	unknown, err := us.QueryUnknownUsage(time.Now().AddDate(0, 0, -6), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unknown.Total.UnknownCount != 1 || unknown.Total.TotalCount != 1 {
+		t.Fatalf("unexpected unknown usage: %+v", unknown.Total)
+	}
+}
@@ -0,0 +1,415 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	hertzconfig "github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+
+	"github.com/auth-engine/internal/pkg/dao"
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/routers/ctrl"
+	"github.com/auth-engine/internal/pkg/routers/ctrl/common"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/pkg/constants"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func dailyPolicy(startTime, endTime string) *models.ValidityPolicy {
+	return &models.ValidityPolicy{PolicyType: constants.DaliyPolicyType, StartTime: strPtr(startTime), EndTime: strPtr(endTime)}
+}
+
+func weeklyPolicy(startDay, endDay string, times ...string) *models.ValidityPolicy {
+	policy := &models.ValidityPolicy{PolicyType: constants.WeeklyPolicyType, StartDay: startDay, EndDay: endDay}
+	if len(times) == 2 {
+		policy.StartTime, policy.EndTime = strPtr(times[0]), strPtr(times[1])
+	}
+	return policy
+}
+
+func dateRangePolicy(startDate, endDate string) *models.ValidityPolicy {
+	return &models.ValidityPolicy{PolicyType: constants.DateRangePolicyType, StartDate: strPtr(startDate), EndDate: strPtr(endDate)}
+}
+
+func TestCheckValidityPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policyType string
+		policys    []*models.ValidityPolicy
+		wantErr    bool
+	}{
+		{name: "daily", policys: []*models.ValidityPolicy{dailyPolicy("09:00:00", "18:00:00")}},
+		{name: "daily overnight", policys: []*models.ValidityPolicy{dailyPolicy("22:00:00", "06:00:00")}},
+		{name: "daily equal times", policys: []*models.ValidityPolicy{dailyPolicy("09:00:00", "09:00:00")}, wantErr: true},
+		{name: "daily equal midnight", policys: []*models.ValidityPolicy{dailyPolicy("00:00:00", "00:00:00")}, wantErr: true},
+		{name: "daily invalid time", policys: []*models.ValidityPolicy{dailyPolicy("09:00", "18:00:00")}, wantErr: true},
+		{name: "daily missing time", policys: []*models.ValidityPolicy{{PolicyType: constants.DaliyPolicyType}}, wantErr: true},
+		{name: "token level type", policyType: constants.DaliyPolicyType,
+			policys: []*models.ValidityPolicy{{StartTime: strPtr("09:00:00"), EndTime: strPtr("18:00:00")}}},
+		{name: "weekly", policys: []*models.ValidityPolicy{weeklyPolicy("MONDAY", "FRIDAY")}},
+		{name: "weekly across weekend", policys: []*models.ValidityPolicy{weeklyPolicy("SATURDAY", "MONDAY")}},
+		{name: "weekly with time", policys: []*models.ValidityPolicy{weeklyPolicy("MONDAY", "FRIDAY", "09:00:00", "18:00:00")}},
+		{name: "weekly overnight", policys: []*models.ValidityPolicy{weeklyPolicy("FRIDAY", "MONDAY", "22:00:00", "06:00:00")}},
+		{name: "weekly continuous", policys: []*models.ValidityPolicy{continuous(weeklyPolicy("FRIDAY", "MONDAY", "22:00:00", "06:00:00"))}},
+		{name: "weekly continuous without time", policys: []*models.ValidityPolicy{continuous(weeklyPolicy("FRIDAY", "MONDAY"))}, wantErr: true},
+		{name: "daily continuous", policys: []*models.ValidityPolicy{continuous(dailyPolicy("22:00:00", "06:00:00"))}, wantErr: true},
+		{name: "weekly equal times", policys: []*models.ValidityPolicy{weeklyPolicy("MONDAY", "FRIDAY", "09:00:00", "09:00:00")}, wantErr: true},
+		{name: "weekly invalid start time", policys: []*models.ValidityPolicy{weeklyPolicy("FRIDAY", "MONDAY", "22:00", "06:00:00")}, wantErr: true},
+		{name: "weekly invalid end time", policys: []*models.ValidityPolicy{weeklyPolicy("MONDAY", "FRIDAY", "09:00:00", "25:00:00")}, wantErr: true},
+		{name: "weekly only start time", policys: []*models.ValidityPolicy{{PolicyType: constants.WeeklyPolicyType,
+			StartDay: "MONDAY", EndDay: "FRIDAY", StartTime: strPtr("09:00:00")}}, wantErr: true},
+		{name: "weekly invalid day", policys: []*models.ValidityPolicy{weeklyPolicy("MONDAY", "FUNDAY")}, wantErr: true},
+		{name: "weekly missing day", policys: []*models.ValidityPolicy{weeklyPolicy("MONDAY", "")}, wantErr: true},
+		{name: "date range", policys: []*models.ValidityPolicy{dateRangePolicy("2026-01-01", "2026-12-31")}},
+		{name: "date range single day", policys: []*models.ValidityPolicy{dateRangePolicy("2026-10-17", "2026-10-17")}},
+		{name: "date range start after end", policys: []*models.ValidityPolicy{dateRangePolicy("2026-12-31", "2026-01-01")}, wantErr: true},
+		{name: "date range invalid date", policys: []*models.ValidityPolicy{dateRangePolicy("2026-13-01", "2026-12-31")}, wantErr: true},
+		{name: "mixed", policyType: constants.DaliyPolicyType, policys: []*models.ValidityPolicy{
+			dailyPolicy("09:00:00", "18:00:00"), weeklyPolicy("SATURDAY", "SUNDAY"), dateRangePolicy("2026-10-01", "2026-10-07"),
+		}},
+		{name: "invalid token level type", policyType: "MONTHLY", policys: []*models.ValidityPolicy{dailyPolicy("09:00:00", "18:00:00")}, wantErr: true},
+		{name: "missing type", policys: []*models.ValidityPolicy{{StartTime: strPtr("09:00:00"), EndTime: strPtr("18:00:00")}}, wantErr: true},
+		{name: "cron", policys: []*models.ValidityPolicy{cronPolicy("0 */2 * * *", "15m")}},
+		{name: "cron descriptor", policys: []*models.ValidityPolicy{cronPolicy("@daily", "1h")}},
+		{name: "cron invalid expr", policys: []*models.ValidityPolicy{cronPolicy("0 */2 * *", "15m")}, wantErr: true},
+		{name: "cron every descriptor", policys: []*models.ValidityPolicy{cronPolicy("@every 2h", "15m")}, wantErr: true},
+		{name: "cron timezone prefix", policys: []*models.ValidityPolicy{cronPolicy("CRON_TZ=Asia/Shanghai 0 9 * * *", "1h")}, wantErr: true},
+		{name: "cron never fires", policys: []*models.ValidityPolicy{cronPolicy("0 0 30 2 *", "1h")}, wantErr: true},
+		{name: "cron invalid duration", policys: []*models.ValidityPolicy{cronPolicy("0 9 * * *", "15x")}, wantErr: true},
+		{name: "cron zero duration", policys: []*models.ValidityPolicy{cronPolicy("0 9 * * *", "0s")}, wantErr: true},
+		{name: "cron duration too long", policys: []*models.ValidityPolicy{cronPolicy("0 9 * * *", "9000h")}, wantErr: true},
+		{name: "cron missing duration", policys: []*models.ValidityPolicy{{PolicyType: constants.CronPolicyType, CronExpr: strPtr("0 9 * * *")}}, wantErr: true},
+		{name: "timezone", policys: []*models.ValidityPolicy{withTimezone(dailyPolicy("09:00:00", "18:00:00"), "Asia/Shanghai")}},
+		{name: "invalid timezone", policys: []*models.ValidityPolicy{withTimezone(dailyPolicy("09:00:00", "18:00:00"), "Asia/Nowhere")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ctrl.CheckValidityPolicy(tt.policyType, tt.policys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckValidityPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func cronPolicy(cronExpr, cronDuration string) *models.ValidityPolicy {
+	return &models.ValidityPolicy{PolicyType: constants.CronPolicyType, CronExpr: strPtr(cronExpr), CronDuration: strPtr(cronDuration)}
+}
+
+func continuous(policy *models.ValidityPolicy) *models.ValidityPolicy {
+	policy.Continuous = true
+	return policy
+}
+
+func withTimezone(policy *models.ValidityPolicy, timezone string) *models.ValidityPolicy {
+	policy.Timezone = timezone
+	return policy
+}
+
+// toAuthInfos 按入库时的格式生成策略，再转换为认证时查询到的记录
+func toAuthInfos(policys ...*models.ValidityPolicy) []*dao.TokenAuthInfo {
+	entitys := (&services.TokenService{}).GeneratePolicyEntitys("token-1", "", policys)
+	infos := make([]*dao.TokenAuthInfo, 0, len(entitys))
+	for _, entity := range entitys {
+		infos = append(infos, &dao.TokenAuthInfo{
+			TokenID:              entity.TokenID,
+			EnableValidityPolicy: true,
+			PolicyType:           entity.PolicyType,
+			StartTime:            entity.StartTime,
+			EndTime:              entity.EndTime,
+			StartDay:             entity.StartDay,
+			EndDay:               entity.EndDay,
+			Continuous:           entity.Continuous,
+			StartDate:            entity.StartDate,
+			EndDate:              entity.EndDate,
+			Timezone:             entity.Timezone,
+			CronExpr:             entity.CronExpr,
+			CronDuration:         entity.CronDuration,
+		})
+	}
+	return infos
+}
+
+func TestAuthValidityPolicy(t *testing.T) {
+	// 2026-10-12 是周一，2026-10-17 是周六
+	at := func(day, clock string) time.Time {
+		now, err := time.ParseInLocation("2006-01-02 15:04:05", day+" "+clock, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return now
+	}
+	tests := []struct {
+		name    string
+		now     time.Time
+		policys []*models.ValidityPolicy
+		want    bool
+	}{
+		{name: "daily inside", now: at("2026-10-12", "12:00:00"), policys: []*models.ValidityPolicy{dailyPolicy("09:00:00", "18:00:00")}, want: true},
+		{name: "daily start boundary", now: at("2026-10-12", "09:00:00"), policys: []*models.ValidityPolicy{dailyPolicy("09:00:00", "18:00:00")}, want: true},
+		{name: "daily end boundary", now: at("2026-10-12", "18:00:00"), policys: []*models.ValidityPolicy{dailyPolicy("09:00:00", "18:00:00")}},
+		{name: "daily before end", now: at("2026-10-12", "17:59:59"), policys: []*models.ValidityPolicy{dailyPolicy("09:00:00", "18:00:00")}, want: true},
+		{name: "daily outside", now: at("2026-10-12", "18:00:01"), policys: []*models.ValidityPolicy{dailyPolicy("09:00:00", "18:00:00")}},
+		{name: "weekly weekday", now: at("2026-10-14", "03:00:00"), policys: []*models.ValidityPolicy{weeklyPolicy("MONDAY", "FRIDAY")}, want: true},
+		{name: "weekly weekend", now: at("2026-10-17", "12:00:00"), policys: []*models.ValidityPolicy{weeklyPolicy("MONDAY", "FRIDAY")}},
+		{name: "weekly end day", now: at("2026-10-16", "23:59:59"), policys: []*models.ValidityPolicy{weeklyPolicy("MONDAY", "FRIDAY")}, want: true},
+		{name: "weekly across weekend sunday", now: at("2026-10-18", "12:00:00"), policys: []*models.ValidityPolicy{weeklyPolicy("SATURDAY", "MONDAY")}, want: true},
+		{name: "weekly across weekend monday", now: at("2026-10-12", "12:00:00"), policys: []*models.ValidityPolicy{weeklyPolicy("SATURDAY", "MONDAY")}, want: true},
+		{name: "weekly across weekend tuesday", now: at("2026-10-13", "12:00:00"), policys: []*models.ValidityPolicy{weeklyPolicy("SATURDAY", "MONDAY")}},
+		{name: "weekly with time inside", now: at("2026-10-13", "10:00:00"),
+			policys: []*models.ValidityPolicy{weeklyPolicy("MONDAY", "FRIDAY", "09:00:00", "18:00:00")}, want: true},
+		{name: "weekly with time outside", now: at("2026-10-13", "20:00:00"),
+			policys: []*models.ValidityPolicy{weeklyPolicy("MONDAY", "FRIDAY", "09:00:00", "18:00:00")}},
+		{name: "weekly with time end boundary", now: at("2026-10-16", "18:00:00"),
+			policys: []*models.ValidityPolicy{weeklyPolicy("MONDAY", "FRIDAY", "09:00:00", "18:00:00")}},
+		{name: "date range inside", now: at("2026-10-17", "12:00:00"), policys: []*models.ValidityPolicy{dateRangePolicy("2026-10-01", "2026-10-31")}, want: true},
+		{name: "date range end date", now: at("2026-10-31", "23:59:59"), policys: []*models.ValidityPolicy{dateRangePolicy("2026-10-01", "2026-10-31")}, want: true},
+		{name: "date range start date", now: at("2026-10-01", "00:00:00"), policys: []*models.ValidityPolicy{dateRangePolicy("2026-10-01", "2026-10-31")}, want: true},
+		{name: "date range after", now: at("2026-11-01", "00:00:00"), policys: []*models.ValidityPolicy{dateRangePolicy("2026-10-01", "2026-10-31")}},
+		{name: "date range before", now: at("2026-09-30", "23:59:59"), policys: []*models.ValidityPolicy{dateRangePolicy("2026-10-01", "2026-10-31")}},
+		{name: "mixed any pass", now: at("2026-10-17", "20:00:00"), policys: []*models.ValidityPolicy{
+			dailyPolicy("09:00:00", "18:00:00"), weeklyPolicy("SATURDAY", "SUNDAY"),
+		}, want: true},
+		{name: "mixed none pass", now: at("2026-10-13", "20:00:00"), policys: []*models.ValidityPolicy{
+			dailyPolicy("09:00:00", "18:00:00"), weeklyPolicy("SATURDAY", "SUNDAY"), dateRangePolicy("2026-10-01", "2026-10-07"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ctrl.AuthValidityPolicy(tt.now, toAuthInfos(tt.policys...)); got != tt.want {
+				t.Fatalf("AuthValidityPolicy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthValidityPolicyOvernight(t *testing.T) {
+	// 2026-10-12 是周一，2026-10-16 是周五
+	at := func(clock string) time.Time {
+		now, err := time.ParseInLocation("2006-01-02 15:04:05", clock, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return now
+	}
+	nightly := dailyPolicy("22:00:00", "06:00:00")
+	weekend := continuous(weeklyPolicy("FRIDAY", "MONDAY", "22:00:00", "06:00:00"))
+	// 周五到周一每天晚上 22:00 到次日 06:00
+	nights := weeklyPolicy("FRIDAY", "MONDAY", "22:00:00", "06:00:00")
+	tests := []struct {
+		name   string
+		now    time.Time
+		policy *models.ValidityPolicy
+		want   bool
+	}{
+		{name: "nightly start", now: at("2026-10-12 22:00:00"), policy: nightly, want: true},
+		{name: "nightly before midnight", now: at("2026-10-12 23:59:59"), policy: nightly, want: true},
+		{name: "nightly after midnight", now: at("2026-10-13 00:00:00"), policy: nightly, want: true},
+		{name: "nightly before end", now: at("2026-10-13 05:59:59"), policy: nightly, want: true},
+		{name: "nightly end", now: at("2026-10-13 06:00:00"), policy: nightly},
+		{name: "nightly daytime", now: at("2026-10-13 12:00:00"), policy: nightly},
+		{name: "nightly before start", now: at("2026-10-13 21:59:59"), policy: nightly},
+		// 历史数据中开始和结束时间相同的策略全天有效
+		{name: "legacy whole day", now: at("2026-10-13 23:59:59"), policy: dailyPolicy("00:00:00", "00:00:00"), want: true},
+		{name: "weekend before start", now: at("2026-10-16 21:59:59"), policy: weekend},
+		{name: "weekend start", now: at("2026-10-16 22:00:00"), policy: weekend, want: true},
+		{name: "weekend saturday noon", now: at("2026-10-17 12:00:00"), policy: weekend, want: true},
+		{name: "weekend sunday night", now: at("2026-10-18 23:00:00"), policy: weekend, want: true},
+		{name: "weekend before end", now: at("2026-10-19 05:59:59"), policy: weekend, want: true},
+		{name: "weekend end", now: at("2026-10-19 06:00:00"), policy: weekend},
+		{name: "weekend wednesday night", now: at("2026-10-14 23:00:00"), policy: weekend},
+		{name: "weekend friday morning", now: at("2026-10-16 05:00:00"), policy: weekend},
+		{name: "continuous weekdays", now: at("2026-10-14 12:00:00"), policy: continuous(weeklyPolicy("MONDAY", "FRIDAY", "22:00:00", "06:00:00")), want: true},
+		{name: "continuous within one day", now: at("2026-10-14 12:00:00"), policy: continuous(weeklyPolicy("MONDAY", "FRIDAY", "09:00:00", "18:00:00")), want: true},
+		{name: "continuous after end", now: at("2026-10-16 18:00:00"), policy: continuous(weeklyPolicy("MONDAY", "FRIDAY", "09:00:00", "18:00:00"))},
+		{name: "nights before start", now: at("2026-10-16 21:59:59"), policy: nights},
+		{name: "nights friday night", now: at("2026-10-16 22:00:00"), policy: nights, want: true},
+		{name: "nights saturday morning", now: at("2026-10-17 05:59:59"), policy: nights, want: true},
+		{name: "nights saturday noon", now: at("2026-10-17 12:00:00"), policy: nights},
+		{name: "nights saturday night", now: at("2026-10-17 23:00:00"), policy: nights, want: true},
+		{name: "nights monday night", now: at("2026-10-19 23:00:00"), policy: nights, want: true},
+		{name: "nights tuesday morning", now: at("2026-10-20 05:00:00"), policy: nights, want: true},
+		{name: "nights tuesday night", now: at("2026-10-20 23:00:00"), policy: nights},
+		{name: "nights friday morning", now: at("2026-10-16 05:00:00"), policy: nights},
+		{name: "nights sunday wraps to monday", now: at("2026-10-19 03:00:00"), policy: weeklyPolicy("SUNDAY", "SUNDAY", "22:00:00", "06:00:00"), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ctrl.AuthValidityPolicy(tt.now, toAuthInfos(tt.policy)); got != tt.want {
+				t.Fatalf("AuthValidityPolicy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthValidityPolicyCron(t *testing.T) {
+	at := func(clock string) time.Time {
+		now, err := time.ParseInLocation("2006-01-02 15:04:05", clock, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return now
+	}
+	everyTwoHours := cronPolicy("0 */2 * * *", "15m")
+	// 每月 1 日 09:00 起有效 8 小时
+	monthStart := cronPolicy("0 9 1 * *", "8h")
+	tests := []struct {
+		name   string
+		now    time.Time
+		policy *models.ValidityPolicy
+		want   bool
+	}{
+		{name: "fire time", now: at("2026-10-12 10:00:00"), policy: everyTwoHours, want: true},
+		{name: "inside duration", now: at("2026-10-12 10:14:59"), policy: everyTwoHours, want: true},
+		{name: "duration end", now: at("2026-10-12 10:15:00"), policy: everyTwoHours},
+		{name: "between fires", now: at("2026-10-12 11:00:00"), policy: everyTwoHours},
+		{name: "before fire", now: at("2026-10-12 11:59:59"), policy: everyTwoHours},
+		{name: "across midnight", now: at("2026-10-13 00:05:00"), policy: everyTwoHours, want: true},
+		{name: "month start", now: at("2026-10-01 12:00:00"), policy: monthStart, want: true},
+		{name: "month start after duration", now: at("2026-10-01 17:00:00"), policy: monthStart},
+		{name: "middle of month", now: at("2026-10-14 12:00:00"), policy: monthStart},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ctrl.AuthValidityPolicy(tt.now, toAuthInfos(tt.policy)); got != tt.want {
+				t.Fatalf("AuthValidityPolicy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthValidityPolicyTimezone(t *testing.T) {
+	at := func(clock string, location string) time.Time {
+		now, err := time.ParseInLocation("2006-01-02 15:04:05", clock, mustLoadLocation(t, location))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return now
+	}
+	tests := []struct {
+		name    string
+		now     time.Time
+		policys []*models.ValidityPolicy
+		want    bool
+	}{
+		// 服务器时区为 UTC，UTC 02:00 是上海时间 10:00
+		{name: "default timezone", now: at("2026-10-12 02:00:00", "UTC"), policys: []*models.ValidityPolicy{dailyPolicy("09:00:00", "18:00:00")}},
+		{name: "default timezone shanghai", now: at("2026-10-12 02:00:00", "UTC").In(mustLoadLocation(t, "Asia/Shanghai")),
+			policys: []*models.ValidityPolicy{dailyPolicy("09:00:00", "18:00:00")}, want: true},
+		{name: "policy timezone", now: at("2026-10-12 02:00:00", "UTC"),
+			policys: []*models.ValidityPolicy{withTimezone(dailyPolicy("09:00:00", "18:00:00"), "Asia/Shanghai")}, want: true},
+		// UTC 周一 20:00 是上海时间周二 04:00
+		{name: "policy timezone weekday", now: at("2026-10-12 20:00:00", "UTC"),
+			policys: []*models.ValidityPolicy{withTimezone(weeklyPolicy("MONDAY", "MONDAY"), "Asia/Shanghai")}},
+		{name: "policy timezone date", now: at("2026-10-31 20:00:00", "UTC"),
+			policys: []*models.ValidityPolicy{withTimezone(dateRangePolicy("2026-10-01", "2026-10-31"), "Asia/Shanghai")}},
+		// 纽约 2026-03-08 开始夏令时，UTC 13:30 在夏令时前是 08:30，之后是 09:30
+		{name: "before dst", now: at("2026-03-06 13:30:00", "UTC"),
+			policys: []*models.ValidityPolicy{withTimezone(dailyPolicy("09:00:00", "18:00:00"), "America/New_York")}},
+		{name: "after dst", now: at("2026-03-09 13:30:00", "UTC"),
+			policys: []*models.ValidityPolicy{withTimezone(dailyPolicy("09:00:00", "18:00:00"), "America/New_York")}, want: true},
+		{name: "cron default timezone", now: at("2026-10-12 01:30:00", "UTC"), policys: []*models.ValidityPolicy{cronPolicy("0 9 * * *", "1h")}},
+		{name: "cron policy timezone", now: at("2026-10-12 01:30:00", "UTC"),
+			policys: []*models.ValidityPolicy{withTimezone(cronPolicy("0 9 * * *", "1h"), "Asia/Shanghai")}, want: true},
+		{name: "mixed timezone", now: at("2026-10-12 02:00:00", "UTC"), policys: []*models.ValidityPolicy{
+			withTimezone(dailyPolicy("09:00:00", "18:00:00"), "America/New_York"), withTimezone(dailyPolicy("09:00:00", "18:00:00"), "Asia/Shanghai"),
+		}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ctrl.AuthValidityPolicy(tt.now, toAuthInfos(tt.policys...)); got != tt.want {
+				t.Fatalf("AuthValidityPolicy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestTokenAuthWithValidityPolicy(t *testing.T) {
+	handler := newTestTokenHandler(t, &models.CreateTokenReq{
+		WorkspaceID: "1", AppScenarioName: "a", ModelName: "m", EnvName: "test", EnableValidityPolicy: true,
+		ValidityPolicy: []*models.ValidityPolicy{weeklyPolicy("MONDAY", "FRIDAY", "09:00:00", "18:00:00"), dateRangePolicy("2026-10-17", "2026-10-17")},
+	})
+	created := handler.created
+	// 未指定 policyType 时使用第一个策略的类型
+	if created.TokenEntity.PolicyType != constants.WeeklyPolicyType {
+		t.Fatalf("unexpected token policy type: %s", created.TokenEntity.PolicyType)
+	}
+
+	var now time.Time
+	handler.Now = func() time.Time { return now }
+	engine := route.NewEngine(hertzconfig.NewOptions(nil))
+	engine.POST("/token/auth", common.Handle(handler.TokenAuth))
+	body, _ := json.Marshal(models.TokenAuthBody{Token: created.Token})
+
+	tests := []struct {
+		now  string
+		want int
+	}{
+		{now: "2026-10-12 10:00:00", want: http.StatusOK},        // 周一工作时间
+		{now: "2026-10-12 20:00:00", want: http.StatusForbidden}, // 周一非工作时间
+		{now: "2026-10-17 20:00:00", want: http.StatusOK},        // 周六，在日期范围内
+		{now: "2026-10-18 10:00:00", want: http.StatusForbidden}, // 周日
+	}
+	for _, tt := range tests {
+		now, _ = time.ParseInLocation("2006-01-02 15:04:05", tt.now, time.Local)
+		resp := ut.PerformRequest(engine, http.MethodPost, "/token/auth",
+			&ut.Body{Body: bytes.NewReader(body), Len: len(body)}, ut.Header{Key: "Content-Type", Value: "application/json"}).Result()
+		if resp.StatusCode() != tt.want {
+			t.Fatalf("%s: expected %d, got %d, %s", tt.now, tt.want, resp.StatusCode(), resp.Body())
+		}
+	}
+}
+
+func TestCronPolicyExportRoundTrip(t *testing.T) {
+	ts := newBundleTokenService(newFakeTokenDao())
+	created, err := ts.Create(&models.CreateTokenReq{
+		WorkspaceID: "1", AppScenarioName: "a", ModelName: "m", EnvName: "test", EnableValidityPolicy: true,
+		ValidityPolicy: []*models.ValidityPolicy{withTimezone(cronPolicy("0 */2 * * *", "1h30m"), "Asia/Shanghai")},
+	}, &models.UserInfo{Username: "admin"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.TokenEntity.PolicyType != constants.CronPolicyType {
+		t.Fatalf("unexpected token policy type: %s", created.TokenEntity.PolicyType)
+	}
+	exported, err := ts.GetExportEntity("1", created.TokenEntity.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(exported)
+	var imported models.TokenExportEntity
+	if err := json.Unmarshal(data, &imported); err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.ValidityPolicy) != 1 {
+		t.Fatalf("unexpected validity policy: %s", data)
+	}
+	policy := imported.ValidityPolicy[0]
+	if policy.PolicyType != constants.CronPolicyType || policy.CronExpr == nil || *policy.CronExpr != "0 */2 * * *" ||
+		policy.CronDuration == nil || *policy.CronDuration != "1h30m0s" || policy.Timezone != "Asia/Shanghai" {
+		t.Fatalf("unexpected validity policy: %s", data)
+	}
+	if err := ctrl.CheckValidityPolicy(imported.PolicyType, imported.ValidityPolicy); err != nil {
+		t.Fatalf("imported policy is invalid: %v", err)
+	}
+	entitys := (&services.TokenService{}).GeneratePolicyEntitys("token-2", imported.PolicyType, imported.ValidityPolicy)
+	if entitys[0].CronExpr != "0 */2 * * *" || entitys[0].CronDuration != 5400 {
+		t.Fatalf("unexpected policy entity: %+v", entitys[0])
+	}
+}
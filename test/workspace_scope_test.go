@@ -0,0 +1,114 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"ghippo.io/api/wssdk/v1alpha1/types"
+	"github.com/cloudwego/hertz/pkg/app"
+	hertzconfig "github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"golang.org/x/xerrors"
+
+	"github.com/auth-engine/internal/pkg/models"
+	"github.com/auth-engine/internal/pkg/routers/ctrl/common"
+	"github.com/auth-engine/internal/pkg/services"
+	"github.com/auth-engine/internal/pkg/store"
+	"github.com/auth-engine/pkg/constants"
+)
+
+type fakeWorkspaceLister []types.Workspace
+
+func (l fakeWorkspaceLister) ListVisibleWorkspaces(context.Context) ([]types.Workspace, error) {
+	return l, nil
+}
+
+// TestTokenWorkspaceScope 其他工作空间的 Token 视为不存在
+func TestTokenWorkspaceScope(t *testing.T) {
+	ts := services.NewTokenService(newFakeTokenDao(), newFakePolicyDao(), newCachedAppConfig(), store.NewMemoryStore(100, time.Minute))
+	created, err := ts.Create(&models.CreateTokenReq{WorkspaceID: "1", AppScenarioName: "app", ModelName: "model"}, &models.UserInfo{Username: "admin"}, nil)
+	if err != nil {
+		t.Fatalf("create token failed: %v", err)
+	}
+	if _, err := ts.Get("1", created.ID); err != nil {
+		t.Fatalf("expected token in own workspace, got %v", err)
+	}
+	if _, err := ts.Get("2", created.ID); !xerrors.Is(err, services.ErrTokenNotFound) {
+		t.Fatalf("expected not found for other workspace, got %v", err)
+	}
+	if _, err := ts.GetExportEntity("2", created.ID); !xerrors.Is(err, services.ErrTokenNotFound) {
+		t.Fatalf("expected not found for other workspace, got %v", err)
+	}
+	if err := ts.Delete("2", created.ID); !xerrors.Is(err, services.ErrTokenNotFound) {
+		t.Fatalf("expected not found for other workspace, got %v", err)
+	}
+	if _, err := ts.FindTokenEntity("1", created.ID); err != nil {
+		t.Fatalf("expected token to survive delete from other workspace, got %v", err)
+	}
+}
+
+func TestVerifyWorkspace(t *testing.T) {
+	engine := route.NewEngine(hertzconfig.NewOptions(nil))
+	lister := fakeWorkspaceLister{{WorkspaceId: 1, Alias: "ws-1"}}
+	engine.GET("/workspaces/:workspaceId/tokens", common.VerifyWorkspace(lister), func(_ context.Context, c *app.RequestContext) {
+		c.String(http.StatusOK, "ok")
+	})
+	if resp := ut.PerformRequest(engine, http.MethodGet, "/workspaces/1/tokens", nil).Result(); resp.StatusCode() != http.StatusOK {
+		t.Fatalf("expected visible workspace to pass, got %d", resp.StatusCode())
+	}
+	if resp := ut.PerformRequest(engine, http.MethodGet, "/workspaces/2/tokens", nil).Result(); resp.StatusCode() != http.StatusNotFound {
+		t.Fatalf("expected invisible workspace to return 404, got %d", resp.StatusCode())
+	}
+}
+
+type fakeRoleGetter map[string][]string // key 为工作空间 ID
+
+func (g fakeRoleGetter) GetWorkspaceRoles(_ context.Context, workspaceID string) ([]string, error) {
+	return g[workspaceID], nil
+}
+
+func TestRequirePermission(t *testing.T) {
+	getter := fakeRoleGetter{
+		"1": {constants.WorkspaceRoleViewer},
+		"2": {constants.WorkspaceRoleViewer, constants.WorkspaceRoleEditor},
+		"3": {constants.WorkspaceRoleAdmin},
+	}
+	engine := route.NewEngine(hertzconfig.NewOptions(nil))
+	// 路由中不能包含 ':'，以权限的动作部分作为路径
+	paths := map[string]string{
+		constants.PermissionTokenView:   "view",
+		constants.PermissionTokenEdit:   "edit",
+		constants.PermissionTokenManage: "manage",
+	}
+	for permission, path := range paths {
+		engine.GET("/workspaces/:workspaceId/"+path, common.RequirePermission(getter, permission), func(_ context.Context, c *app.RequestContext) {
+			c.String(http.StatusOK, c.GetString(common.WorkspaceRoleKey))
+		})
+	}
+	cases := []struct {
+		workspaceID string
+		permission  string
+		expected    int
+	}{
+		{"1", constants.PermissionTokenView, http.StatusOK},
+		{"1", constants.PermissionTokenEdit, http.StatusForbidden},
+		{"2", constants.PermissionTokenEdit, http.StatusOK},
+		{"2", constants.PermissionTokenManage, http.StatusForbidden},
+		{"3", constants.PermissionTokenManage, http.StatusOK},
+		{"4", constants.PermissionTokenView, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		resp := ut.PerformRequest(engine, http.MethodGet, "/workspaces/"+c.workspaceID+"/"+paths[c.permission], nil).Result()
+		if resp.StatusCode() != c.expected {
+			t.Errorf("workspace %s permission %s: expected %d, got %d", c.workspaceID, c.permission, c.expected, resp.StatusCode())
+		}
+	}
+	// 多个角色时取权限最大的角色
+	resp := ut.PerformRequest(engine, http.MethodGet, "/workspaces/2/view", nil).Result()
+	if string(resp.Body()) != constants.WorkspaceRoleEditor {
+		t.Fatalf("expected editor role in context, got %s", resp.Body())
+	}
+}
@@ -18,6 +18,7 @@ import (
 
 import (
 	_ "github.com/auth-engine/docs"
+	_ "time/tzdata"
 )
 
 // Injectors from wrie.go:
@@ -43,12 +44,35 @@ func InitProject() (*servers.WebServer, error) {
 	}
 	iTokenDao := dao.NewTokenDao(db)
 	iTokenValidityPolicyDao := dao.NewTokenValidityPolicyDao(db)
-	iTokenService := services.NewTokenService(iTokenDao, iTokenValidityPolicyDao)
-	tokenHandler := ctrl.NewTokenHandler(iTokenService, appConfig, hertz)
+	client, err := global.RedisInit(appConfig)
+	if err != nil {
+		return nil, err
+	}
+	iStore := global.StoreInit(appConfig, client)
+	iTokenService := services.NewTokenService(iTokenDao, iTokenValidityPolicyDao, appConfig, iStore)
+	iTokenUsageDao := dao.NewTokenUsageDao(db)
+	iTokenUsageService := services.NewTokenUsageService(iTokenUsageDao, appConfig)
+	iAuditLogDao := dao.NewAuditLogDao(db)
+	iAuditLogService := services.NewAuditLogService(iAuditLogDao)
+	iCalendarDao := dao.NewCalendarDao(db)
+	iCalendarService := services.NewCalendarService(iCalendarDao, appConfig, iStore)
 	iClientService := services.NewClientService(appConfig, typesInterface)
-	workspaceHandler := ctrl.NewWorkspaceHandler(iClientService, hertz)
-	routeInit := routeinit.NewInit(swaggerCtrl, tokenHandler, workspaceHandler)
-	webServer, err := global.WebServerInit(appConfig, typesInterface, routeInit, hertz)
+	verifier, err := global.JWTVerifierInit(appConfig)
+	if err != nil {
+		return nil, err
+	}
+	exportCipher, err := global.ExportCipherInit(appConfig)
+	if err != nil {
+		return nil, err
+	}
+	tokenHandler := ctrl.NewTokenHandler(iTokenService, iTokenUsageService, iAuditLogService, iCalendarService, iClientService, verifier, exportCipher, appConfig, hertz)
+	workspaceHandler := ctrl.NewWorkspaceHandler(iClientService, verifier, hertz)
+	usageHandler := ctrl.NewUsageHandler(iTokenUsageService, iClientService, verifier, hertz)
+	auditLogHandler := ctrl.NewAuditLogHandler(iAuditLogService, iClientService, verifier, hertz)
+	calendarHandler := ctrl.NewCalendarHandler(iCalendarService, iTokenService, iAuditLogService, iClientService, verifier, hertz)
+	routeInit := routeinit.NewInit(swaggerCtrl, tokenHandler, workspaceHandler, usageHandler, auditLogHandler, calendarHandler)
+	extAuthzServer := global.ExtAuthzServerInit(appConfig, tokenHandler)
+	webServer, err := global.WebServerInit(appConfig, typesInterface, routeInit, hertz, extAuthzServer)
 	if err != nil {
 		return nil, err
 	}
@@ -57,10 +81,10 @@ func InitProject() (*servers.WebServer, error) {
 
 // wrie.go:
 
-var initSet = wire.NewSet(global.ConfigInit, global.LogInit, global.DBInit, global.GhippoAuthInit, global.ServerOptInit, global.WebHertzInit, global.WebServerInit, routeinit.NewInit)
+var initSet = wire.NewSet(global.ConfigInit, global.LogInit, global.DBInit, global.RedisInit, global.StoreInit, global.JWTVerifierInit, global.ExportCipherInit, global.GhippoAuthInit, global.ServerOptInit, global.WebHertzInit, global.ExtAuthzServerInit, global.WebServerInit, routeinit.NewInit)
 
-var controllerSet = wire.NewSet(ctrl.NewSwaggerCtrl, ctrl.NewTokenHandler, ctrl.NewWorkspaceHandler)
+var controllerSet = wire.NewSet(ctrl.NewSwaggerCtrl, ctrl.NewTokenHandler, ctrl.NewWorkspaceHandler, ctrl.NewUsageHandler, ctrl.NewAuditLogHandler, ctrl.NewCalendarHandler)
 
-var serviceSet = wire.NewSet(services.NewClientService, services.NewTokenService)
+var serviceSet = wire.NewSet(services.NewClientService, services.NewTokenService, services.NewTokenUsageService, services.NewAuditLogService, services.NewCalendarService)
 
-var daoSet = wire.NewSet(dao.NewTokenDao, dao.NewTokenValidityPolicyDao)
+var daoSet = wire.NewSet(dao.NewTokenDao, dao.NewTokenValidityPolicyDao, dao.NewTokenUsageDao, dao.NewAuditLogDao, dao.NewCalendarDao)
@@ -18,9 +18,14 @@ var initSet = wire.NewSet(
 	global.ConfigInit,
 	global.LogInit,
 	global.DBInit,
+	global.RedisInit,
+	global.StoreInit,
+	global.JWTVerifierInit,
+	global.ExportCipherInit,
 	global.GhippoAuthInit,
 	global.ServerOptInit,
 	global.WebHertzInit,
+	global.ExtAuthzServerInit,
 	global.WebServerInit,
 	routeinit.NewInit,
 )
@@ -29,16 +34,25 @@ var controllerSet = wire.NewSet(
 	ctrl.NewSwaggerCtrl,
 	ctrl.NewTokenHandler,
 	ctrl.NewWorkspaceHandler,
+	ctrl.NewUsageHandler,
+	ctrl.NewAuditLogHandler,
+	ctrl.NewCalendarHandler,
 )
 
 var serviceSet = wire.NewSet(
 	services.NewClientService,
 	services.NewTokenService,
+	services.NewTokenUsageService,
+	services.NewAuditLogService,
+	services.NewCalendarService,
 )
 
 var daoSet = wire.NewSet(
 	dao.NewTokenDao,
 	dao.NewTokenValidityPolicyDao,
+	dao.NewTokenUsageDao,
+	dao.NewAuditLogDao,
+	dao.NewCalendarDao,
 )
 
 func InitProject() (*servers.WebServer, error) {